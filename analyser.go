@@ -1,66 +1,98 @@
 package sudoku
 
-// guessCount counts the number of solutions to a puzzle by guesswork.
+import (
+	"context"
+	"sort"
+)
+
+// NumSolutions returns the number of solutions to a puzzle.
 //
-// Starting with the given cell, guessCount tries each glyph in turn, and if it
-// finds a valid glyph, recurses on to the next unknown cell and repeats the
-// process.
+// It searches for solutions by logical elimination and guesswork with
+// backtracking.  If at any point it becomes clear that multiple solutions
+// exist, the method returns an integer greater than one.
+//
+// Otherwise, it continues until all possibilities have been exhausted and
+// returns one if a solution has been found, zero if it has not.  The puzzle is
+// not modified.
+func (puz *Puzzle) NumSolutions() int {
+	n, _ := puz.NumSolutionsContext(context.Background())
+	return n
+}
+
+// NumSolutionsContext is like NumSolutions, but abandons the count when the
+// context is cancelled or its deadline expires.
 //
-// The channel receives the number of valid solutions found for this cell and
-// all subsequent cells.  If at any point the method detects that more than one
-// solution has been found, it immediately writes to the channel and returns.
-func (puz *Puzzle) guessCount(r, c int, ch chan int) {
-	subgrid := CellSubGrid(r, c)
-	index := coordsToIndex(r, c)
-	count := 0
-	orig := puz[index]
-	for _, glyph := range Glyphs {
-		if (puz.glyphInRow(glyph, r, c) ||
-				puz.glyphInColumn(glyph, c, r) ||
-				puz.glyphInSubGrid(glyph, subgrid, r, c)) {
+// In that case it returns zero and ctx.Err().
+func (puz *Puzzle) NumSolutionsContext(ctx context.Context) (int, error) {
+	return puz.Grid().NumSolutionsContext(ctx)
+}
+
+// Contradiction returns a minimal set of givens which together make the
+// puzzle unsolvable, or nil if the puzzle has a solution.
+//
+// The set is minimal in the sense that the puzzle formed from the returned
+// givens alone has no solution, but removing any one of them would make it
+// solvable.  A puzzle may contain several such sets, in which case only one
+// is returned.  The puzzle is not modified.
+func (puz *Puzzle) Contradiction() []CellRef {
+	refs, _ := puz.ContradictionContext(context.Background())
+	return refs
+}
+
+// ContradictionContext is like Contradiction, but gives up when the context
+// is cancelled or its deadline expires, in which case it returns nil and
+// ctx.Err().
+func (puz *Puzzle) ContradictionContext(ctx context.Context) ([]CellRef, error) {
+	attempt := *puz
+	_, core, err := attempt.SolveSATContext(ctx)
+	if err != nil || core == nil {
+		return nil, err
+	}
+	// Try dropping each given in turn.  If the remainder is still unsolvable,
+	// the solver's new conflict is a subset of it, so carry on from there.
+	for i := 0; i < len(core); {
+		var subset Puzzle
+		subset.Clear()
+		for j, cell := range core {
+			if j != i {
+				subset.SetCell(cell, puz.GetCell(cell))
+			}
+		}
+		_, conflict, err := subset.SolveSATContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if conflict == nil {
+			// This given is necessary.
+			i++
 			continue
 		}
-		puz[index] = glyph
-		if puz.Validate() == nil {
-			nr, nc, found := puz.NextUnknown(r, c)
-			if found {
-				// So far so good, recurse to the next cell.
-				nch := make(chan int)
-				go puz.guessCount(nr, nc, nch)
-				count += <-nch
-			} else {
-				count++
-			}
-			if count > 1 {
-				ch <- count
-				return
+		// Keep the givens already found to be necessary at the front.
+		next := append([]CellRef(nil), core[:i]...)
+		for _, cell := range conflict {
+			if !containsRef(core[:i], cell) {
+				next = append(next, cell)
 			}
 		}
+		core = next
 	}
-	puz[index] = orig
-	ch <- count
+	sortRefs(core)
+	return core, nil
 }
 
-// NumSolutions returns the number of solutions to a puzzle.
-//
-// It first solves all cells which can be determined by simple logical
-// elimination, and then begins guesswork with backtracking to discover all
-// possible solutions to the remaining unknown cells.  If at any point it
-// becomes clear that multiple solutions exist, the method returns an integer
-// greater than one.
-//
-// Otherwise, it continues until all possibilities have been exhausted and
-// returns one if a solution has been found, zero if it has not.
-//
-// This method will modify the contents of 'puz', so make a copy to pass in
-// here, if you want to keep the original.
-func (puz *Puzzle) NumSolutions() int {
-	puz.SolveEasy()
-	r, c, found := puz.NextUnknown(0, 0)
-	if !found {
-		return 1
+// containsRef returns whether 'refs' contains 'cell'.
+func containsRef(refs []CellRef, cell CellRef) bool {
+	for _, ref := range refs {
+		if ref == cell {
+			return true
+		}
 	}
-	ch := make(chan int)
-	go puz.guessCount(r, c, ch)
-	return <-ch
+	return false
+}
+
+// sortRefs sorts a slice of CellRefs into grid order.
+func sortRefs(refs []CellRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return cellRefToIndex(refs[i]) < cellRefToIndex(refs[j])
+	})
 }
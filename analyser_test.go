@@ -1,6 +1,10 @@
 package sudoku
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+)
 
 func TestNumSolutions(t *testing.T) {
 	// “Easy” difficulty with a single solution
@@ -68,3 +72,106 @@ func TestNumSolutions(t *testing.T) {
 		t.Errorf("incorrect return from NumSolutions() for golang-8 puzzle: expected multiple, got %v", solutions)
 	}
 }
+
+func TestNumSolutionsContext(t *testing.T) {
+	var puz Puzzle
+	puz.Clear()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err := puz.NumSolutionsContext(ctx)
+	if n != 0 || err != context.Canceled {
+		t.Errorf("incorrect return from NumSolutionsContext: expected 0, %v, got %v, %v", context.Canceled, n, err)
+	}
+
+	n, err = puz.NumSolutionsContext(context.Background())
+	if n <= 1 || err != nil {
+		t.Errorf("incorrect return from NumSolutionsContext for blank puzzle: expected multiple, got %v, %v", n, err)
+	}
+}
+
+// pigeonholeGrid returns an unsolvable grid which takes the solver a long time
+// to rule out: ten cells in the first two rows must all differ, but there
+// are only nine glyphs.
+func pigeonholeGrid() *Grid {
+	g := NewGrid(Classic)
+	g.Cells[80] = '5'
+	unit := Unit{Type: DisjointUnit, Members: []int{0, 1, 2, 3, 4, 5, 6, 7, 9, 10}}
+	g.Constraints = append(DefaultConstraints(Classic), unit)
+	return g
+}
+
+func TestNumSolutionsDeadline(t *testing.T) {
+	g := pigeonholeGrid()
+	orig := g.String()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n, err := g.NumSolutionsContext(ctx)
+	if n != 0 || err != ctx.Err() || err != context.DeadlineExceeded {
+		t.Errorf("incorrect return from NumSolutionsContext: expected 0, %v, got %v, %v", context.DeadlineExceeded, n, err)
+	}
+	if result := g.String(); result != orig {
+		t.Errorf("grid modified by NumSolutionsContext: expected:\n%v\n\ngot:\n%v", orig, result)
+	}
+}
+
+func TestContradiction(t *testing.T) {
+	// “Easy” difficulty, solvable
+	puz := Puzzle{
+		'2', ' ', ' ', '6', '3', ' ', ' ', '1', ' ',
+		' ', '5', '1', ' ', '2', ' ', '7', '9', '3',
+		'4', ' ', '3', '1', '9', '7', '5', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', '9', ' ', '3', '2',
+		' ', '6', '5', ' ', '7', ' ', '1', '4', ' ',
+		'1', '3', ' ', '8', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', '9', '3', '6', '2', '4', ' ', '7',
+		'3', '7', '6', ' ', '8', ' ', '2', '5', ' ',
+		' ', '2', ' ', ' ', '5', '1', ' ', ' ', '9'}
+	result := puz.Contradiction()
+	if result != nil {
+		t.Errorf("incorrect return from Contradiction for solvable puzzle: expected nil, got %v", result)
+	}
+
+	// Nothing can go in R1C9: 1-8 are in row 1 and 9 is in column 9.  The
+	// other givens play no part.
+	puz = Puzzle{
+		'1', '2', '3', '4', '5', '6', '7', '8', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', '3', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', '9',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		'9', ' ', '4', ' ', ' ', ' ', ' ', ' ', ' '}
+	orig := puz
+	result = puz.Contradiction()
+	if !puz.Equal(orig) {
+		t.Errorf("puzzle modified by Contradiction:\n%v", puz.String())
+	}
+	if len(result) == 0 {
+		t.Fatalf("no contradiction found for unsolvable puzzle")
+	}
+	var core Puzzle
+	core.Clear()
+	for _, cell := range result {
+		core.SetCell(cell, puz.GetCell(cell))
+	}
+	test := core
+	if remain, _ := test.SolveSAT(); remain == 0 {
+		t.Errorf("givens from Contradiction are solvable: %v", result)
+	}
+	for _, cell := range result {
+		test := core
+		test.SetCell(cell, Unknown)
+		if remain, _ := test.SolveSAT(); remain != 0 {
+			t.Errorf("givens from Contradiction are not minimal, %v is unnecessary: %v", cell.String(), result)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := puz.ContradictionContext(ctx)
+	if result != nil || err != context.Canceled {
+		t.Errorf("incorrect return from cancelled ContradictionContext: expected nil, %v, got %v, %v", context.Canceled, result, err)
+	}
+}
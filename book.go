@@ -0,0 +1,159 @@
+package sudoku
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+)
+
+// BookPuzzle is one puzzle in a Book.
+type BookPuzzle struct {
+	Puzzle Puzzle
+	// Solution is shown in the answer key.  If nil, the puzzle is solved
+	// when the book is written.
+	Solution *Puzzle
+	// Title is shown after the puzzle's number, if not empty.
+	Title string
+	// Difficulty is shown opposite the puzzle's number, if not empty.
+	Difficulty string
+}
+
+// Book is a printable collection of puzzles, followed by an answer key.
+type Book struct {
+	// Title is shown at the top of every page, if not empty.
+	Title string
+	// PageWidth and PageHeight are the size of each page, in points.
+	// Default to A4.
+	PageWidth, PageHeight float64
+	// PerPage is the number of puzzles on each page.  Defaults to 4.
+	PerPage int
+	// AnswersPerPage is the number of solutions on each page of the answer
+	// key.  Defaults to 12.
+	AnswersPerPage int
+	Puzzles        []BookPuzzle
+}
+
+// Measurements of the printed page, in points.
+const (
+	bookMargin    = 36
+	bookTitleSize = 16
+	bookLabelSize = 11
+	bookFooter    = 10
+	bookGutter    = 18
+)
+
+var bookText = color.RGBA{0x00, 0x00, 0x00, 0xff}
+
+// bookArrangement returns the number of columns and rows in which to arrange
+// 'n' grids within an area, along with the size of each grid, choosing the
+// arrangement that makes the grids largest.  Each grid has a label of height
+// 'label' above it.
+func bookArrangement(n int, width, height, label float64) (cols, rows int, size float64) {
+	for c := 1; c <= n; c++ {
+		r := (n + c - 1) / c
+		w := (width - bookGutter*float64(c-1)) / float64(c)
+		h := (height-bookGutter*float64(r-1))/float64(r) - label
+		if s := math.Min(w, h); s > size {
+			cols, rows, size = c, r, s
+		}
+	}
+	return
+}
+
+// bookWriter lays out the pages of a book.
+type bookWriter struct {
+	doc    *pdfDocument
+	number int
+}
+
+// start begins a new page with a heading and page number, and returns
+// the page along with the top of the area left for content.
+func (bp *bookWriter) start(heading string) (*pdfPage, float64) {
+	p := bp.doc.newPage()
+	bp.number++
+	top := float64(bookMargin)
+	if heading != "" {
+		p.text((bp.doc.width-textWidth(heading, bookTitleSize, pdfBold))/2, top+bookTitleSize, bookTitleSize, pdfBold, heading, bookText)
+		top += bookTitleSize * 2
+	}
+	num := fmt.Sprint(bp.number)
+	p.text((bp.doc.width-textWidth(num, bookFooter, pdfRegular))/2, bp.doc.height-bookMargin/2, bookFooter, pdfRegular, num, bookText)
+	return p, top
+}
+
+// grids lays out a sequence of grids, 'perPage' to a page.  Each grid is
+// given by a call to 'grid', which returns its layout and labels.
+func (bp *bookWriter) grids(count, perPage int, heading string, labelSize float64,
+	grid func(i int) (l *layout, left, right string)) {
+	width := bp.doc.width - 2*bookMargin
+	label := labelSize * 1.6
+	var page *pdfPage
+	var top, size float64
+	var cols int
+	for i := 0; i < count; i++ {
+		slot := i % perPage
+		if slot == 0 {
+			page, top = bp.start(heading)
+			height := bp.doc.height - top - bookMargin - bookFooter
+			cols, _, size = bookArrangement(perPage, width, height, label)
+		}
+		// Centre the columns across the page.
+		used := float64(cols)*size + float64(cols-1)*bookGutter
+		x := bookMargin + (width-used)/2 + float64(slot%cols)*(size+bookGutter)
+		y := top + float64(slot/cols)*(size+label+bookGutter)
+
+		l, left, right := grid(i)
+		page.text(x, y+labelSize, labelSize, pdfBold, left, bookText)
+		if right != "" {
+			page.text(x+size-textWidth(right, labelSize, pdfRegular), y+labelSize, labelSize, pdfRegular, right, bookText)
+		}
+		l.drawPDF(page, x, y+label, size/l.width)
+	}
+}
+
+// WritePDF writes the book to 'w' as a PDF document.  The puzzles are laid
+// out PerPage to a page, each with its number, title and difficulty, and are
+// followed by an answer key in which the solutions are laid out
+// AnswersPerPage to a page.
+func (b *Book) WritePDF(w io.Writer) error {
+	doc := &pdfDocument{width: b.PageWidth, height: b.PageHeight}
+	if doc.width <= 0 || doc.height <= 0 {
+		doc.width, doc.height = 595, 842
+	}
+	perPage, answersPerPage := b.PerPage, b.AnswersPerPage
+	if perPage <= 0 {
+		perPage = 4
+	}
+	if answersPerPage <= 0 {
+		answersPerPage = 12
+	}
+	opts := &RenderOptions{Margin: 2}
+	bp := &bookWriter{doc: doc}
+
+	bp.grids(len(b.Puzzles), perPage, b.Title, bookLabelSize, func(i int) (*layout, string, string) {
+		entry := &b.Puzzles[i]
+		left := fmt.Sprintf("Puzzle %v", i+1)
+		if entry.Title != "" {
+			left += ": " + entry.Title
+		}
+		return newLayout(&entry.Puzzle, opts), left, entry.Difficulty
+	})
+
+	heading := "Answers"
+	if b.Title != "" {
+		heading = b.Title + ": " + heading
+	}
+	bp.grids(len(b.Puzzles), answersPerPage, heading, bookLabelSize*0.8, func(i int) (*layout, string, string) {
+		entry := &b.Puzzles[i]
+		solution := entry.Puzzle
+		if entry.Solution != nil {
+			solution = *entry.Solution
+		} else {
+			solution.Solve()
+		}
+		givens := entry.Puzzle.GetMask()
+		return newLayout(&solution, &RenderOptions{Margin: 2, Givens: &givens}), fmt.Sprint(i + 1), ""
+	})
+	return doc.writeTo(w)
+}
@@ -0,0 +1,70 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBookArrangement(t *testing.T) {
+	cases := []struct {
+		n          int
+		cols, rows int
+	}{
+		{1, 1, 1},
+		{2, 1, 2},
+		{4, 2, 2},
+		{6, 2, 3},
+		{12, 3, 4},
+	}
+	for _, c := range cases {
+		cols, rows, size := bookArrangement(c.n, 523, 740, 18)
+		if cols != c.cols || rows != c.rows || size <= 0 {
+			t.Errorf("incorrect result from bookArrangement(%v): expected %vx%v, got %vx%v (size %v)", c.n, c.cols, c.rows, cols, rows, size)
+		}
+	}
+}
+
+func TestBookWritePDF(t *testing.T) {
+	puz := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' ',
+	}
+	book := Book{Title: "Weekly", PerPage: 2}
+	for i := 0; i < 5; i++ {
+		book.Puzzles = append(book.Puzzles, BookPuzzle{Puzzle: puz, Difficulty: "Hard"})
+	}
+	book.Puzzles[0].Title = "Extreme"
+
+	var buf bytes.Buffer
+	if err := book.WritePDF(&buf); err != nil {
+		t.Fatalf("unexpected error from WritePDF: %v", err)
+	}
+	out := buf.String()
+	// Three pages of puzzles, and one of answers.
+	expect := []string{
+		"/Count 4",
+		"(Weekly)",
+		"(Puzzle 1: Extreme)",
+		"(Puzzle 5)",
+		"(Hard)",
+		"(Weekly: Answers)",
+		"(4)",
+	}
+	for _, e := range expect {
+		if !strings.Contains(out, e) {
+			t.Errorf("incorrect result from WritePDF: expected output to contain %q", e)
+		}
+	}
+	// The solution's first row appears in the answer key.
+	if !strings.Contains(out, "(7) Tj") {
+		t.Errorf("incorrect result from WritePDF: solution missing from answer key")
+	}
+}
@@ -0,0 +1,197 @@
+package sudoku
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// CandidateGrid records the candidate glyphs for every cell of a puzzle, such
+// as the pencil marks of a partially solved puzzle.
+//
+// Each cell is a bit set, in which bit i is set if Glyphs[i] is a candidate.
+// A cell with a single candidate is considered solved.
+type CandidateGrid [GridSize]uint16
+
+// CandidateGrid returns the candidates for every cell of the puzzle.  Known
+// cells have their glyph as their only candidate, and unknown cells have the
+// glyphs returned by Candidates.
+func (puz *Puzzle) CandidateGrid() (cg CandidateGrid) {
+	for i := 0; i < GridSize; i++ {
+		if Known(puz[i]) {
+			cg[i] = glyphBit(puz[i])
+		} else {
+			r, c := indexToCoords(i)
+			for _, glyph := range puz.Candidates(r, c) {
+				cg[i] |= glyphBit(glyph)
+			}
+		}
+	}
+	return
+}
+
+// glyphBit returns the bit representing a known glyph in a CandidateGrid.
+func glyphBit(glyph byte) uint16 {
+	return 1 << uint(glyph-Glyphs[0])
+}
+
+// Get returns the candidate glyphs for a cell, in ascending order.
+func (cg *CandidateGrid) Get(cell CellRef) (result []byte) {
+	bits := cg[cellRefToIndex(cell)]
+	for i, glyph := range Glyphs {
+		if bits&(1<<uint(i)) != 0 {
+			result = append(result, glyph)
+		}
+	}
+	return
+}
+
+// Set replaces the candidate glyphs for a cell.  Bytes that are not known
+// glyphs are ignored.
+func (cg *CandidateGrid) Set(cell CellRef, glyphs []byte) {
+	var bits uint16
+	for _, glyph := range glyphs {
+		if Known(glyph) {
+			bits |= glyphBit(glyph)
+		}
+	}
+	cg[cellRefToIndex(cell)] = bits
+}
+
+// Has returns whether a glyph is a candidate for a cell.
+func (cg *CandidateGrid) Has(cell CellRef, glyph byte) bool {
+	return Known(glyph) && cg[cellRefToIndex(cell)]&glyphBit(glyph) != 0
+}
+
+// Remove eliminates a glyph as a candidate for a cell.
+func (cg *CandidateGrid) Remove(cell CellRef, glyph byte) {
+	if Known(glyph) {
+		cg[cellRefToIndex(cell)] &^= glyphBit(glyph)
+	}
+}
+
+// Puzzle returns a puzzle containing the glyph of every solved cell in the
+// grid.  All other cells are Unknown.
+func (cg *CandidateGrid) Puzzle() (puz Puzzle) {
+	for i := 0; i < GridSize; i++ {
+		puz[i] = Unknown
+		for g, glyph := range Glyphs {
+			if cg[i] == 1<<uint(g) {
+				puz[i] = glyph
+			}
+		}
+	}
+	return
+}
+
+// isBorder returns whether a byte is used to draw a candidate grid.
+func isBorder(b byte) bool {
+	return strings.IndexByte("|:.'+-*", b) >= 0
+}
+
+// Read in a candidate grid from a slice of bytes.
+//
+// The input lists the candidates of each cell as a string of digits, in rows
+// from top to bottom, separated by whitespace and by any of the characters
+// '|', ':', '.', '+', '-', '*' and apostrophe, which are used to draw the
+// subgrid borders.  A cell with no candidates is written as '0'.  This is the
+// pencil-mark grid format used by HoDoKu, Sudoku Explainer and many other
+// solvers, and produced by String.
+//
+// E.g., the first rows of a grid:
+// .----------------.----------------.------------------.
+// | 1    29   4    | 5    389  6    | 378  2378  2378  |
+// | 6    8    3    | 2    7    14   | 5    19    149   |
+// :----------------+----------------+------------------:
+func (cg *CandidateGrid) Read(input []byte) error {
+	var result CandidateGrid
+	i := 0
+	for n, line := range bytes.Split(input, []byte("\n")) {
+		start := -1
+		for c := 0; c <= len(line); c++ {
+			var b byte = ' '
+			if c < len(line) {
+				b = line[c]
+			}
+			switch {
+			case b == ' ' || b == '\t' || b == '\r' || isBorder(b):
+				if start < 0 {
+					continue
+				}
+				if i >= GridSize {
+					return &ParseError{Line: n + 1, Column: start + 1, Reason: fmt.Sprintf("more than %v cells", GridSize)}
+				}
+				for _, glyph := range line[start:c] {
+					if Known(glyph) {
+						result[i] |= glyphBit(glyph)
+					}
+				}
+				i++
+				start = -1
+			case Known(b) || b == '0':
+				if start < 0 {
+					start = c
+				}
+			default:
+				return &ParseError{Line: n + 1, Column: c + 1, Byte: b, Reason: fmt.Sprintf("expected digit 0-9, got %q", b)}
+			}
+		}
+	}
+	if i < GridSize {
+		return &ParseError{Reason: fmt.Sprintf("expected %v cells, got %v", GridSize, i)}
+	}
+	*cg = result
+	return nil
+}
+
+// String returns the candidate grid drawn with subgrid borders, in the format
+// accepted by Read.  Each column is as wide as its widest entry.
+func (cg *CandidateGrid) String() string {
+	var marks [GridSize]string
+	var widths [Size]int
+	for i := 0; i < GridSize; i++ {
+		marks[i] = string(cg.Get(indexToCellRef(i)))
+		if marks[i] == "" {
+			marks[i] = "0"
+		}
+		if len(marks[i]) > widths[i%Size] {
+			widths[i%Size] = len(marks[i])
+		}
+	}
+	border := func(left, mid, right byte) string {
+		var buf bytes.Buffer
+		for b := 0; b < SubSize; b++ {
+			if b == 0 {
+				buf.WriteByte(left)
+			} else {
+				buf.WriteByte(mid)
+			}
+			n := 1
+			for c := b * SubSize; c < (b+1)*SubSize; c++ {
+				n += widths[c] + 2
+			}
+			buf.WriteString(strings.Repeat("-", n))
+		}
+		buf.WriteByte(right)
+		buf.WriteByte('\n')
+		return buf.String()
+	}
+	var buf bytes.Buffer
+	buf.WriteString(border('.', '.', '.'))
+	for r := 0; r < Size; r++ {
+		if r > 0 && r%SubSize == 0 {
+			buf.WriteString(border(':', '+', ':'))
+		}
+		for c := 0; c < Size; c++ {
+			if c%SubSize == 0 {
+				buf.WriteString("| ")
+			}
+			m := marks[coordsToIndex(r, c)]
+			buf.WriteString(m)
+			buf.WriteString(strings.Repeat(" ", widths[c]-len(m)+2))
+		}
+		buf.WriteString("|\n")
+	}
+	buf.WriteString(border('\'', '\'', '\''))
+	return buf.String()
+}
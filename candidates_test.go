@@ -0,0 +1,114 @@
+package sudoku
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPuzzleCandidateGrid(t *testing.T) {
+	puz := Puzzle{
+		'2', ' ', ' ', '6', '3', ' ', ' ', '1', ' ',
+		' ', '5', '1', ' ', '2', ' ', '7', '9', '3',
+		'4', ' ', '3', '1', '9', '7', '5', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', '9', ' ', '3', '2',
+		' ', '6', '5', ' ', '7', ' ', '1', '4', ' ',
+		'1', '3', ' ', '8', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', '9', '3', '6', '2', '4', ' ', '7',
+		'3', '7', '6', ' ', '8', ' ', '2', '5', ' ',
+		' ', '2', ' ', ' ', '5', '1', ' ', ' ', '9'}
+	cg := puz.CandidateGrid()
+	cases := []struct {
+		cell   CellRef
+		expect []byte
+	}{
+		{CellRef{0, 0}, []byte{'2'}},
+		{CellRef{7, 8}, []byte{'1'}},
+		{CellRef{5, 5}, []byte{'4', '5', '6'}},
+	}
+	for _, c := range cases {
+		result := cg.Get(c.cell)
+		if !bytes.Equal(result, c.expect) {
+			t.Errorf("incorrect candidates for %v: expected %q, got %q", c.cell.String(), c.expect, result)
+		}
+	}
+
+	result := cg.Puzzle()
+	for _, cell := range puz.Knowns() {
+		if result.GetCell(cell) != puz.GetCell(cell) {
+			t.Errorf("incorrect value from CandidateGrid.Puzzle for %v: expected %q, got %q", cell.String(), puz.GetCell(cell), result.GetCell(cell))
+		}
+	}
+}
+
+func TestCandidateGridSetRemove(t *testing.T) {
+	var cg CandidateGrid
+	cell := CellRef{4, 4}
+	cg.Set(cell, []byte{'9', '1', '5', ' '})
+	expect := []byte{'1', '5', '9'}
+	if result := cg.Get(cell); !bytes.Equal(result, expect) {
+		t.Errorf("incorrect candidates after Set: expected %q, got %q", expect, result)
+	}
+	if !cg.Has(cell, '5') || cg.Has(cell, '4') || cg.Has(cell, Unknown) {
+		t.Errorf("incorrect result from Has")
+	}
+	cg.Remove(cell, '5')
+	cg.Remove(cell, '9')
+	if cg.Has(cell, '5') {
+		t.Errorf("candidate still present after Remove")
+	}
+	puz := cg.Puzzle()
+	if puz.GetCell(cell) != '1' || puz.NumUnknowns() != GridSize-1 {
+		t.Errorf("incorrect result from Puzzle after Remove:\n%v", puz.String())
+	}
+}
+
+func TestCandidateGridReadString(t *testing.T) {
+	input := ".--------------.---------------.----------------.\n" +
+		"| 14  19  8    | 349  34  6    | 2   5   47     |\n" +
+		"| 12  15  12   | 45   7   458  | 56  3   146    |\n" +
+		"| 34  45  347  | 345  1   2    | 9   8   467    |\n" +
+		":--------------+---------------+----------------:\n" +
+		"| 78  49  5    | 46   26  3    | 78  12  1789   |\n" +
+		"| 89  2   349  | 7    45  1    | 35  6   3589   |\n" +
+		"| 36  46  34   | 8    25  59   | 1   27  2579   |\n" +
+		":--------------+---------------+----------------:\n" +
+		"| 45  3   6    | 2    8   457  | 57  19  159    |\n" +
+		"| 15  7   14   | 35   9   45   | 35  12  1256   |\n" +
+		"| 59  8   2    | 1    36  0    | 4   79  35679  |\n" +
+		"'--------------'---------------'----------------'\n"
+	var cg CandidateGrid
+	err := cg.Read([]byte(input))
+	if err != nil {
+		t.Fatalf("Read failed on valid input: %v", err)
+	}
+	if result := cg.Get(CellRef{8, 8}); string(result) != "35679" {
+		t.Errorf("incorrect candidates for R9C9: expected \"35679\", got %q", result)
+	}
+	if result := cg.Get(CellRef{8, 5}); result != nil {
+		t.Errorf("incorrect candidates for R9C6: expected none, got %q", result)
+	}
+	output := cg.String()
+	if output != input {
+		t.Errorf("incorrect result from String: expected:\n%v\ngot:\n%v", input, output)
+	}
+
+	var other CandidateGrid
+	err = other.Read([]byte(output))
+	if err != nil || other != cg {
+		t.Errorf("incorrect round trip through String and Read: %v", err)
+	}
+
+	err = cg.Read([]byte("| 14 1x |"))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Byte != 'x' {
+		t.Errorf("incorrect error from Read for invalid input: %v", err)
+	}
+	err = cg.Read([]byte("| 14 19 |"))
+	if !errors.As(err, &perr) {
+		t.Errorf("incorrect error from Read for short input: %v", err)
+	}
+	if cg != other {
+		t.Errorf("candidate grid modified by failed Read")
+	}
+}
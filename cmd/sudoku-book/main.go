@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"github.com/direvus/sudoku"
+	"os"
+)
+
+// rate returns a difficulty label for a puzzle: "Easy" if it can be solved
+// without guesswork, or "Hard" otherwise.
+func rate(puzzle sudoku.Puzzle) string {
+	if puzzle.SolveEasy() == 0 {
+		return "Easy"
+	}
+	return "Hard"
+}
+
+func main() {
+	title := flag.String("title", "", "title shown at the top of each page")
+	perPage := flag.Int("per-page", 4, "number of puzzles on each page")
+	answersPerPage := flag.Int("answers-per-page", 12, "number of solutions on each page of the answer key")
+	difficulty := flag.String("difficulty", "auto", "difficulty label for every puzzle: 'auto' to rate each puzzle, or '' for none")
+	letter := flag.Bool("letter", false, "use US Letter pages instead of A4")
+	flag.Parse()
+
+	book := sudoku.Book{
+		Title:          *title,
+		PerPage:        *perPage,
+		AnswersPerPage: *answersPerPage,
+	}
+	if *letter {
+		book.PageWidth, book.PageHeight = 612, 792
+	}
+
+	reader := sudoku.NewPuzzleReader(os.Stdin)
+	for reader.Scan() {
+		entry := sudoku.BookPuzzle{Puzzle: reader.Puzzle(), Difficulty: *difficulty}
+		if *difficulty == "auto" {
+			entry.Difficulty = rate(entry.Puzzle)
+		}
+		book.Puzzles = append(book.Puzzles, entry)
+	}
+	if err := reader.Err(); err != nil {
+		os.Stdout.WriteString(err.Error())
+		os.Exit(1)
+	}
+	if len(book.Puzzles) == 0 {
+		os.Stderr.WriteString("no puzzles found\n")
+		os.Exit(1)
+	}
+
+	if err := book.WritePDF(os.Stdout); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -1,15 +1,180 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/direvus/sudoku"
 	"os"
+	"strings"
 )
 
+// generateMulti writes a multi-grid puzzle with the named layout to stdout,
+// and returns the exit status.
+func generateMulti(name string, size int, format string) int {
+	layout, err := sudoku.LookupLayout(name)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 2
+	}
+	shape, err := sudoku.ShapeForSize(size)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 2
+	}
+	if format != "grid" {
+		os.Stderr.WriteString("only the grid format is supported with -layout\n")
+		return 2
+	}
+	if _, err := sudoku.NewMulti(shape, layout); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 2
+	}
+	puzzle, _, err := sudoku.GenerateMultiPuzzle(shape, layout)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	os.Stdout.WriteString(puzzle.String())
+	return 0
+}
+
 func main() {
-	solution := sudoku.GenerateSolution()
-	mask := solution.MinimalMask()
-	puzzle := solution.ApplyMask(&mask)
+	format := flag.String("format", "grid", "output format: 'grid' for one line per row with glyphs separated by spaces, 'line' for a single line, or 'json' for killer puzzles")
+	size := flag.Int("size", sudoku.Size, "number of rows and columns in the grid, e.g. 4, 6, 9, 12, 16 or 25")
+	jigsaw := flag.Bool("jigsaw", false, "generate a jigsaw puzzle with randomly shaped regions, written as the grid followed by a blank line and a grid of region letters")
+	ruleNames := flag.String("rules", "", "variant rules for the puzzle, separated by commas: any of 'x', 'windoku', 'centre-dot', 'disjoint', 'anti-knight', 'anti-king' and 'non-consecutive'")
+	killer := flag.Bool("killer", false, "generate a killer puzzle, written as the grid followed by a blank line and a list of cages, each a sum and its cells")
+	edgeNames := flag.String("edges", "", "edge clue families for the puzzle, separated by commas: any of 'kropki', 'xv' and 'greater-than'; written after the grid and a blank line")
+	negative := flag.Bool("negative", false, "with -edges, give every Kropki dot and XV clue, so that their absence is also a clue")
+	edgeGivens := flag.Bool("edge-givens", false, "with -edges, remove givens and edge clues together, rather than preferring edge clues to givens")
+	layoutName := flag.String("layout", "", "generate a multi-grid puzzle with the named layout: 'samurai', 'butterfly' or 'twodoku', drawn as its board with blanks outside the grids")
+	flag.Parse()
 
-	os.Stdout.WriteString(puzzle.String())
+	// Check the format before spending any time on generation.
+	switch *format {
+	case "grid":
+	case "line":
+		if *killer {
+			os.Stderr.WriteString("the line format is not supported with -killer\n")
+			os.Exit(2)
+		}
+	case "json":
+		if !*killer {
+			os.Stderr.WriteString("the json format is only supported with -killer\n")
+			os.Exit(2)
+		}
+	default:
+		os.Stderr.WriteString("unknown format: " + *format + "\n")
+		os.Exit(2)
+	}
+
+	if *layoutName != "" {
+		os.Exit(generateMulti(*layoutName, *size, *format))
+	}
+
+	rules, err := sudoku.ParseRules(*ruleNames)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(2)
+	}
+	if *jigsaw && len(rules) > 0 {
+		os.Stderr.WriteString("rules cannot be combined with -jigsaw\n")
+		os.Exit(2)
+	}
+	if *killer && (*jigsaw || len(rules) > 0) {
+		os.Stderr.WriteString("-killer cannot be combined with -jigsaw or rules\n")
+		os.Exit(2)
+	}
+	var families []sudoku.EdgeFamily
+	for _, name := range strings.Split(*edgeNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		f := sudoku.EdgeFamily(strings.ToLower(name))
+		if f.Kinds() == nil {
+			os.Stderr.WriteString("unknown edge family: " + name + "\n")
+			os.Exit(2)
+		}
+		families = append(families, f)
+	}
+	if len(families) > 0 && (*jigsaw || *killer || len(rules) > 0) {
+		os.Stderr.WriteString("-edges cannot be combined with -jigsaw, -killer or rules\n")
+		os.Exit(2)
+	}
+
+	var solution *sudoku.Grid
+	if *jigsaw {
+		if *size < 2 || *size > sudoku.MaxSize {
+			os.Stderr.WriteString(fmt.Sprintf("invalid grid size %v\n", *size))
+			os.Exit(2)
+		}
+		solution, err = sudoku.GenerateJigsaw(*size)
+	} else if *killer {
+		var shape sudoku.Shape
+		shape, err = sudoku.ShapeForSize(*size)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		solution, err = sudoku.GenerateKiller(shape, 2, 4, 10)
+	} else {
+		var shape sudoku.Shape
+		shape, err = sudoku.ShapeForSize(*size)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		var constraints []sudoku.Constraint
+		constraints, err = sudoku.VariantConstraints(shape, rules)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		solution, err = sudoku.GenerateVariant(shape, constraints)
+	}
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	if solution == nil {
+		os.Stderr.WriteString("the rules cannot be satisfied\n")
+		os.Exit(1)
+	}
+	var puzzle *sudoku.Grid
+	if len(families) > 0 {
+		puzzle, err = sudoku.GenerateEdgePuzzle(solution, families, *negative, *edgeGivens)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+	} else {
+		puzzle = solution.ApplyMask(solution.MinimalMask())
+	}
+	if len(rules) > 0 {
+		os.Stdout.WriteString("# rules: " + sudoku.FormatRules(rules) + "\n")
+	}
+
+	switch {
+	case *killer && *format == "grid":
+		os.Stdout.WriteString(puzzle.KillerString())
+	case *killer && *format == "json":
+		data, _ := sudoku.MarshalKiller(puzzle)
+		os.Stdout.Write(append(data, '\n'))
+	case len(families) > 0 && *format == "grid":
+		os.Stdout.WriteString(puzzle.EdgePuzzleString())
+	case len(families) > 0 && *format == "line":
+		os.Stdout.WriteString(puzzle.Line() + "\n\n" + sudoku.FormatEdges(puzzle.Edges(), puzzle.NegativeEdges(), puzzle.Size()))
+	case *jigsaw && *format == "grid":
+		os.Stdout.WriteString(puzzle.JigsawString())
+	case *jigsaw && *format == "line":
+		os.Stdout.WriteString(puzzle.Line() + "\n\n" + puzzle.Shape.Regions.Line() + "\n")
+	case *format == "grid":
+		os.Stdout.WriteString(puzzle.String())
+	case *format == "line":
+		os.Stdout.WriteString(puzzle.Line() + "\n")
+	default:
+		os.Stderr.WriteString("unknown format: " + *format + "\n")
+		os.Exit(2)
+	}
 	os.Exit(0)
 }
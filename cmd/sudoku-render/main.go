@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/direvus/sudoku"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// renderMulti draws a multi-grid puzzle from stdin, in the format read by
+// sudoku.ParseMulti for the named layout of 9×9 grids.
+func renderMulti(name, format string, cell int, solve bool) int {
+	layout, err := sudoku.LookupLayout(name)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 2
+	}
+	var buf bytes.Buffer
+	buf.ReadFrom(os.Stdin)
+	m, err := sudoku.ParseMulti(buf.Bytes(), sudoku.Classic, layout)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	if solve {
+		m.Solve()
+	}
+	opts := sudoku.RenderOptions{CellSize: cell}
+	switch format {
+	case "svg":
+		err = m.RenderSVG(os.Stdout, &opts)
+	case "png":
+		err = m.RenderPNG(os.Stdout, &opts)
+	default:
+		os.Stderr.WriteString("unknown format: " + format + "\n")
+		return 2
+	}
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	input := flag.String("input", "auto", "input format: 'auto' for the grid or line format, or the name of any registered format")
+	format := flag.String("format", "svg", "output format: 'svg' or 'png'")
+	cell := flag.Int("cell", 40, "size of each cell in pixels")
+	candidates := flag.Bool("candidates", false, "draw the candidates of each unknown cell")
+	solve := flag.Bool("solve", false, "solve the puzzle, and draw the solved cells as entered digits")
+	highlight := flag.String("highlight", "", "comma-separated list of cells to highlight, e.g. 'R1C1,R5C5'")
+	lines := flag.String("lines", "", "file of line constraints to draw, one per line as a type and its cells, e.g. 'thermo R1C1 R1C2 R1C3'")
+	edges := flag.String("edges", "", "file of edge clues to draw, in the format written by sudoku-gen -edges, e.g. 'R1C1|w R1C1_<'")
+	layout := flag.String("layout", "", "draw a multi-grid puzzle with the named layout: 'samurai', 'butterfly' or 'twodoku'; only -format, -cell and -solve apply")
+	flag.Parse()
+
+	if *layout != "" {
+		os.Exit(renderMulti(*layout, *format, *cell, *solve))
+	}
+
+	var buf bytes.Buffer
+	var puzzle sudoku.Puzzle
+	buf.ReadFrom(os.Stdin)
+	if *input == "auto" {
+		reader := sudoku.NewPuzzleReader(&buf)
+		if !reader.Scan() {
+			if err := reader.Err(); err != nil {
+				os.Stdout.WriteString(err.Error())
+			} else {
+				os.Stdout.WriteString("no puzzle found")
+			}
+			os.Exit(1)
+		}
+		puzzle = reader.Puzzle()
+	} else {
+		decoder := sudoku.LookupFormat(*input)
+		if decoder == nil {
+			os.Stderr.WriteString("unknown input format: " + *input + "\n")
+			os.Exit(2)
+		}
+		var err error
+		puzzle, err = decoder.Decode(buf.Bytes())
+		if err != nil {
+			os.Stdout.WriteString(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	opts := sudoku.RenderOptions{CellSize: *cell}
+	givens := puzzle.GetMask()
+	opts.Givens = &givens
+	if *lines != "" {
+		data, err := ioutil.ReadFile(*lines)
+		if err == nil {
+			opts.Lines, err = sudoku.ParseLines(data, sudoku.Classic)
+		}
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+	}
+	var negative []sudoku.EdgeFamily
+	if *edges != "" {
+		data, err := ioutil.ReadFile(*edges)
+		if err == nil {
+			opts.Edges, negative, err = sudoku.ParseEdges(data, sudoku.Classic)
+		}
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+	}
+	if *solve && (len(opts.Lines) > 0 || len(opts.Edges) > 0) {
+		grid := puzzle.Grid()
+		grid.Constraints, _ = sudoku.EdgeConstraints(grid.Shape, opts.Edges, negative)
+		for _, line := range opts.Lines {
+			grid.Constraints = append(grid.Constraints, line)
+		}
+		grid.Solve()
+		puzzle, _ = grid.Puzzle()
+	} else if *solve {
+		puzzle.Solve()
+	}
+	if *candidates {
+		grid := puzzle.CandidateGrid()
+		opts.Candidates = &grid
+	}
+	if *highlight != "" {
+		for _, s := range strings.Split(*highlight, ",") {
+			ref, err := sudoku.ParseCellRef(strings.TrimSpace(s))
+			if err != nil {
+				os.Stderr.WriteString(err.Error() + "\n")
+				os.Exit(2)
+			}
+			opts.Highlights = append(opts.Highlights, sudoku.Highlight{Cell: ref, Colour: color.RGBA{0xff, 0xee, 0x88, 0xff}})
+		}
+	}
+
+	var err error
+	switch *format {
+	case "svg":
+		err = puzzle.RenderSVG(os.Stdout, &opts)
+	case "png":
+		err = puzzle.RenderPNG(os.Stdout, &opts)
+	default:
+		os.Stderr.WriteString("unknown format: " + *format + "\n")
+		os.Exit(2)
+	}
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
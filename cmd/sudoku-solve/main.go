@@ -2,22 +2,325 @@ package main
 
 import (
 	"bytes"
+	"flag"
+	"fmt"
 	"github.com/direvus/sudoku"
 	"os"
+	"strings"
 )
 
-func main() {
+// formatNames returns the names of all registered formats, for usage text.
+func formatNames() string {
+	var names []string
+	for _, f := range sudoku.Formats() {
+		names = append(names, "'"+f.Name()+"'")
+	}
+	return strings.Join(names, ", ")
+}
+
+// solveGrids solves a collection of grids of any shape from stdin, writing
+// the solutions to stdout.  It returns the exit status.
+func solveGrids(shape sudoku.Shape, lineFormat bool) int {
+	writer := sudoku.NewPuzzleWriter(os.Stdout)
+	writer.LineFormat = lineFormat
+	status := 0
+	rules := "none"
+	reader := sudoku.NewGridReader(os.Stdin, shape)
+	for reader.Scan() {
+		grid := reader.Grid()
+		if r := sudoku.FormatRules(reader.Rules()); r != rules {
+			writer.WriteRules(reader.Rules())
+			rules = r
+		}
+		if grid.Solve() > 0 {
+			writer.Flush()
+			os.Stderr.WriteString(fmt.Sprintf("no solution for puzzle on line %v\n", reader.Line()))
+			status = 3
+			continue
+		}
+		writer.WriteGrid(grid)
+	}
+	writer.Flush()
+	if err := reader.Err(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	return status
+}
+
+// solvePuzzle solves a single puzzle from stdin, read by 'parse', writing the
+// solution to stdout as formatted by 'format'.  It returns the exit status.
+func solvePuzzle(parse func([]byte) (*sudoku.Grid, error), format func(*sudoku.Grid) string) int {
 	var buf bytes.Buffer
-	var puzzle sudoku.Puzzle
+	if _, err := buf.ReadFrom(os.Stdin); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	grid, err := parse(buf.Bytes())
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	if err := grid.Validate(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 3
+	}
+	if grid.Solve() > 0 {
+		os.Stderr.WriteString("no solution for puzzle\n")
+		return 3
+	}
+	os.Stdout.WriteString(format(grid))
+	return 0
+}
+
+// parseKiller reads a killer puzzle in the text format read by
+// sudoku.ParseKiller or the JSON format read by sudoku.UnmarshalKiller.
+func parseKiller(input []byte) (*sudoku.Grid, error) {
+	if input = bytes.TrimSpace(input); len(input) > 0 && input[0] == '{' {
+		return sudoku.UnmarshalKiller(input)
+	}
+	return sudoku.ParseKiller(input)
+}
 
-	buf.ReadFrom(os.Stdin)
-	err := puzzle.Read(buf.Bytes())
+// solveMulti solves a single multi-grid puzzle from stdin, in the format read
+// by sudoku.ParseMulti for the named layout, writing its solution to stdout in
+// the same format.
+func solveMulti(shape sudoku.Shape, name string) int {
+	layout, err := sudoku.LookupLayout(name)
 	if err != nil {
-		os.Stdout.WriteString(err.Error())
-		os.Exit(1)
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 2
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(os.Stdin); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	m, err := sudoku.ParseMulti(buf.Bytes(), shape, layout)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 1
+	}
+	if err := m.Validate(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return 3
+	}
+	if m.Solve() > 0 {
+		os.Stderr.WriteString("no solution for puzzle\n")
+		return 3
+	}
+	os.Stdout.WriteString(m.String())
+	return 0
+}
+
+func main() {
+	input := flag.String("input", "auto", "input format: 'auto' to read a collection of puzzles in the grid and line formats, or one of "+formatNames()+" to read a single puzzle")
+	format := flag.String("format", "grid", "output format for the solved puzzles: one of "+formatNames()+", or 'dimacs' for the unsolved puzzles as CNF")
+	pretty := flag.Bool("pretty", false, "draw the solved puzzles with box-drawing characters, in colour if stdout is a terminal")
+	backend := flag.String("backend", "guess", "solver backend: 'guess' for elimination and guesswork, or 'sat' for the clause-learning SAT solver")
+	size := flag.Int("size", sudoku.Size, "number of rows and columns in each grid; sizes other than 9 support only the 'auto' input and the 'grid' and 'line' formats")
+	jigsaw := flag.Bool("jigsaw", false, "read a single jigsaw puzzle, as a grid followed by a blank line and a grid of region letters, and write its solution in the same format")
+	killer := flag.Bool("killer", false, "read a single killer puzzle, as a grid followed by a blank line and a list of cages, or as JSON, and write its solution in the same text format")
+	lines := flag.Bool("lines", false, "read a single puzzle with line constraints, as a grid followed by a blank line and a list of lines, each a type and its cells, and write its solution in the same format")
+	edges := flag.Bool("edges", false, "read a single puzzle with edge clues, as a grid followed by a blank line and a list of Kropki, XV and inequality clues, and write its solution in the same format")
+	outer := flag.Bool("outer", false, "read a single puzzle with outside clues, as a grid followed by a blank line and a list of sandwich, little killer and skyscraper clues, and write its solution in the same format; -killer, -lines, -edges and -outer may be combined, giving each list after a blank line in that order")
+	layout := flag.String("layout", "", "read a single multi-grid puzzle with the named layout, 'samurai', 'butterfly' or 'twodoku', drawn as its board with blanks outside the grids, and write its solution in the same format")
+	flag.Parse()
+
+	// The clues of each kind follow the grid in this order, each after a
+	// blank line.
+	var sections []sudoku.Section
+	for _, s := range []struct {
+		set     bool
+		section sudoku.Section
+	}{
+		{*killer, sudoku.CageSection},
+		{*lines, sudoku.LineSection},
+		{*edges, sudoku.EdgeSection},
+		{*outer, sudoku.OuterSection},
+	} {
+		if s.set {
+			sections = append(sections, s.section)
+		}
+	}
+	if *jigsaw {
+		if len(sections) > 0 {
+			os.Stderr.WriteString("-jigsaw cannot be combined with -killer, -lines, -edges or -outer\n")
+			os.Exit(2)
+		}
+		os.Exit(solvePuzzle(sudoku.ParseJigsaw, (*sudoku.Grid).JigsawString))
+	}
+	if *killer && len(sections) == 1 {
+		os.Exit(solvePuzzle(parseKiller, (*sudoku.Grid).KillerString))
+	}
+	if len(sections) > 0 {
+		parse := func(input []byte) (*sudoku.Grid, error) {
+			return sudoku.ParsePuzzle(input, sections...)
+		}
+		os.Exit(solvePuzzle(parse, func(g *sudoku.Grid) string {
+			return g.PuzzleString(sections...)
+		}))
+	}
+	if *layout != "" {
+		shape, err := sudoku.ShapeForSize(*size)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		os.Exit(solveMulti(shape, *layout))
+	}
+	if *size != sudoku.Size {
+		shape, err := sudoku.ShapeForSize(*size)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		if *input != "auto" || (*format != "grid" && *format != "line") || *pretty {
+			os.Stderr.WriteString(fmt.Sprintf("only the grid and line formats are supported for grids of size %v\n", *size))
+			os.Exit(2)
+		}
+		os.Exit(solveGrids(shape, *format == "line"))
 	}
 
-	puzzle.Solve()
-	os.Stdout.WriteString(puzzle.String())
-	os.Exit(0)
+	var decoder, encoder sudoku.Format
+	if *input != "auto" {
+		decoder = sudoku.LookupFormat(*input)
+		if decoder == nil {
+			os.Stderr.WriteString("unknown input format: " + *input + "\n")
+			os.Exit(2)
+		}
+	}
+	if *format != "dimacs" {
+		encoder = sudoku.LookupFormat(*format)
+		if encoder == nil {
+			os.Stderr.WriteString("unknown format: " + *format + "\n")
+			os.Exit(2)
+		}
+	}
+	switch *backend {
+	case "guess", "sat":
+	default:
+		os.Stderr.WriteString("unknown backend: " + *backend + "\n")
+		os.Exit(2)
+	}
+
+	colour := false
+	if info, err := os.Stdout.Stat(); err == nil {
+		colour = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	writer := sudoku.NewPuzzleWriter(os.Stdout)
+	writer.LineFormat = *format == "line"
+	status := 0
+	count := 0
+	process := func(puzzle sudoku.Puzzle, line int) {
+		if *format == "dimacs" {
+			err := puzzle.EncodeDIMACS(os.Stdout)
+			if err != nil {
+				os.Stderr.WriteString(err.Error() + "\n")
+				os.Exit(1)
+			}
+			return
+		}
+
+		givens := puzzle
+		if *backend == "sat" {
+			puzzle.SolveSAT()
+		} else {
+			puzzle.Solve()
+		}
+		if puzzle.NumUnknowns() > 0 {
+			msg := fmt.Sprintf("no solution for puzzle on line %v", line)
+			if refs := givens.Contradiction(); refs != nil {
+				msg += ": these givens cannot all be satisfied:"
+				for _, ref := range refs {
+					msg += " " + ref.String()
+				}
+			}
+			writer.Flush()
+			os.Stderr.WriteString(msg + "\n")
+			status = 3
+			return
+		}
+		switch {
+		case *pretty:
+			if count > 0 {
+				os.Stdout.WriteString("\n")
+			}
+			mask := givens.GetMask()
+			os.Stdout.WriteString(puzzle.Pretty(&sudoku.PrettyOptions{Colour: colour, Givens: &mask}))
+		case *format == "grid" || *format == "line":
+			writer.Write(&puzzle)
+		default:
+			writer.Flush()
+			if count > 0 {
+				os.Stdout.WriteString("\n")
+			}
+			// Presentation formats distinguish the givens from the solution.
+			mask := givens.GetMask()
+			switch f := encoder.(type) {
+			case sudoku.HTMLFormat:
+				f.Givens = &mask
+				f.Encode(os.Stdout, &puzzle)
+			case sudoku.LaTeXFormat:
+				f.Givens = &mask
+				f.Encode(os.Stdout, &puzzle)
+			default:
+				encoder.Encode(os.Stdout, &puzzle)
+			}
+		}
+		count++
+	}
+
+	if decoder != nil {
+		var buf bytes.Buffer
+		buf.ReadFrom(os.Stdin)
+		puzzle, err := decoder.Decode(buf.Bytes())
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+		process(puzzle, 1)
+	} else {
+		rules := "none"
+		reader := sudoku.NewPuzzleReader(os.Stdin)
+		for reader.Scan() {
+			if r := sudoku.FormatRules(reader.Rules()); r != rules {
+				if *format != "grid" && *format != "line" && *format != "dimacs" || *pretty || *backend != "guess" {
+					writer.Flush()
+					os.Stderr.WriteString("variant rules are only supported with the grid, line and dimacs formats and the guess backend\n")
+					os.Exit(2)
+				}
+				if *format != "dimacs" {
+					writer.WriteRules(reader.Rules())
+				}
+				rules = r
+			}
+			if grid := reader.Grid(); grid.Constraints != nil {
+				if *format == "dimacs" {
+					if err := grid.EncodeDIMACS(os.Stdout); err != nil {
+						os.Stderr.WriteString(err.Error() + "\n")
+						os.Exit(1)
+					}
+					continue
+				}
+				if grid.Solve() > 0 {
+					writer.Flush()
+					os.Stderr.WriteString(fmt.Sprintf("no solution for puzzle on line %v\n", reader.Line()))
+					status = 3
+					continue
+				}
+				writer.WriteGrid(grid)
+				continue
+			}
+			process(reader.Puzzle(), reader.Line())
+		}
+		if err := reader.Err(); err != nil {
+			writer.Flush()
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+	}
+	writer.Flush()
+	os.Exit(status)
 }
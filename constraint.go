@@ -0,0 +1,159 @@
+package sudoku
+
+import "math/bits"
+
+// Constraint is a rule which the solution of a grid must obey.
+//
+// The rows, columns and boxes of a grid are each a Unit constraint, and
+// together they make up the DefaultConstraints for its shape.  Variants are
+// described by appending further constraints to those in Grid.Constraints.
+//
+// During a search, the candidates of each cell are held as a bit mask, with
+// bit 'n' set if the shape's glyph 'n' is still possible.  Constraints which
+// do arithmetic treat glyph 'n' as having the value n+1, so that the digits
+// of grids up to 9×9 have their face value.
+type Constraint interface {
+	// Cells returns the indexes of the cells the constraint involves, in grid
+	// order.
+	Cells() []int
+	// Check returns an error if the known cells of the grid break the
+	// constraint.  Unknown cells are ignored.
+	Check(g *Grid) error
+	// Propagate removes from 'cand' any candidates of the constraint's cells
+	// which cannot be part of a solution, and returns false if the remaining
+	// candidates cannot satisfy the constraint.  It must not change the
+	// candidates of any other cell.
+	Propagate(cand []uint64) bool
+}
+
+// Unit is the constraint that no glyph appears more than once among a group
+// of cells.  A unit with one cell for each glyph, such as a row, must contain
+// every glyph exactly once.
+type Unit struct {
+	// Type and Index identify the unit in a ConflictError.
+	Type  UnitType
+	Index int
+	// Members are the indexes of the unit's cells, in grid order.
+	Members []int
+}
+
+// Cells returns the members of the unit.
+func (u Unit) Cells() []int {
+	return u.Members
+}
+
+// Check returns a *ConflictError describing the first duplicated glyph in
+// the unit, if there is one.
+func (u Unit) Check(g *Grid) error {
+	if conflicts := u.conflicts(g, false); len(conflicts) > 0 {
+		return conflicts[0]
+	}
+	return nil
+}
+
+// Propagate removes the glyph of each solved cell from the candidates of the
+// unit's other cells.
+func (u Unit) Propagate(cand []uint64) bool {
+	for changed := true; changed; {
+		changed = false
+		for _, i := range u.Members {
+			if bits.OnesCount64(cand[i]) != 1 {
+				continue
+			}
+			for _, j := range u.Members {
+				if j == i || cand[j]&cand[i] == 0 {
+					continue
+				}
+				cand[j] &^= cand[i]
+				if cand[j] == 0 {
+					return false
+				}
+				changed = true
+			}
+		}
+	}
+	return true
+}
+
+// conflicts returns the duplicated glyphs in the unit.  If 'all' is false, it
+// stops after the first duplicate it finds.
+func (u Unit) conflicts(g *Grid, all bool) (result []*ConflictError) {
+	seen := make(map[byte]int)
+	for _, i := range u.Members {
+		glyph := g.Cells[i]
+		if !g.Shape.Known(glyph) {
+			continue
+		}
+		seen[glyph]++
+		if seen[glyph] != 2 {
+			continue
+		}
+		err := &ConflictError{Unit: u.Type, Index: u.Index, Glyph: glyph}
+		for _, j := range u.Members {
+			if g.Cells[j] == glyph {
+				err.Cells = append(err.Cells, g.cellRef(j))
+			}
+		}
+		if !all {
+			return []*ConflictError{err}
+		}
+		result = append(result, err)
+	}
+	return
+}
+
+// DefaultConstraints returns the constraints of a classic grid of the given
+// shape: a Unit for every row, then every column, then every box or region.
+func DefaultConstraints(shape Shape) []Constraint {
+	size := shape.Size()
+	units := shape.units()
+	result := make([]Constraint, len(units))
+	for u, cells := range units {
+		unit := Unit{Type: UnitType(u / size), Index: u % size, Members: cells}
+		if unit.Type == SubGridUnit && shape.Regions != "" {
+			unit.Type = RegionUnit
+		}
+		result[u] = unit
+	}
+	return result
+}
+
+// constraints returns the grid's constraints, or the default constraints for
+// its shape if it has none.
+func (g *Grid) constraints() []Constraint {
+	if g.Constraints == nil {
+		return DefaultConstraints(g.Shape)
+	}
+	return g.Constraints
+}
+
+// Candidates returns the glyphs which could be placed in a cell without
+// breaking any of the grid's constraints, given its known cells.  The cell's
+// own contents are disregarded.
+func (g *Grid) Candidates(row, col int) (result []byte) {
+	index := g.index(row, col)
+	var involved []Constraint
+	for _, c := range g.constraints() {
+		for _, i := range c.Cells() {
+			if i == index {
+				involved = append(involved, c)
+				break
+			}
+		}
+	}
+	trial := g.Copy()
+	for k := 0; k < len(g.Shape.Glyphs); k++ {
+		trial.Cells[index] = g.Shape.Glyphs[k]
+		ok := true
+		for _, c := range involved {
+			if c.Check(trial) != nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			result = append(result, trial.Cells[index])
+		}
+	}
+	return
+}
@@ -0,0 +1,119 @@
+package sudoku
+
+import (
+	"reflect"
+	"testing"
+)
+
+// evenCell is a test constraint requiring a cell to hold an even value.
+type evenCell int
+
+func (c evenCell) Cells() []int {
+	return []int{int(c)}
+}
+
+func (c evenCell) Check(g *Grid) error {
+	if n := g.Shape.glyphIndex(g.Cells[c]); n >= 0 && (n+1)%2 != 0 {
+		return &ConstraintError{Constraint: c, Cells: []CellRef{g.cellRef(int(c))}, Reason: "odd value in even cell"}
+	}
+	return nil
+}
+
+func (c evenCell) Propagate(cand []uint64) bool {
+	// Bits 1, 3, 5 ... hold the even values.
+	cand[c] &= 0xaaaaaaaaaaaaaaaa
+	return cand[c] != 0
+}
+
+func TestDefaultConstraints(t *testing.T) {
+	cs := DefaultConstraints(Classic)
+	if len(cs) != 27 {
+		t.Fatalf("incorrect number of constraints: expected 27, got %v", len(cs))
+	}
+	expect := Unit{Type: ColumnUnit, Index: 1, Members: []int{1, 10, 19, 28, 37, 46, 55, 64, 73}}
+	if !reflect.DeepEqual(cs[10], expect) {
+		t.Errorf("incorrect constraint for column 2: expected %v, got %v", expect, cs[10])
+	}
+	expect = Unit{Type: SubGridUnit, Index: 4, Members: []int{30, 31, 32, 39, 40, 41, 48, 49, 50}}
+	if !reflect.DeepEqual(cs[22], expect) {
+		t.Errorf("incorrect constraint for subgrid 5: expected %v, got %v", expect, cs[22])
+	}
+
+	g, _ := ParseJigsaw([]byte(jigsawPuzzle))
+	for _, c := range DefaultConstraints(g.Shape)[18:] {
+		if u := c.(Unit); u.Type != RegionUnit {
+			t.Errorf("incorrect unit type for jigsaw region %v: expected region, got %v", u.Index+1, u.Type)
+		}
+	}
+}
+
+func TestUnitPropagate(t *testing.T) {
+	u := Unit{Members: []int{0, 1, 2}}
+	cand := []uint64{1, 3, 7}
+	if !u.Propagate(cand) {
+		t.Fatalf("unexpected contradiction from Propagate")
+	}
+	if expect := []uint64{1, 2, 4}; !reflect.DeepEqual(cand, expect) {
+		t.Errorf("incorrect result from Propagate: expected %v, got %v", expect, cand)
+	}
+	cand = []uint64{1, 1, 7}
+	if u.Propagate(cand) {
+		t.Errorf("no contradiction from Propagate for a repeated glyph")
+	}
+}
+
+func TestGridConstraints(t *testing.T) {
+	shape, _ := NewShape(2, 2)
+	g := NewGrid(shape)
+	g.Constraints = append(DefaultConstraints(shape), evenCell(0), evenCell(5), evenCell(10))
+
+	if result := g.Candidates(0, 0); string(result) != "24" {
+		t.Errorf("incorrect result from Candidates: expected \"24\", got %q", result)
+	}
+	g.Set(1, 1, '3')
+	err, ok := g.Validate().(*ConstraintError)
+	if !ok {
+		t.Fatalf("expected *ConstraintError from Validate, got %v", g.Validate())
+	}
+	if expect := "invalid puzzle: odd value in even cell at R2C2"; err.Error() != expect {
+		t.Errorf("incorrect error from Validate: expected %q, got %q", expect, err.Error())
+	}
+	if n := g.NumSolutions(); n != 0 {
+		t.Errorf("incorrect result from NumSolutions: expected 0, got %v", n)
+	}
+
+	g.Set(1, 1, Unknown)
+	g.Set(0, 0, '2')
+	if remain := g.Solve(); remain != 0 {
+		t.Fatalf("incorrect result from Solve: expected 0, got %v", remain)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("unexpected error from Validate for solution %v: %v", g.Line(), err)
+	}
+	if g.Get(0, 0) != '2' || g.Get(1, 1) != '4' || g.Get(2, 2)%2 != 0 {
+		t.Errorf("incorrect solution: expected 2 at R1C1, 4 at R2C2 and even R3C3, got %v", g.Line())
+	}
+}
+
+func TestGenerateVariant(t *testing.T) {
+	constraints := append(DefaultConstraints(Classic), evenCell(0), evenCell(40), evenCell(80))
+	g, err := GenerateVariant(Classic, constraints)
+	if err != nil {
+		t.Fatalf("unexpected error from GenerateVariant: %v", err)
+	}
+	if n := g.NumUnknowns(); n != 0 {
+		t.Errorf("incorrect number of unknowns: expected 0, got %v", n)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("invalid result from GenerateVariant: %v", err)
+	}
+	puzzle := g.ApplyMask(g.MinimalMask())
+	if n := puzzle.NumSolutions(); n != 1 {
+		t.Errorf("incorrect number of solutions for minimal puzzle: expected 1, got %v", n)
+	}
+
+	impossible := append(DefaultConstraints(Classic), evenCell(0), Unit{Members: []int{0, 1}}, evenCell(1), Unit{Members: []int{0, 2}}, evenCell(2), Unit{Members: []int{0, 3}}, evenCell(3), evenCell(4))
+	if g, err := GenerateVariant(Classic, impossible); g != nil || err != nil {
+		t.Errorf("expected nil result from GenerateVariant for impossible constraints, got %v, %v", g, err)
+	}
+}
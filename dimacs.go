@@ -0,0 +1,289 @@
+package sudoku
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NumVariables is the number of boolean variables in the CNF encoding of a
+// puzzle: one for each combination of cell and glyph.
+const NumVariables = GridSize * Size
+
+// Variable returns the DIMACS variable number which is true when the given
+// cell contains the given glyph.
+//
+// Variables are numbered from 1, in cell order and then glyph order, so R1C1
+// holding '1' is variable 1, R1C1 holding '9' is variable 9 and R1C2 holding
+// '1' is variable 10.  It returns zero if the glyph is not known.
+func Variable(cell CellRef, glyph byte) int {
+	if !Known(glyph) {
+		return 0
+	}
+	return cellRefToIndex(cell)*Size + int(glyph-Glyphs[0]) + 1
+}
+
+// variableCell returns the grid index and glyph for a DIMACS variable number.
+func variableCell(v int) (index int, glyph byte) {
+	v--
+	return v / Size, Glyphs[v%Size]
+}
+
+// units returns the grid indexes of every row, column and subgrid in the
+// puzzle, in that order.
+func units() (result [][]int) {
+	for i := 0; i < Size; i++ {
+		row := make([]int, Size)
+		for j := 0; j < Size; j++ {
+			row[j] = coordsToIndex(i, j)
+		}
+		result = append(result, row)
+	}
+	for i := 0; i < Size; i++ {
+		col := make([]int, Size)
+		for j := 0; j < Size; j++ {
+			col[j] = coordsToIndex(j, i)
+		}
+		result = append(result, col)
+	}
+	for i := 0; i < Size; i++ {
+		sr := (i / SubSize) * SubSize
+		sc := (i % SubSize) * SubSize
+		sub := make([]int, 0, Size)
+		for r := sr; r < sr+SubSize; r++ {
+			for c := sc; c < sc+SubSize; c++ {
+				sub = append(sub, coordsToIndex(r, c))
+			}
+		}
+		result = append(result, sub)
+	}
+	return
+}
+
+// exactlyOne appends clauses to 'clauses' which require exactly one of the
+// given variables to be true: one clause of all the variables, and a binary
+// clause forbidding each pair.
+func exactlyOne(clauses [][]int, vars []int) [][]int {
+	clauses = append(clauses, append([]int(nil), vars...))
+	for i := 0; i < len(vars); i++ {
+		for j := i + 1; j < len(vars); j++ {
+			clauses = append(clauses, []int{-vars[i], -vars[j]})
+		}
+	}
+	return clauses
+}
+
+// Clauses returns the CNF encoding of the puzzle as a slice of clauses, each
+// of which is a slice of non-zero DIMACS literals.
+//
+// The encoding requires each cell to hold exactly one glyph, and each glyph to
+// appear exactly once in every row, column and subgrid.  Each known cell in
+// the puzzle is added as a unit clause.
+func (puz *Puzzle) Clauses() (clauses [][]int) {
+	clauses = ruleClauses()
+	for i := 0; i < GridSize; i++ {
+		if Known(puz[i]) {
+			clauses = append(clauses, []int{Variable(indexToCellRef(i), puz[i])})
+		}
+	}
+	return
+}
+
+// ruleClauses returns the clauses of the CNF encoding which express the rules
+// of sudoku, independent of the contents of any particular puzzle.
+func ruleClauses() (clauses [][]int) {
+	vars := make([]int, Size)
+	for i := 0; i < GridSize; i++ {
+		for g := 0; g < Size; g++ {
+			vars[g] = i*Size + g + 1
+		}
+		clauses = exactlyOne(clauses, vars)
+	}
+	for _, unit := range units() {
+		for g := 0; g < Size; g++ {
+			for j, index := range unit {
+				vars[j] = index*Size + g + 1
+			}
+			clauses = exactlyOne(clauses, vars)
+		}
+	}
+	return
+}
+
+// EncodeDIMACS writes the CNF encoding of the puzzle to 'w' in DIMACS format.
+//
+// See Clauses for a description of the encoding, and Variable for the
+// numbering of variables.  Variant puzzles are encoded by Grid.EncodeDIMACS.
+func (puz *Puzzle) EncodeDIMACS(w io.Writer) error {
+	return writeDIMACS(w, NumVariables, puz.Clauses())
+}
+
+// Clauses returns the CNF encoding of the grid and its constraints as a slice
+// of clauses, in the same manner as Puzzle.Clauses.  Variables are numbered
+// as by Variable, but for a grid of any size: the variable for cell 'i'
+// holding glyph 'n' is i*size+n+1.
+//
+// Each unit which covers the whole size of the grid holds each glyph exactly
+// once, and a smaller unit holds each glyph at most once.  A Relation forbids
+// each pair of glyphs it does not allow, as do the relations behind edge
+// clues and thermometer, whisper and palindrome lines.  The other
+// constraints, namely cages, arrow and renban lines and outside clues, have
+// no encoding, and Clauses returns an error if the grid has any of them.
+func (g *Grid) Clauses() (clauses [][]int, err error) {
+	size := g.Size()
+	variable := func(i, n int) int {
+		return i*size + n + 1
+	}
+	vars := make([]int, size)
+	for i := range g.Cells {
+		for n := range vars {
+			vars[n] = variable(i, n)
+		}
+		clauses = exactlyOne(clauses, vars)
+	}
+	relation := func(r Relation) {
+		for _, p := range r.Pairs {
+			for a, allowed := range r.Allowed {
+				for b := 0; b < size; b++ {
+					if allowed&(1<<uint(b)) == 0 {
+						clauses = append(clauses, []int{-variable(p[0], a), -variable(p[1], b)})
+					}
+				}
+			}
+		}
+	}
+	for _, c := range g.constraints() {
+		switch c := c.(type) {
+		case Unit:
+			for n := 0; n < size; n++ {
+				vars := make([]int, len(c.Members))
+				for k, i := range c.Members {
+					vars[k] = variable(i, n)
+				}
+				if len(vars) == size {
+					clauses = exactlyOne(clauses, vars)
+					continue
+				}
+				for j := range vars {
+					for k := j + 1; k < len(vars); k++ {
+						clauses = append(clauses, []int{-vars[j], -vars[k]})
+					}
+				}
+			}
+		case Relation:
+			relation(c)
+		case Edge:
+			relation(c.relation)
+		case NegativeEdges:
+			relation(c.relation)
+		case Line:
+			if c.Type == ArrowLine || c.Type == RenbanLine {
+				return nil, fmt.Errorf("cannot encode %v lines as CNF", c.Type)
+			}
+			relation(c.pairs)
+		default:
+			return nil, fmt.Errorf("cannot encode %T constraints as CNF", c)
+		}
+	}
+	for i, glyph := range g.Cells {
+		if n := g.Shape.glyphIndex(glyph); n >= 0 {
+			clauses = append(clauses, []int{variable(i, n)})
+		}
+	}
+	return clauses, nil
+}
+
+// EncodeDIMACS writes the CNF encoding of the grid to 'w' in DIMACS format,
+// or returns an error if its constraints cannot be encoded.  See Grid.Clauses
+// for a description of the encoding.
+func (g *Grid) EncodeDIMACS(w io.Writer) error {
+	clauses, err := g.Clauses()
+	if err != nil {
+		return err
+	}
+	return writeDIMACS(w, len(g.Cells)*g.Size(), clauses)
+}
+
+// writeDIMACS writes clauses over 'vars' variables in DIMACS format.
+func writeDIMACS(w io.Writer, vars int, clauses [][]int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c sudoku\n")
+	fmt.Fprintf(bw, "p cnf %v %v\n", vars, len(clauses))
+	for _, clause := range clauses {
+		for _, lit := range clause {
+			bw.WriteString(strconv.Itoa(lit))
+			bw.WriteByte(' ')
+		}
+		bw.WriteString("0\n")
+	}
+	return bw.Flush()
+}
+
+// DecodeModel reads a satisfying assignment produced by a SAT solver for the
+// encoding written by EncodeDIMACS, and stores the resulting glyphs in the
+// puzzle.
+//
+// Both the competition output format ("s SATISFIABLE" followed by "v" lines)
+// and the MiniSat result file format ("SAT" followed by bare literals) are
+// accepted.  Comment lines beginning with "c" are ignored.  Cells for which
+// the model holds no true variable are set to Unknown.
+//
+// An error is returned if the solver reported the formula unsatisfiable, if
+// the input contains a literal that is not a valid variable, or if the model
+// assigns more than one glyph to any cell.  The puzzle is not modified when an
+// error is returned.
+func (puz *Puzzle) DecodeModel(r io.Reader) error {
+	var result Puzzle
+	for i := 0; i < GridSize; i++ {
+		result[i] = Unknown
+	}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "c":
+			continue
+		case "s":
+			if len(fields) > 1 && fields[1] != "SATISFIABLE" {
+				return fmt.Errorf("malformed model: solver reported %v", strings.Join(fields[1:], " "))
+			}
+			continue
+		case "SAT":
+			continue
+		case "UNSAT", "UNSATISFIABLE":
+			return fmt.Errorf("malformed model: solver reported %v", fields[0])
+		case "v":
+			fields = fields[1:]
+		}
+		for _, field := range fields {
+			lit, err := strconv.Atoi(field)
+			if err != nil {
+				return fmt.Errorf("malformed model on line %v: invalid literal %q", line, field)
+			}
+			if lit < -NumVariables || lit > NumVariables {
+				return fmt.Errorf("malformed model on line %v: variable %v out of range", line, lit)
+			}
+			if lit <= 0 {
+				continue
+			}
+			index, glyph := variableCell(lit)
+			if Known(result[index]) && result[index] != glyph {
+				cell := indexToCellRef(index)
+				return fmt.Errorf("malformed model on line %v: cell %v assigned both %q and %q", line, cell.String(), result[index], glyph)
+			}
+			result[index] = glyph
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	*puz = result
+	return nil
+}
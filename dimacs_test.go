@@ -0,0 +1,258 @@
+package sudoku
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parseDIMACS reads back the clauses written by EncodeDIMACS.
+func parseDIMACS(t *testing.T, input []byte) (vars int, clauses [][]int) {
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	var expect int
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		if fields[0] == "p" {
+			vars, _ = strconv.Atoi(fields[2])
+			expect, _ = strconv.Atoi(fields[3])
+			continue
+		}
+		var clause []int
+		for _, f := range fields {
+			lit, err := strconv.Atoi(f)
+			if err != nil {
+				t.Fatalf("invalid literal %q in DIMACS output", f)
+			}
+			if lit == 0 {
+				break
+			}
+			clause = append(clause, lit)
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) != expect {
+		t.Errorf("incorrect clause count in DIMACS output: header says %v, got %v", expect, len(clauses))
+	}
+	return
+}
+
+// satisfies returns whether the assignment given by a complete puzzle
+// satisfies every clause.
+func satisfies(puz *Puzzle, clauses [][]int) bool {
+	for _, clause := range clauses {
+		sat := false
+		for _, lit := range clause {
+			v := lit
+			if v < 0 {
+				v = -v
+			}
+			index, glyph := variableCell(v)
+			if (puz[index] == glyph) == (lit > 0) {
+				sat = true
+				break
+			}
+		}
+		if !sat {
+			return false
+		}
+	}
+	return true
+}
+
+// model returns a competition-format SAT model for a complete puzzle.
+func model(puz *Puzzle) string {
+	var buf bytes.Buffer
+	buf.WriteString("s SATISFIABLE\n")
+	for i := 0; i < GridSize; i++ {
+		buf.WriteString("v")
+		for _, glyph := range Glyphs {
+			v := Variable(indexToCellRef(i), glyph)
+			if puz[i] != glyph {
+				v = -v
+			}
+			fmt.Fprintf(&buf, " %v", v)
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("v 0\n")
+	return buf.String()
+}
+
+func TestVariable(t *testing.T) {
+	cases := []struct {
+		cell   CellRef
+		glyph  byte
+		expect int
+	}{
+		{CellRef{0, 0}, '1', 1},
+		{CellRef{0, 0}, '9', 9},
+		{CellRef{0, 1}, '1', 10},
+		{CellRef{8, 8}, '9', NumVariables},
+		{CellRef{4, 4}, Unknown, 0},
+	}
+	for _, c := range cases {
+		result := Variable(c.cell, c.glyph)
+		if result != c.expect {
+			t.Errorf("incorrect result from Variable(%v, %q): expected %v, got %v", c.cell.String(), c.glyph, c.expect, result)
+		}
+		if result != 0 {
+			index, glyph := variableCell(result)
+			if index != cellRefToIndex(c.cell) || glyph != c.glyph {
+				t.Errorf("incorrect result from variableCell(%v): expected %v, %q, got %v, %q", result, cellRefToIndex(c.cell), c.glyph, index, glyph)
+			}
+		}
+	}
+}
+
+func TestEncodeDIMACS(t *testing.T) {
+	// “Tricky” difficulty
+	puz := Puzzle{
+		' ', ' ', '3', ' ', '5', ' ', '2', ' ', ' ',
+		'2', ' ', ' ', '7', ' ', '6', ' ', ' ', '9',
+		'7', ' ', ' ', ' ', ' ', ' ', ' ', ' ', '4',
+		' ', '2', ' ', '8', ' ', '1', ' ', '6', ' ',
+		' ', ' ', '9', '6', ' ', '2', '4', ' ', ' ',
+		' ', '4', ' ', '3', ' ', '5', ' ', '2', ' ',
+		'4', ' ', ' ', ' ', ' ', ' ', ' ', ' ', '8',
+		'3', ' ', ' ', '4', ' ', '8', ' ', ' ', '2',
+		' ', ' ', '5', ' ', '1', ' ', '3', ' ', ' '}
+	var buf bytes.Buffer
+	err := puz.EncodeDIMACS(&buf)
+	if err != nil {
+		t.Fatalf("EncodeDIMACS failed: %v", err)
+	}
+	vars, clauses := parseDIMACS(t, buf.Bytes())
+	if vars != NumVariables {
+		t.Errorf("incorrect variable count in DIMACS output: expected %v, got %v", NumVariables, vars)
+	}
+	givens := GridSize - puz.NumUnknowns()
+	expect := (GridSize+Size*3*Size)*(1+Size*(Size-1)/2) + givens
+	if len(clauses) != expect {
+		t.Errorf("incorrect clause count in DIMACS output: expected %v, got %v", expect, len(clauses))
+	}
+
+	sol := puz
+	sol.Solve()
+	if !satisfies(&sol, clauses) {
+		t.Errorf("solution does not satisfy the encoded clauses:\n%v", sol.String())
+	}
+	wrong := sol
+	wrong[0], wrong[1] = wrong[1], wrong[0]
+	if satisfies(&wrong, clauses) {
+		t.Errorf("invalid grid satisfies the encoded clauses:\n%v", wrong.String())
+	}
+}
+
+func TestDecodeModel(t *testing.T) {
+	// “Easy” difficulty
+	puz := Puzzle{
+		'2', ' ', ' ', '6', '3', ' ', ' ', '1', ' ',
+		' ', '5', '1', ' ', '2', ' ', '7', '9', '3',
+		'4', ' ', '3', '1', '9', '7', '5', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', '9', ' ', '3', '2',
+		' ', '6', '5', ' ', '7', ' ', '1', '4', ' ',
+		'1', '3', ' ', '8', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', '9', '3', '6', '2', '4', ' ', '7',
+		'3', '7', '6', ' ', '8', ' ', '2', '5', ' ',
+		' ', '2', ' ', ' ', '5', '1', ' ', ' ', '9'}
+	sol := puz
+	sol.Solve()
+
+	var result Puzzle
+	err := result.DecodeModel(strings.NewReader(model(&sol)))
+	if err != nil {
+		t.Errorf("DecodeModel failed on valid model: %v", err)
+	}
+	if !result.Equal(sol) {
+		t.Errorf("incorrect result from DecodeModel: expected:\n%v\n\ngot:\n%v", sol.String(), result.String())
+	}
+
+	// MiniSat result file format, with a partial assignment.
+	err = result.DecodeModel(strings.NewReader("SAT\n1 -2 11 0\n"))
+	if err != nil {
+		t.Errorf("DecodeModel failed on MiniSat model: %v", err)
+	}
+	if result[0] != '1' || result[1] != '2' || result.NumUnknowns() != GridSize-2 {
+		t.Errorf("incorrect result from DecodeModel for MiniSat model:\n%v", result.String())
+	}
+
+	bad := []string{
+		"s UNSATISFIABLE\n",
+		"UNSAT\n",
+		"v 1 x 0\n",
+		"v 730 0\n",
+		"v 1 2 0\n",
+	}
+	for _, input := range bad {
+		orig := result
+		if result.DecodeModel(strings.NewReader(input)) == nil {
+			t.Errorf("no error from DecodeModel for %q", input)
+		}
+		if !result.Equal(orig) {
+			t.Errorf("puzzle modified by failed DecodeModel for %q", input)
+		}
+	}
+}
+
+func TestGridEncodeDIMACS(t *testing.T) {
+	constraints, err := VariantConstraints(Classic, []Rule{DiagonalRule, AntiKnightRule})
+	if err != nil {
+		t.Fatalf("unexpected error from VariantConstraints: %v", err)
+	}
+	solution, err := GenerateVariant(Classic, constraints)
+	if err != nil || solution == nil {
+		t.Fatalf("unexpected result from GenerateVariant: %v, %v", solution, err)
+	}
+	puzzle := solution.ApplyMask(solution.MinimalMask())
+	var buf bytes.Buffer
+	if err := puzzle.EncodeDIMACS(&buf); err != nil {
+		t.Fatalf("EncodeDIMACS failed: %v", err)
+	}
+	vars, clauses := parseDIMACS(t, buf.Bytes())
+	if vars != NumVariables {
+		t.Errorf("incorrect variable count in DIMACS output: expected %v, got %v", NumVariables, vars)
+	}
+
+	// The solution satisfies every clause.
+	for _, clause := range clauses {
+		sat := false
+		for _, lit := range clause {
+			v := lit
+			if v < 0 {
+				v = -v
+			}
+			index, glyph := variableCell(v)
+			if (solution.Cells[index] == glyph) == (lit > 0) {
+				sat = true
+				break
+			}
+		}
+		if !sat {
+			t.Errorf("solution does not satisfy clause %v", clause)
+		}
+	}
+
+	// A knight's move apart, R1C1 and R2C3 cannot both hold a 5.
+	expect := fmt.Sprint([]int{-Variable(CellRef{0, 0}, '5'), -Variable(CellRef{1, 2}, '5')})
+	found := false
+	for _, clause := range clauses {
+		if fmt.Sprint(clause) == expect {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("incorrect result from EncodeDIMACS: expected clause %v", expect)
+	}
+
+	puzzle.Constraints = append(puzzle.Constraints, Cage{Sum: 3, Members: []int{0, 1}})
+	if _, err := puzzle.Clauses(); err == nil {
+		t.Errorf("expected error from Clauses for a cage")
+	}
+}
@@ -0,0 +1,527 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// EdgeKind identifies a clue on the border between two orthogonally adjacent
+// cells.  Each kind is written as a single character in the format read by
+// ParseEdges.
+type EdgeKind byte
+
+const (
+	// WhiteDot (Kropki) requires values which differ by one.
+	WhiteDot EdgeKind = 'w'
+	// BlackDot (Kropki) requires values of which one is double the other.
+	BlackDot EdgeKind = 'b'
+	// XEdge requires values which add up to 10.
+	XEdge EdgeKind = 'x'
+	// VEdge requires values which add up to 5.
+	VEdge EdgeKind = 'v'
+	// LessThan requires the first cell of the edge to hold the smaller
+	// value, and GreaterThan the larger.
+	LessThan    EdgeKind = '<'
+	GreaterThan EdgeKind = '>'
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case WhiteDot:
+		return "white dot"
+	case BlackDot:
+		return "black dot"
+	case XEdge:
+		return "X"
+	case VEdge:
+		return "V"
+	case LessThan:
+		return "less-than"
+	case GreaterThan:
+		return "greater-than"
+	default:
+		return fmt.Sprintf("EdgeKind(%q)", byte(k))
+	}
+}
+
+// allowed returns whether the kind of edge allows the values 'a' in its first
+// cell and 'b' in its second.
+func (k EdgeKind) allowed(a, b int) bool {
+	switch k {
+	case WhiteDot:
+		return a-b == 1 || b-a == 1
+	case BlackDot:
+		return a == 2*b || b == 2*a
+	case XEdge:
+		return a+b == 10
+	case VEdge:
+		return a+b == 5
+	case LessThan:
+		return a < b
+	case GreaterThan:
+		return a > b
+	}
+	return false
+}
+
+// EdgeFamily names a group of edge kinds which are used together.
+type EdgeFamily string
+
+const (
+	// KropkiEdges are the white and black dots.
+	KropkiEdges EdgeFamily = "kropki"
+	// XVEdges are the X and V clues.
+	XVEdges EdgeFamily = "xv"
+	// InequalityEdges are the less-than and greater-than signs.
+	InequalityEdges EdgeFamily = "greater-than"
+)
+
+// EdgeFamilies lists the supported edge families.
+var EdgeFamilies = []EdgeFamily{KropkiEdges, XVEdges, InequalityEdges}
+
+// Kinds returns the kinds of edge in the family, or nil if the family is
+// unknown.
+func (f EdgeFamily) Kinds() []EdgeKind {
+	switch f {
+	case KropkiEdges:
+		return []EdgeKind{WhiteDot, BlackDot}
+	case XVEdges:
+		return []EdgeKind{XEdge, VEdge}
+	case InequalityEdges:
+		return []EdgeKind{LessThan, GreaterThan}
+	}
+	return nil
+}
+
+// family returns the family of an edge kind.
+func (k EdgeKind) family() EdgeFamily {
+	for _, f := range EdgeFamilies {
+		for _, kind := range f.Kinds() {
+			if kind == k {
+				return f
+			}
+		}
+	}
+	return ""
+}
+
+// Edge is a clue on the border between two orthogonally adjacent cells.
+//
+// Edges should be made with NewEdge, which checks the cells and prepares the
+// relation between them.
+type Edge struct {
+	Kind EdgeKind
+	// A and B are the indexes of the cells either side of the border, where
+	// B is the cell to the right of A or below it.
+	A, B     int
+	relation Relation
+}
+
+// NewEdge returns an edge clue of the given kind between two cells of a grid
+// of the given shape.  Cell 'b' must be to the right of cell 'a', or below it.
+func NewEdge(shape Shape, kind EdgeKind, a, b int) (Edge, error) {
+	size := shape.Size()
+	e := Edge{Kind: kind, A: a, B: b}
+	if kind.family() == "" {
+		return e, fmt.Errorf("unknown edge kind %q", byte(kind))
+	}
+	if a < 0 || b >= size*size || !((b == a+1 && b%size != 0) || b == a+size) {
+		return e, fmt.Errorf("cells %v and %v are not next to each other", indexRef(a, size), indexRef(b, size))
+	}
+	e.relation = NewRelation(kind.String(), shape, [][2]int{{a, b}}, kind.allowed)
+	return e, nil
+}
+
+// Cells returns the two cells either side of the edge.
+func (e Edge) Cells() []int {
+	return []int{e.A, e.B}
+}
+
+// Check returns a *ConstraintError if both cells of the edge are known, and
+// do not obey its clue.
+func (e Edge) Check(g *Grid) error {
+	if err := e.relation.Check(g); err != nil {
+		err.(*ConstraintError).Constraint = e
+		return err
+	}
+	return nil
+}
+
+// Propagate removes the candidates of each cell of the edge which have no
+// candidate in the other cell obeying its clue.
+func (e Edge) Propagate(cand []uint64) bool {
+	return e.relation.Propagate(cand)
+}
+
+// NegativeEdges is the constraint, known as the negative constraint, that
+// every clue of a family is given: any two adjacent cells without an edge of
+// the family between them obey none of its kinds.  For example, with
+// negative Kropki edges, cells without a dot between them are neither
+// consecutive nor in the ratio 1:2.
+type NegativeEdges struct {
+	Family   EdgeFamily
+	relation Relation
+}
+
+// NewNegativeEdges returns the negative constraint for a family of edges,
+// given the edges of a grid of the given shape.  Edges of other families are
+// ignored.  The inequality family has no negative constraint.
+func NewNegativeEdges(shape Shape, family EdgeFamily, edges []Edge) (NegativeEdges, error) {
+	n := NegativeEdges{Family: family}
+	kinds := family.Kinds()
+	if kinds == nil || family == InequalityEdges {
+		return n, fmt.Errorf("no negative constraint for %q edges", family)
+	}
+	marked := make(map[[2]int]bool)
+	for _, e := range edges {
+		if e.Kind.family() == family {
+			marked[[2]int{e.A, e.B}] = true
+		}
+	}
+	var pairs [][2]int
+	for _, p := range movePairs(shape.Size(), rookSteps) {
+		if !marked[p] {
+			pairs = append(pairs, p)
+		}
+	}
+	n.relation = NewRelation(string(family)+" negative", shape, pairs, func(a, b int) bool {
+		for _, k := range kinds {
+			if k.allowed(a, b) {
+				return false
+			}
+		}
+		return true
+	})
+	return n, nil
+}
+
+// Cells returns every cell next to another without an edge of the family.
+func (n NegativeEdges) Cells() []int {
+	return n.relation.Cells()
+}
+
+// Check returns a *ConstraintError for the first pair of known adjacent cells
+// without an edge which obey one of the family's kinds.
+func (n NegativeEdges) Check(g *Grid) error {
+	if err := n.relation.Check(g); err != nil {
+		err.(*ConstraintError).Constraint = n
+		return err
+	}
+	return nil
+}
+
+// Propagate removes the candidates of each cell which would obey one of the
+// family's kinds with every candidate of a neighbour without an edge.
+func (n NegativeEdges) Propagate(cand []uint64) bool {
+	return n.relation.Propagate(cand)
+}
+
+// EdgeConstraints returns the DefaultConstraints for a shape followed by the
+// given edges, and the negative constraint for each of the given families.
+func EdgeConstraints(shape Shape, edges []Edge, negative []EdgeFamily) ([]Constraint, error) {
+	clues, err := edgeConstraints(shape, edges, negative)
+	if err != nil {
+		return nil, err
+	}
+	return append(DefaultConstraints(shape), clues...), nil
+}
+
+// edgeConstraints returns the given edges and the negative constraints for
+// the given families, without the DefaultConstraints.
+func edgeConstraints(shape Shape, edges []Edge, negative []EdgeFamily) ([]Constraint, error) {
+	var result []Constraint
+	for _, e := range edges {
+		result = append(result, e)
+	}
+	for _, f := range negative {
+		n, err := NewNegativeEdges(shape, f, edges)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// Edges returns the edges among the grid's constraints.
+func (g *Grid) Edges() (edges []Edge) {
+	for _, c := range g.Constraints {
+		if e, ok := c.(Edge); ok {
+			edges = append(edges, e)
+		}
+	}
+	return
+}
+
+// NegativeEdges returns the families of the negative constraints among the
+// grid's constraints.
+func (g *Grid) NegativeEdges() (families []EdgeFamily) {
+	for _, c := range g.Constraints {
+		if n, ok := c.(NegativeEdges); ok {
+			families = append(families, n.Family)
+		}
+	}
+	return
+}
+
+// SolutionEdges returns every edge of the given families which the values of
+// a solution grid obey, in grid order.  Where two kinds of a family apply, as
+// with a white or black dot between 1 and 2, the first kind is chosen.
+func SolutionEdges(solution *Grid, families []EdgeFamily) (edges []Edge) {
+	size := solution.Size()
+	for _, p := range movePairs(size, rookSteps) {
+		a := solution.Shape.glyphIndex(solution.Cells[p[0]]) + 1
+		b := solution.Shape.glyphIndex(solution.Cells[p[1]]) + 1
+		for _, f := range families {
+			for _, k := range f.Kinds() {
+				if k.allowed(a, b) {
+					e, _ := NewEdge(solution.Shape, k, p[0], p[1])
+					edges = append(edges, e)
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+// ParseEdges reads edges for a grid of the given shape from a slice of bytes.
+//
+// Each edge is written as the reference of its first cell, then '|' for an
+// edge with the cell to its right or '_' for an edge with the cell below,
+// then the character for its kind: 'w' or 'b' for a white or black dot, 'x'
+// or 'v', or '<' or '>' when the first cell is the smaller or larger.  For
+// example, "R1C1|w R1C1_<" puts a white dot between R1C1 and R1C2, and says
+// that R1C1 is less than R2C1.  Edges are separated by spaces or newlines.
+//
+// A line beginning with the word "negative" names the families which have
+// the negative constraint: "kropki", "xv" or both.  Blank lines and comment
+// lines beginning with '#' are ignored.
+func ParseEdges(input []byte, shape Shape) (edges []Edge, negative []EdgeFamily, err error) {
+	size := shape.Size()
+	for n, line := range bytes.Split(input, []byte("\n")) {
+		if skip(line) {
+			continue
+		}
+		fields := strings.Fields(string(line))
+		if fields[0] == "negative" {
+			for _, f := range fields[1:] {
+				family := EdgeFamily(strings.ToLower(f))
+				if family.Kinds() == nil || family == InequalityEdges {
+					return nil, nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("no negative constraint for %q edges", f)}
+				}
+				negative = append(negative, family)
+			}
+			continue
+		}
+		for _, field := range fields {
+			sep := strings.IndexAny(field, "|_")
+			if sep < 0 || sep != len(field)-2 {
+				return nil, nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("invalid edge %q", field)}
+			}
+			ref, err := parseCellRef(field[:sep], size)
+			if err != nil {
+				return nil, nil, &ParseError{Line: n + 1, Reason: err.Error()}
+			}
+			a := ref.row*size + ref.col
+			b := a + 1
+			if field[sep] == '_' {
+				b = a + size
+			}
+			e, err := NewEdge(shape, EdgeKind(field[sep+1]), a, b)
+			if err != nil {
+				return nil, nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("invalid edge %q: %v", field, err)}
+			}
+			edges = append(edges, e)
+		}
+	}
+	return
+}
+
+// FormatEdges returns the edges and negative constraints of a grid with
+// 'size' rows and columns in the format consumed by ParseEdges, with the
+// edges of each row of the grid on a line of their own.
+func FormatEdges(edges []Edge, negative []EdgeFamily, size int) string {
+	var buf bytes.Buffer
+	if len(negative) > 0 {
+		buf.WriteString("negative")
+		for _, f := range negative {
+			buf.WriteString(" " + string(f))
+		}
+		buf.WriteByte('\n')
+	}
+	row := -1
+	for _, e := range edges {
+		if e.A/size != row && row >= 0 {
+			buf.WriteByte('\n')
+		} else if row >= 0 {
+			buf.WriteByte(' ')
+		}
+		row = e.A / size
+		sep := byte('|')
+		if e.B != e.A+1 {
+			sep = '_'
+		}
+		buf.WriteString(indexRef(e.A, size).String())
+		buf.WriteByte(sep)
+		buf.WriteByte(byte(e.Kind))
+	}
+	if row >= 0 {
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// ParseEdgePuzzle reads a puzzle with edge clues from a slice of bytes in the
+// format written by Grid.EdgePuzzleString: the grid's givens in either the
+// grid or the line format, then a blank line, then its edges in the format
+// consumed by ParseEdges.  The shape is taken from the size of the grid, as
+// in ParseGrid, and the grid's constraints are the EdgeConstraints for its
+// edges.
+func ParseEdgePuzzle(input []byte) (*Grid, error) {
+	return ParsePuzzle(input, EdgeSection)
+}
+
+// EdgePuzzleString returns a representation of the grid and its edges in the
+// format consumed by ParseEdgePuzzle.
+func (g *Grid) EdgePuzzleString() string {
+	return g.PuzzleString(EdgeSection)
+}
+
+// EdgeSection is the Section for edge clues and negative constraints, in the
+// format read by ParseEdges.
+var EdgeSection = Section{
+	Name: "edges",
+	Parse: func(input []byte, shape Shape) ([]Constraint, error) {
+		edges, negative, err := ParseEdges(input, shape)
+		if err != nil {
+			return nil, err
+		}
+		return edgeConstraints(shape, edges, negative)
+	},
+	Format: func(g *Grid) string {
+		return FormatEdges(g.Edges(), g.NegativeEdges(), g.Size())
+	},
+}
+
+// GenerateEdgePuzzle returns a puzzle for a solution grid whose clues are
+// givens and edges of the given families.  Its constraints are those of the
+// solution followed by the edges, and by the negative constraint for each
+// family if 'negative' is true.
+//
+// Starting from every given and every edge which the solution obeys, clues
+// are removed in random order for as long as the solution stays unique.  If
+// 'givens' is false, the givens are removed first, so that the edges take
+// their place as far as possible; otherwise givens and edges are removed
+// together.  Under the negative constraint, the edges of the family cannot be
+// removed, since their absence is a clue in itself.
+func GenerateEdgePuzzle(solution *Grid, families []EdgeFamily, negative, givens bool) (*Grid, error) {
+	return GenerateEdgePuzzleContext(context.Background(), solution, families, negative, givens)
+}
+
+// GenerateEdgePuzzleContext is like GenerateEdgePuzzle, but stops removing
+// clues when the context is cancelled or its deadline expires.
+//
+// In that case it returns ctx.Err() along with the puzzle reached so far,
+// which still has a unique solution.
+func GenerateEdgePuzzleContext(ctx context.Context, solution *Grid, families []EdgeFamily, negative, givens bool) (*Grid, error) {
+	var neg []EdgeFamily
+	if negative {
+		for _, f := range families {
+			if f != InequalityEdges {
+				neg = append(neg, f)
+			}
+		}
+	}
+	all := SolutionEdges(solution, families)
+	kept := make([]bool, len(all))
+	for k := range kept {
+		kept[k] = true
+	}
+	base := solution.constraints()
+	puzzle := solution.Copy()
+	update := func() error {
+		var edges []Edge
+		for k, e := range all {
+			if kept[k] {
+				edges = append(edges, e)
+			}
+		}
+		puzzle.Constraints = append([]Constraint(nil), base...)
+		for _, e := range edges {
+			puzzle.Constraints = append(puzzle.Constraints, e)
+		}
+		for _, f := range neg {
+			n, err := NewNegativeEdges(solution.Shape, f, edges)
+			if err != nil {
+				return err
+			}
+			puzzle.Constraints = append(puzzle.Constraints, n)
+		}
+		return nil
+	}
+	if err := update(); err != nil {
+		return nil, err
+	}
+
+	// Each clue is a cell index, or an edge number offset by the number of
+	// cells.
+	cells := len(solution.Cells)
+	var clues []int
+	for i, glyph := range solution.Cells {
+		if solution.Shape.Known(glyph) {
+			clues = append(clues, i)
+		}
+	}
+	var edgeClues []int
+	for k, e := range all {
+		fixed := false
+		for _, f := range neg {
+			fixed = fixed || e.Kind.family() == f
+		}
+		if !fixed {
+			edgeClues = append(edgeClues, cells+k)
+		}
+	}
+	random := newRand()
+	if givens {
+		clues = append(clues, edgeClues...)
+		random.Shuffle(len(clues), func(i, j int) {
+			clues[i], clues[j] = clues[j], clues[i]
+		})
+	} else {
+		random.Shuffle(len(clues), func(i, j int) {
+			clues[i], clues[j] = clues[j], clues[i]
+		})
+		random.Shuffle(len(edgeClues), func(i, j int) {
+			edgeClues[i], edgeClues[j] = edgeClues[j], edgeClues[i]
+		})
+		clues = append(clues, edgeClues...)
+	}
+
+	for _, c := range clues {
+		var glyph byte
+		if c < cells {
+			glyph = puzzle.Cells[c]
+			puzzle.Cells[c] = Unknown
+		} else {
+			kept[c-cells] = false
+			update()
+		}
+		n, err := puzzle.NumSolutionsContext(ctx)
+		if err != nil || n != 1 {
+			if c < cells {
+				puzzle.Cells[c] = glyph
+			} else {
+				kept[c-cells] = true
+				update()
+			}
+		}
+		if err != nil {
+			return puzzle, err
+		}
+	}
+	return puzzle, nil
+}
@@ -0,0 +1,221 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewEdge(t *testing.T) {
+	cases := []struct {
+		kind EdgeKind
+		a, b int
+		err  string
+	}{
+		{WhiteDot, 0, 1, ""},
+		{LessThan, 0, 9, ""},
+		{BlackDot, 8, 9, "cells R1C9 and R2C1 are not next to each other"},
+		{XEdge, 0, 10, "cells R1C1 and R2C2 are not next to each other"},
+		{VEdge, 72, 81, "cells R9C1 and R10C1 are not next to each other"},
+		{EdgeKind('?'), 0, 1, `unknown edge kind '?'`},
+	}
+	for _, c := range cases {
+		_, err := NewEdge(Classic, c.kind, c.a, c.b)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("unexpected error from NewEdge(%v, %v, %v): %v", c.kind, c.a, c.b, err)
+		case c.err != "" && (err == nil || err.Error() != c.err):
+			t.Errorf("incorrect error from NewEdge(%v, %v, %v): expected %q, got %v", c.kind, c.a, c.b, c.err, err)
+		}
+	}
+}
+
+func TestEdgeCheck(t *testing.T) {
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(killerSolution))
+	edges := SolutionEdges(g, EdgeFamilies)
+	for _, e := range edges {
+		if err := e.Check(g); err != nil {
+			t.Errorf("unexpected error from Check for %v: %v", e.Kind, err)
+		}
+	}
+	// Every pair of neighbours is either less or greater.
+	if n := len(edges); n < 144 {
+		t.Errorf("incorrect number of edges from SolutionEdges: expected at least 144, got %v", n)
+	}
+
+	// R1C1 holds 9 and R1C2 holds 3.
+	cases := []struct {
+		kind   EdgeKind
+		expect string
+	}{
+		{WhiteDot, "invalid puzzle: white dot rule broken by '9' and '3' at R1C1, R1C2"},
+		{BlackDot, "invalid puzzle: black dot rule broken by '9' and '3' at R1C1, R1C2"},
+		{XEdge, "invalid puzzle: X rule broken by '9' and '3' at R1C1, R1C2"},
+		{LessThan, "invalid puzzle: less-than rule broken by '9' and '3' at R1C1, R1C2"},
+	}
+	for _, c := range cases {
+		e, _ := NewEdge(Classic, c.kind, 0, 1)
+		err := e.Check(g)
+		if err == nil || err.Error() != c.expect {
+			t.Errorf("incorrect result from Check: expected %q, got %v", c.expect, err)
+		}
+	}
+
+	// Without any dots, the negative constraint forbids the 3 and 6 in R1C2
+	// and R1C3, one double the other.
+	n, err := NewNegativeEdges(Classic, KropkiEdges, nil)
+	if err != nil {
+		t.Fatalf("unexpected error from NewNegativeEdges: %v", err)
+	}
+	expect := "invalid puzzle: kropki negative rule broken by '3' and '6' at R1C2, R1C3"
+	if err := n.Check(g); err == nil || err.Error() != expect {
+		t.Errorf("incorrect result from Check: expected %q, got %v", expect, err)
+	}
+	n, _ = NewNegativeEdges(Classic, KropkiEdges, edges)
+	if err := n.Check(g); err != nil {
+		t.Errorf("unexpected error from Check with every dot: %v", err)
+	}
+	if _, err := NewNegativeEdges(Classic, InequalityEdges, nil); err == nil {
+		t.Errorf("expected error from NewNegativeEdges for inequalities")
+	}
+}
+
+func TestEdgePropagate(t *testing.T) {
+	cand := make([]uint64, 81)
+	for i := range cand {
+		cand[i] = 0x1ff
+	}
+	// A black dot next to a 5 is impossible, and next to a 4 needs 2 or 8.
+	black, _ := NewEdge(Classic, BlackDot, 0, 1)
+	cand[0] = 1 << 3
+	black.Propagate(cand)
+	if expected := uint64(1<<1 | 1<<7); cand[1] != expected {
+		t.Errorf("incorrect black dot candidates: expected %09b, got %09b", expected, cand[1])
+	}
+	cand[0] = 1 << 4
+	if black.Propagate(cand) {
+		t.Errorf("expected failure from Propagate for a 5 on a black dot")
+	}
+
+	// Without an X or V, a 5 cannot sit next to a 5, nor a 1 next to a 4 or 9.
+	n, _ := NewNegativeEdges(Classic, XVEdges, nil)
+	for i := range cand {
+		cand[i] = 0x1ff
+	}
+	cand[40] = 1 << 0
+	n.Propagate(cand)
+	if expected := uint64(0x1ff &^ (1<<3 | 1<<8)); cand[41] != expected || cand[31] != expected {
+		t.Errorf("incorrect negative XV candidates: expected %09b, got %09b and %09b", expected, cand[41], cand[31])
+	}
+}
+
+func TestParseEdgePuzzle(t *testing.T) {
+	input := "negative kropki\nR1C1|w R1C1_<\nR2C5|x R2C5_b\n"
+	edges, negative, err := ParseEdges([]byte(input), Classic)
+	if err != nil {
+		t.Fatalf("unexpected error from ParseEdges: %v", err)
+	}
+	if len(edges) != 4 || len(negative) != 1 || negative[0] != KropkiEdges {
+		t.Fatalf("incorrect result from ParseEdges: got %v and %v", edges, negative)
+	}
+	if e := edges[3]; e.Kind != BlackDot || e.A != 13 || e.B != 22 {
+		t.Errorf("incorrect edge from ParseEdges: expected black dot from 13 to 22, got %v from %v to %v", e.Kind, e.A, e.B)
+	}
+	if result := FormatEdges(edges, negative, Size); result != input {
+		t.Errorf("incorrect result from FormatEdges: expected %q, got %q", input, result)
+	}
+
+	errors := []struct {
+		input string
+		line  int
+	}{
+		{"R1C1|w\nR1C9|w\n", 2},
+		{"R1C1w\n", 1},
+		{"R1C1|q\n", 1},
+		{"negative greater-than\n", 1},
+	}
+	for _, c := range errors {
+		_, _, err := ParseEdges([]byte(c.input), Classic)
+		if e, ok := err.(*ParseError); !ok || e.Line != c.line {
+			t.Errorf("expected ParseError on line %v from ParseEdges(%q), got %v", c.line, c.input, err)
+		}
+	}
+
+	g, err := ParseEdgePuzzle([]byte(strings.Repeat(".", 81) + "\n\n" + input))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseEdgePuzzle: %v", err)
+	}
+	if len(g.Edges()) != 4 || len(g.NegativeEdges()) != 1 {
+		t.Errorf("incorrect constraints from ParseEdgePuzzle: got %v", g.Constraints[27:])
+	}
+	if result := g.EdgePuzzleString(); !strings.HasSuffix(result, "\n\n"+input) {
+		t.Errorf("incorrect result from EdgePuzzleString: got %q", result)
+	}
+}
+
+func TestGenerateEdgePuzzle(t *testing.T) {
+	solution := NewGrid(Classic)
+	solution.ReadLine([]byte(killerSolution))
+	cases := []struct {
+		families []EdgeFamily
+		negative bool
+		givens   bool
+	}{
+		{[]EdgeFamily{KropkiEdges}, true, false},
+		{[]EdgeFamily{XVEdges, InequalityEdges}, false, false},
+		{[]EdgeFamily{InequalityEdges}, false, true},
+	}
+	for _, c := range cases {
+		puzzle, err := GenerateEdgePuzzle(solution, c.families, c.negative, c.givens)
+		if err != nil {
+			t.Fatalf("unexpected error from GenerateEdgePuzzle(%v): %v", c.families, err)
+		}
+		if err := puzzle.Validate(); err != nil {
+			t.Errorf("invalid result from GenerateEdgePuzzle(%v): %v", c.families, err)
+		}
+		if n := puzzle.NumSolutions(); n != 1 {
+			t.Errorf("incorrect number of solutions for GenerateEdgePuzzle(%v): expected 1, got %v", c.families, n)
+		}
+		if len(puzzle.Edges()) == 0 {
+			t.Errorf("no edges from GenerateEdgePuzzle(%v)", c.families)
+		}
+		if n := len(puzzle.NegativeEdges()); c.negative != (n > 0) {
+			t.Errorf("incorrect negative constraints from GenerateEdgePuzzle(%v): got %v", c.families, n)
+		}
+		if !c.givens && puzzle.NumUnknowns() < 60 {
+			t.Errorf("too many givens from GenerateEdgePuzzle(%v): %v", c.families, 81-puzzle.NumUnknowns())
+		}
+	}
+}
+
+func TestRenderEdges(t *testing.T) {
+	edges, _, _ := ParseEdges([]byte("R1C1|w R1C2|b R2C1|x R3C3_<\n"), Classic)
+	var puz Puzzle
+	var buf bytes.Buffer
+	if err := puz.RenderSVG(&buf, &RenderOptions{Edges: edges}); err != nil {
+		t.Fatalf("unexpected error from RenderSVG: %v", err)
+	}
+	out := buf.String()
+	expect := []string{
+		`<circle cx="60" cy="40" r="4.8" fill="#ffffff" stroke="#000000"`,
+		`<circle cx="100" cy="40" r="4.8" fill="#000000"/>`,
+		`font-weight="bold" text-anchor="middle" dominant-baseline="central" fill="#000000">X</text>`,
+	}
+	for _, e := range expect {
+		if !strings.Contains(out, e) {
+			t.Errorf("incorrect result from RenderSVG: expected output to contain %q, got:\n%v", e, out)
+		}
+	}
+	if n, expected := strings.Count(out, "<line"), 2*(Size+1)+2; n != expected {
+		t.Errorf("incorrect number of lines from RenderSVG: expected %v, got %v", expected, n)
+	}
+	// The dots and the masks behind X and V cover the grid lines.
+	last := strings.LastIndex(out, "<line")
+	if k := strings.Index(out, "<circle"); k < last {
+		t.Errorf("incorrect order from RenderSVG: expected circles after the last line")
+	}
+	if k := strings.Index(out, `<rect x="54"`); k < last {
+		t.Errorf("incorrect order from RenderSVG: expected the mask of the X after the last line")
+	}
+}
@@ -0,0 +1,124 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError describes a problem with puzzle input.
+type ParseError struct {
+	// Line is the 1-based line number where the problem was found, or zero
+	// if the problem concerns the input as a whole.
+	Line int
+	// Column is the 1-based byte position within the line where the problem
+	// was found, or zero if the problem concerns the whole line.
+	Column int
+	// Byte is the offending input byte, if the problem concerns a single
+	// byte, or the null byte otherwise.
+	Byte byte
+	// Reason describes what was wrong.
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("malformed input: %v", e.Reason)
+	case e.Column == 0:
+		return fmt.Sprintf("malformed input on line %v: %v", e.Line, e.Reason)
+	default:
+		return fmt.Sprintf("malformed input on line %v, column %v: %v", e.Line, e.Column, e.Reason)
+	}
+}
+
+// UnitType identifies a kind of unit: a group of cells which may not contain
+// the same glyph twice.
+type UnitType int
+
+const (
+	RowUnit UnitType = iota
+	ColumnUnit
+	SubGridUnit
+	// RegionUnit is an irregular region of a jigsaw grid, which takes the
+	// place of a subgrid.
+	RegionUnit
+	// DiagonalUnit, WindowUnit and DisjointUnit are the extra units of the
+	// variant rules; see Rule.
+	DiagonalUnit
+	WindowUnit
+	DisjointUnit
+)
+
+func (u UnitType) String() string {
+	switch u {
+	case RowUnit:
+		return "row"
+	case ColumnUnit:
+		return "column"
+	case SubGridUnit:
+		return "subgrid"
+	case RegionUnit:
+		return "region"
+	case DiagonalUnit:
+		return "diagonal"
+	case WindowUnit:
+		return "window"
+	case DisjointUnit:
+		return "disjoint group"
+	}
+	return fmt.Sprintf("UnitType(%d)", int(u))
+}
+
+// ConflictError describes a glyph which appears more than once in a unit.
+type ConflictError struct {
+	// Unit is the type of the unit containing the conflict.
+	Unit UnitType
+	// Index is the 0-based index of the unit, as used by Row, Column and
+	// SubGrid.
+	Index int
+	// Glyph is the duplicated glyph.
+	Glyph byte
+	// Cells lists every cell in the unit which contains the glyph, in grid
+	// order.
+	Cells []CellRef
+}
+
+func (e *ConflictError) Error() string {
+	refs := make([]string, len(e.Cells))
+	for i := range e.Cells {
+		refs[i] = e.Cells[i].String()
+	}
+	return fmt.Sprintf("invalid puzzle: duplicate %q in %v %v at %v", e.Glyph, e.Unit, e.Index+1, strings.Join(refs, ", "))
+}
+
+// ConstraintError describes a constraint, other than a unit, which is broken
+// by the known cells of a grid.
+type ConstraintError struct {
+	// Constraint is the broken constraint.
+	Constraint Constraint
+	// Cells lists the cells which break the constraint, in grid order.
+	Cells []CellRef
+	// Reason describes how the constraint is broken.
+	Reason string
+}
+
+func (e *ConstraintError) Error() string {
+	refs := make([]string, len(e.Cells))
+	for i := range e.Cells {
+		refs[i] = e.Cells[i].String()
+	}
+	return fmt.Sprintf("invalid puzzle: %v at %v", e.Reason, strings.Join(refs, ", "))
+}
+
+// GridError describes a problem with one grid of a Multi.
+type GridError struct {
+	// Grid is the 0-based index of the grid, in the order of its layout.
+	Grid int
+	// Err describes the problem, with cells referred to by their position
+	// within the grid.
+	Err error
+}
+
+func (e *GridError) Error() string {
+	return fmt.Sprintf("grid %v: %v", e.Grid+1, e.Err)
+}
@@ -0,0 +1,39 @@
+package sudoku
+
+import "testing"
+
+func TestParseErrorString(t *testing.T) {
+	cases := []struct {
+		err    ParseError
+		expect string
+	}{
+		{ParseError{Reason: "expected 9 lines, got 2"}, "malformed input: expected 9 lines, got 2"},
+		{ParseError{Line: 3, Reason: "expected 17 bytes, got 15"}, "malformed input on line 3: expected 17 bytes, got 15"},
+		{ParseError{Line: 6, Column: 9, Byte: 'A', Reason: "bad"}, "malformed input on line 6, column 9: bad"},
+	}
+	for _, c := range cases {
+		result := c.err.Error()
+		if result != c.expect {
+			t.Errorf("incorrect result from ParseError.Error: expected %q, got %q", c.expect, result)
+		}
+	}
+}
+
+func TestConflictErrorString(t *testing.T) {
+	err := ConflictError{SubGridUnit, 8, '1', []CellRef{{6, 8}, {7, 8}}}
+	expect := "invalid puzzle: duplicate '1' in subgrid 9 at R7C9, R8C9"
+	result := err.Error()
+	if result != expect {
+		t.Errorf("incorrect result from ConflictError.Error: expected %q, got %q", expect, result)
+	}
+}
+
+func TestUnitTypeString(t *testing.T) {
+	expect := []string{"row", "column", "subgrid", "region", "diagonal", "window", "disjoint group", "UnitType(7)"}
+	for i, e := range expect {
+		result := UnitType(i).String()
+		if result != e {
+			t.Errorf("incorrect result from UnitType.String for %v: expected %q, got %q", i, e, result)
+		}
+	}
+}
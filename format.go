@@ -0,0 +1,278 @@
+package sudoku
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a codec for reading and writing single puzzles in a particular
+// text format.
+type Format interface {
+	// Name returns the short name by which the format is selected.
+	Name() string
+	// Extensions returns the file name extensions conventionally used for
+	// the format, including the leading dot.
+	Extensions() []string
+	// Decode reads a puzzle from the input.  Formats which are only used for
+	// presentation return ErrEncodeOnly.
+	Decode(input []byte) (Puzzle, error)
+	// Encode writes a puzzle to 'w'.
+	Encode(w io.Writer, puz *Puzzle) error
+}
+
+var formats []Format
+
+// ErrEncodeOnly is returned by the Decode method of formats which can only be
+// written.
+var ErrEncodeOnly = errors.New("format cannot be decoded")
+
+// RegisterFormat makes a format available to LookupFormat and FormatForFile.
+// If a format with the same name has already been registered, it is
+// replaced.
+func RegisterFormat(f Format) {
+	for i := range formats {
+		if formats[i].Name() == f.Name() {
+			formats[i] = f
+			return
+		}
+	}
+	formats = append(formats, f)
+}
+
+// Formats returns all of the registered formats, in order of registration.
+func Formats() []Format {
+	return append([]Format(nil), formats...)
+}
+
+// LookupFormat returns the registered format with the given name, or nil if
+// there is none.
+func LookupFormat(name string) Format {
+	for _, f := range formats {
+		if f.Name() == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// FormatForFile returns the first registered format which uses the extension
+// of the given file name, or nil if there is none.  Extensions are compared
+// without regard to case.
+func FormatForFile(path string) Format {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return nil
+	}
+	for _, f := range formats {
+		for _, e := range f.Extensions() {
+			if e == ext {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterFormat(gridFormat{})
+	RegisterFormat(lineFormat{})
+	RegisterFormat(sdkFormat{})
+	RegisterFormat(ssFormat{})
+	RegisterFormat(sdmFormat{})
+	RegisterFormat(pencilMarkFormat{})
+	RegisterFormat(HTMLFormat{})
+	RegisterFormat(LaTeXFormat{})
+}
+
+// gridFormat is the nine-line format of Puzzle.Read and Puzzle.String.
+type gridFormat struct{}
+
+func (gridFormat) Name() string         { return "grid" }
+func (gridFormat) Extensions() []string { return []string{".txt"} }
+
+func (gridFormat) Decode(input []byte) (puz Puzzle, err error) {
+	err = puz.Read(input)
+	return
+}
+
+func (gridFormat) Encode(w io.Writer, puz *Puzzle) error {
+	_, err := io.WriteString(w, puz.String())
+	return err
+}
+
+// lineFormat is the single-line format of Puzzle.ReadLine and Puzzle.Line.
+type lineFormat struct{}
+
+func (lineFormat) Name() string         { return "line" }
+func (lineFormat) Extensions() []string { return nil }
+
+func (lineFormat) Decode(input []byte) (puz Puzzle, err error) {
+	err = puz.ReadLine(input)
+	return
+}
+
+func (lineFormat) Encode(w io.Writer, puz *Puzzle) error {
+	_, err := io.WriteString(w, puz.Line()+"\n")
+	return err
+}
+
+// readGlyphs collects the 81 cells of a puzzle from input in which each cell
+// is a single byte, ignoring whitespace and any of the bytes in 'ignore'.
+// Known glyphs are taken as given, and any of the bytes in 'blank' indicate an
+// unknown cell.  Lines beginning with '#' are treated as comments.
+func readGlyphs(input []byte, ignore, blank string) (puz Puzzle, err error) {
+	i := 0
+	for n, line := range bytes.Split(input, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) > 0 && line[0] == '#' {
+			continue
+		}
+		for c, glyph := range line {
+			switch {
+			case glyph == ' ' || glyph == '\t' || strings.IndexByte(ignore, glyph) >= 0:
+				continue
+			case i >= GridSize:
+				return puz, &ParseError{Line: n + 1, Column: c + 1, Byte: glyph, Reason: fmt.Sprintf("more than %v cells", GridSize)}
+			case Known(glyph):
+				puz[i] = glyph
+			case strings.IndexByte(blank, glyph) >= 0:
+				puz[i] = Unknown
+			default:
+				return puz, &ParseError{Line: n + 1, Column: c + 1, Byte: glyph, Reason: fmt.Sprintf("expected one of %q or digit 1-9, got %q", blank, glyph)}
+			}
+			i++
+		}
+	}
+	if i < GridSize {
+		return puz, &ParseError{Reason: fmt.Sprintf("expected %v cells, got %v", GridSize, i)}
+	}
+	return
+}
+
+// sdkFormat is the SadMan Sudoku format: nine lines of nine glyphs, with '.'
+// for unknown cells, optionally preceded by '#' comment lines holding
+// metadata.
+type sdkFormat struct{}
+
+func (sdkFormat) Name() string         { return "sdk" }
+func (sdkFormat) Extensions() []string { return []string{".sdk"} }
+
+func (sdkFormat) Decode(input []byte) (Puzzle, error) {
+	return readGlyphs(input, "", ".0")
+}
+
+func (sdkFormat) Encode(w io.Writer, puz *Puzzle) error {
+	line := puz.Line()
+	var buf bytes.Buffer
+	for r := 0; r < Size; r++ {
+		buf.WriteString(line[r*Size : (r+1)*Size])
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ssFormat is the Simple Sudoku format: nine lines of nine glyphs, with '.'
+// or 'X' for unknown cells, '|' separating the subgrids within a row, and a
+// line of '-' separating each band of subgrids.
+//
+// E.g.,
+// ..8|..6|25.
+// ...|.7.|.3.
+// ...|.12|98.
+// -----------
+// ..5|..3|...
+// .2.|7.1|.6.
+// ...|8..|1..
+// -----------
+// .36|28.|...
+// .7.|.9.|...
+// .82|1..|4..
+type ssFormat struct{}
+
+func (ssFormat) Name() string         { return "ss" }
+func (ssFormat) Extensions() []string { return []string{".ss"} }
+
+func (ssFormat) Decode(input []byte) (Puzzle, error) {
+	return readGlyphs(input, "|-+", ".Xx0")
+}
+
+func (ssFormat) Encode(w io.Writer, puz *Puzzle) error {
+	line := puz.Line()
+	var buf bytes.Buffer
+	for r := 0; r < Size; r++ {
+		if r > 0 && r%SubSize == 0 {
+			buf.WriteString(strings.Repeat("-", Size+SubSize-1))
+			buf.WriteByte('\n')
+		}
+		for c := 0; c < Size; c++ {
+			if c > 0 && c%SubSize == 0 {
+				buf.WriteByte('|')
+			}
+			buf.WriteByte(line[r*Size+c])
+		}
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// sdmFormat is the SadMan Sudoku multi-puzzle format: one puzzle per line in
+// the line format, with '0' for unknown cells.
+//
+// Decode reads the first puzzle in the input; use PuzzleReader to read them
+// all.
+type sdmFormat struct{}
+
+func (sdmFormat) Name() string         { return "sdm" }
+func (sdmFormat) Extensions() []string { return []string{".sdm"} }
+
+func (sdmFormat) Decode(input []byte) (puz Puzzle, err error) {
+	pr := NewPuzzleReader(bytes.NewReader(input))
+	if pr.Scan() {
+		return pr.Puzzle(), nil
+	}
+	if err = pr.Err(); err == nil {
+		err = &ParseError{Reason: "no puzzle found"}
+	}
+	return
+}
+
+func (sdmFormat) Encode(w io.Writer, puz *Puzzle) error {
+	_, err := io.WriteString(w, strings.Replace(puz.Line(), ".", "0", -1)+"\n")
+	return err
+}
+
+// pencilMarkFormat is the pencil-mark grid format used by HoDoKu and Sudoku
+// Explainer, in which every cell lists its candidate digits and the subgrids
+// are drawn with '|', ':', '.', apostrophes, '+' and '-'.  A cell listing a
+// single digit is solved, so an unknown cell with only one candidate reads
+// back as solved.
+//
+// E.g., the first rows of a grid:
+// .----------------.----------------.------------------.
+// | 1    29   4    | 5    389  6    | 378  2378  2378  |
+// | 6    8    3    | 2    7    14   | 5    19    149   |
+// :----------------+----------------+------------------:
+type pencilMarkFormat struct{}
+
+func (pencilMarkFormat) Name() string         { return "pencilmark" }
+func (pencilMarkFormat) Extensions() []string { return nil }
+
+func (pencilMarkFormat) Decode(input []byte) (puz Puzzle, err error) {
+	var cg CandidateGrid
+	if err = cg.Read(input); err != nil {
+		return
+	}
+	return cg.Puzzle(), nil
+}
+
+func (pencilMarkFormat) Encode(w io.Writer, puz *Puzzle) error {
+	cg := puz.CandidateGrid()
+	_, err := io.WriteString(w, cg.String())
+	return err
+}
@@ -0,0 +1,153 @@
+package sudoku
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLookupFormat(t *testing.T) {
+	for _, name := range []string{"grid", "line", "sdk", "ss", "sdm", "pencilmark", "html", "latex"} {
+		f := LookupFormat(name)
+		if f == nil || f.Name() != name {
+			t.Errorf("incorrect result from LookupFormat(%q): %v", name, f)
+		}
+	}
+	if f := LookupFormat("nonesuch"); f != nil {
+		t.Errorf("incorrect result from LookupFormat for unknown name: expected nil, got %v", f.Name())
+	}
+	if len(Formats()) < 6 {
+		t.Errorf("missing formats from Formats(): got %v", len(Formats()))
+	}
+}
+
+func TestFormatForFile(t *testing.T) {
+	cases := []struct {
+		path   string
+		expect string
+	}{
+		{"puzzles/daily.sdk", "sdk"},
+		{"DAILY.SS", "ss"},
+		{"top95.sdm", "sdm"},
+		{"puzzle.txt", "grid"},
+		{"puzzle.html", "html"},
+		{"column.tex", "latex"},
+		{"puzzle", ""},
+		{"puzzle.xyz", ""},
+	}
+	for _, c := range cases {
+		f := FormatForFile(c.path)
+		name := ""
+		if f != nil {
+			name = f.Name()
+		}
+		if name != c.expect {
+			t.Errorf("incorrect result from FormatForFile(%q): expected %q, got %q", c.path, c.expect, name)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	puz := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' '}
+	for _, f := range Formats() {
+		var buf bytes.Buffer
+		err := f.Encode(&buf, &puz)
+		if err != nil {
+			t.Errorf("Encode failed for format %q: %v", f.Name(), err)
+			continue
+		}
+		result, err := f.Decode(buf.Bytes())
+		if err == ErrEncodeOnly {
+			continue
+		}
+		if err != nil {
+			t.Errorf("Decode failed for format %q: %v\n%v", f.Name(), err, buf.String())
+			continue
+		}
+		if f.Name() == "pencilmark" {
+			// Unknown cells with a single candidate read back as solved.
+			for _, cell := range puz.Unknowns() {
+				candidates := puz.Candidates(cell.row, cell.col)
+				if len(candidates) == 1 && result.GetCell(cell) == candidates[0] {
+					result.SetCell(cell, Unknown)
+				}
+			}
+		}
+		if !result.Equal(puz) {
+			t.Errorf("incorrect round trip for format %q: expected:\n%v\n\ngot:\n%v", f.Name(), puz.String(), result.String())
+		}
+	}
+}
+
+func TestFormatDecode(t *testing.T) {
+	expect := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' '}
+	inputs := []struct {
+		format string
+		input  string
+	}{
+		{"sdk", "#AExample\r\n#DA test puzzle\r\n..8..625.\r\n....7..3.\r\n....1298.\r\n..5..3...\r\n.2.7.1.6.\r\n...8..1..\r\n.3628....\r\n.7..9....\r\n.821..4..\r\n"},
+		{"ss", "XX8|XX6|25X\nXXX|X7X|X3X\nXXX|X12|98X\n-----------\nXX5|XX3|XXX\nX2X|7X1|X6X\nXXX|8XX|1XX\n-----------\nX36|28X|XXX\nX7X|X9X|XXX\nX82|1XX|4XX\n"},
+		{"sdm", "008006250000070030000012980005003000020701060000800100036280000070090000082100400\n" +
+			"100000000000000000000000000000000000000000000000000000000000000000000000000000000\n"},
+		{"pencilmark", ".---------------.---------------.---------------.\n" +
+			"| 14  19  8     | 349 34  6     | 2   5   47    |\n" +
+			"| 12  15  12    | 45  7   458   | 56  3   146   |\n" +
+			"| 34  45  347   | 345 1   2     | 9   8   467   |\n" +
+			":---------------+---------------+---------------:\n" +
+			"| 78  49  5     | 46  26  3     | 78  12  1789  |\n" +
+			"| 89  2   349   | 7   45  1     | 35  6   3589  |\n" +
+			"| 36  46  34    | 8   25  59    | 1   27  2579  |\n" +
+			":---------------+---------------+---------------:\n" +
+			"| 45  3   6     | 2   8   457   | 57  19  159   |\n" +
+			"| 15  7   14    | 35  9   45    | 35  12  1256  |\n" +
+			"| 59  8   2     | 1   36  57    | 4   79  35679 |\n" +
+			"'---------------'---------------'---------------'\n"},
+	}
+	for _, c := range inputs {
+		result, err := LookupFormat(c.format).Decode([]byte(c.input))
+		if err != nil {
+			t.Errorf("Decode failed for format %q: %v", c.format, err)
+			continue
+		}
+		if !result.Equal(expect) {
+			t.Errorf("incorrect result from Decode for format %q: expected:\n%v\n\ngot:\n%v", c.format, expect.String(), result.String())
+		}
+	}
+
+	bad := []struct {
+		format string
+		input  string
+	}{
+		{"sdk", "..8..625.\n....7..3.\n"},
+		{"sdk", "..8..625.\n....7..3?\n"},
+		{"ss", strings.Repeat("...|...|...\n", 10)},
+		{"sdm", ""},
+		{"pencilmark", "| 14  1a  8 |\n"},
+	}
+	for _, c := range bad {
+		_, err := LookupFormat(c.format).Decode([]byte(c.input))
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("incorrect error from Decode for format %q on %q: expected *ParseError, got %v", c.format, c.input, err)
+		}
+	}
+}
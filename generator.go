@@ -1,6 +1,7 @@
 package sudoku
 
 import (
+	"context"
 	"math/rand"
 	"time"
 )
@@ -61,48 +62,38 @@ func (puz *Puzzle) SeedSolution(n int, ch chan int) {
 
 // AttemptSolution tries to randomly generate a valid sudoku solution.
 //
-// It does this by attempting to solve the puzzle using a combination of
-// logical elimination and brute-force guesswork starting from a randomly
-// selected unknown cell.
+// It does this by searching for a solution to the puzzle, trying the glyphs
+// for each cell in a random order.
 //
 // The channel receives true if a solution was found, false otherwise.
 func (puz *Puzzle) AttemptSolution(ch chan bool) {
-	// Try simple logical elimination.
-	puz.SolveEasy()
-	if puz.NumUnknowns() == 0 {
-		ch <- true
-		return
+	g := puz.Grid()
+	ok, _ := g.fillRandom(context.Background())
+	if ok {
+		copy(puz[:], g.Cells)
 	}
-	// Select a random cell to start guessing from.
-	random := newRand()
-	r := random.Intn(Size)
-	c := random.Intn(Size)
-	r, c, _ = puz.FindUnknown(r, c)
-	guess := make(chan bool)
-	go puz.guess(r, c, guess)
-	ch <- <-guess
+	ch <- ok
 }
 
 // GenerateSolution returns a randomly generated sudoku solution.
 //
-// It does this by repeatedly seeding an empty puzzle with a set of randomly
-// chosen and located glyphs, and then calling AttemptSolution() on the result.
-// It returns the first such puzzle which is both complete and valid.
+// It is a wrapper for GenerateGrid with the Classic shape.
 func GenerateSolution() (puz Puzzle) {
-	success := make(chan bool)
-	count := make(chan int)
-	n := 27
-	for {
+	puz, _ = GenerateSolutionContext(context.Background())
+	return
+}
+
+// GenerateSolutionContext is like GenerateSolution, but gives up when the
+// context is cancelled or its deadline expires.
+//
+// In that case it returns a cleared puzzle and ctx.Err().
+func GenerateSolutionContext(ctx context.Context) (puz Puzzle, err error) {
+	g, err := GenerateGridContext(ctx, Classic)
+	if err != nil {
 		puz.Clear()
-		go puz.SeedSolution(n, count)
-		if <-count == n {
-			go puz.AttemptSolution(success)
-			if <-success {
-				break
-			}
-		}
+		return
 	}
-	return
+	return g.Puzzle()
 }
 
 // MinimalMask returns a minimal clue mask for the given solution.
@@ -112,38 +103,150 @@ func GenerateSolution() (puz Puzzle) {
 // indicates the position of a clue in the puzzle, while each false value
 // indicates a hidden cell.
 func (puz *Puzzle) MinimalMask() (mask Mask) {
-	var sol Puzzle
-	sol.Merge(*puz)
-	knowns := sol.Knowns()
-	swapper := func(i, j int) {
-		knowns[i], knowns[j] = knowns[j], knowns[i]
+	mask, _ = puz.MinimalMaskContext(context.Background())
+	return
+}
+
+// MinimalMaskContext is like MinimalMask, but stops removing clues when the
+// context is cancelled or its deadline expires.
+//
+// In that case it returns ctx.Err() along with the mask reached so far.  Every
+// clue removed up to that point has been verified, so the mask still yields a
+// puzzle with a unique solution, but it may not be minimal.  The receiver is
+// never modified.
+func (puz *Puzzle) MinimalMaskContext(ctx context.Context) (mask Mask, err error) {
+	m, err := puz.Grid().MinimalMaskContext(ctx)
+	copy(mask[:], m)
+	return
+}
+
+// GenerateGrid returns a randomly generated solution grid of the given shape.
+func GenerateGrid(shape Shape) (*Grid, error) {
+	return GenerateGridContext(context.Background(), shape)
+}
+
+// GenerateGridContext is like GenerateGrid, but gives up when the context is
+// cancelled or its deadline expires, in which case it returns ctx.Err().
+//
+// The grid is filled by searching for a solution to the empty grid, trying
+// the glyphs for each cell in a random order.
+func GenerateGridContext(ctx context.Context, shape Shape) (*Grid, error) {
+	return GenerateVariantContext(ctx, shape, nil)
+}
+
+// GenerateVariant returns a randomly generated solution grid of the given
+// shape which obeys the given constraints; see Grid.Constraints.  It returns
+// nil if the constraints cannot be satisfied.
+func GenerateVariant(shape Shape, constraints []Constraint) (*Grid, error) {
+	return GenerateVariantContext(context.Background(), shape, constraints)
+}
+
+// GenerateVariantContext is like GenerateVariant, but gives up when the
+// context is cancelled or its deadline expires, in which case it returns
+// ctx.Err().
+func GenerateVariantContext(ctx context.Context, shape Shape, constraints []Constraint) (*Grid, error) {
+	if err := shape.Validate(); err != nil {
+		return nil, err
 	}
-	count := len(knowns)
-	random := newRand()
-	for count >= MIN_CLUES {
-		random.Shuffle(count, swapper)
-		found := false
-		for i, cell := range knowns {
-			var attempt Puzzle
-			attempt.Merge(sol)
-			attempt.SetCell(cell, Unknown)
-			n := attempt.NumSolutions()
-			if n == 1 {
-				// So far so good.  Drop the cell from the solution and start
-				// the next pass.
-				sol.SetCell(cell, Unknown)
-				swapper(i, count-1)
-				knowns = knowns[:count-1]
-				count--
-				found = true
-				break
-			}
+	g := NewGrid(shape)
+	g.Constraints = constraints
+	if ok, err := g.fillRandom(ctx); !ok {
+		return nil, err
+	}
+	return g, nil
+}
+
+// fillRandom writes a random solution into the grid, trying the glyphs for
+// each cell in a random order, and returns whether it found one.  If the
+// context is cancelled first, it returns ctx.Err().
+func (g *Grid) fillRandom(ctx context.Context) (bool, error) {
+	s := newSearcher(g.Shape, g.Constraints)
+	cand := s.start(g)
+	if cand == nil {
+		return false, nil
+	}
+	var solution []uint64
+	_, err := s.search(ctx, cand, newRand(), func(c []uint64) bool {
+		solution = c
+		return true
+	})
+	if solution == nil {
+		return false, err
+	}
+	s.fill(g, solution)
+	return true, nil
+}
+
+// MinimalMask returns a minimal clue mask for the given solution grid, in the
+// same manner as Puzzle.MinimalMask.  The mask holds one value for each cell.
+func (g *Grid) MinimalMask() []bool {
+	mask, _ := g.MinimalMaskContext(context.Background())
+	return mask
+}
+
+// MinimalMaskContext is like MinimalMask, but stops removing clues when the
+// context is cancelled or its deadline expires.
+//
+// In that case it returns ctx.Err() along with the mask reached so far, which
+// still yields a grid with a unique solution.  The receiver is never modified.
+func (g *Grid) MinimalMaskContext(ctx context.Context) ([]bool, error) {
+	return g.minimalMask(ctx, 0)
+}
+
+// minimalMask is the implementation of MinimalMaskContext.  If 'limit' is not
+// zero, a clue whose removal cannot be checked within that time is kept, so
+// that the mask may not be minimal.
+func (g *Grid) minimalMask(ctx context.Context, limit time.Duration) ([]bool, error) {
+	attempt := g.Copy()
+	var knowns []int
+	for i, glyph := range attempt.Cells {
+		if g.Shape.Known(glyph) {
+			knowns = append(knowns, i)
+		}
+	}
+	newRand().Shuffle(len(knowns), func(i, j int) {
+		knowns[i], knowns[j] = knowns[j], knowns[i]
+	})
+	// A clue which cannot be removed now cannot be removed once there are
+	// fewer clues, so one pass over the clues is enough.
+	var err error
+	for _, i := range knowns {
+		glyph := attempt.Cells[i]
+		attempt.Cells[i] = Unknown
+		check, cancel := ctx, context.CancelFunc(func() {})
+		if limit > 0 {
+			check, cancel = context.WithTimeout(ctx, limit)
+		}
+		var n int
+		n, err = attempt.NumSolutionsContext(check)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			// Only the check timed out, so keep the clue and carry on.
+			n, err = 0, nil
 		}
-		if !found {
-			// None of the known cells could be safely removed.  Exit out.
+		if err != nil {
+			attempt.Cells[i] = glyph
 			break
 		}
+		if n != 1 {
+			attempt.Cells[i] = glyph
+		}
 	}
-	mask = sol.GetMask()
-	return
+	mask := make([]bool, len(attempt.Cells))
+	for i, glyph := range attempt.Cells {
+		mask[i] = g.Shape.Known(glyph)
+	}
+	return mask, err
+}
+
+// ApplyMask returns a copy of the grid in which each cell is hidden unless it
+// is true in the mask.
+func (g *Grid) ApplyMask(mask []bool) *Grid {
+	result := g.Copy()
+	for i := range result.Cells {
+		if !mask[i] {
+			result.Cells[i] = Unknown
+		}
+	}
+	return result
 }
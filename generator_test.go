@@ -1,6 +1,10 @@
 package sudoku
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+)
 
 func TestSeedSolution(t *testing.T) {
 	var puz Puzzle
@@ -173,3 +177,76 @@ func TestMinimalMask(t *testing.T) {
 		t.Errorf("incorrect result from MinimalMask: got %v clues:\n%v", count, mask.String())
 	}
 }
+
+func TestGenerateSolutionContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	puz, err := GenerateSolutionContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("incorrect error from GenerateSolutionContext(): expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if puz.NumUnknowns() != GridSize {
+		t.Errorf("expected cleared puzzle from expired GenerateSolutionContext(), got:\n\n%v", puz.String())
+	}
+}
+
+func TestMinimalMaskContext(t *testing.T) {
+	sol := Puzzle{
+		'2', '9', '7', '6', '3', '5', '8', '1', '4',
+		'6', '5', '1', '4', '2', '8', '7', '9', '3',
+		'4', '8', '3', '1', '9', '7', '5', '2', '6',
+		'7', '4', '8', '5', '1', '9', '6', '3', '2',
+		'9', '6', '5', '2', '7', '3', '1', '4', '8',
+		'1', '3', '2', '8', '4', '6', '9', '7', '5',
+		'5', '1', '9', '3', '6', '2', '4', '8', '7',
+		'3', '7', '6', '9', '8', '4', '2', '5', '1',
+		'8', '2', '4', '7', '5', '1', '3', '6', '9'}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mask, err := sol.MinimalMaskContext(ctx)
+	if err != context.Canceled {
+		t.Errorf("incorrect error from MinimalMaskContext: expected %v, got %v", context.Canceled, err)
+	}
+	if mask.Count(true) != GridSize {
+		t.Errorf("expected full mask from cancelled MinimalMaskContext, got:\n%v", mask.String())
+	}
+}
+
+func TestGenerateGrid(t *testing.T) {
+	for _, size := range []int{4, 6, 12, 16} {
+		shape, _ := ShapeForSize(size)
+		g, err := GenerateGrid(shape)
+		if err != nil {
+			t.Errorf("unexpected error from GenerateGrid(%v): %v", shape, err)
+			continue
+		}
+		if g.NumUnknowns() != 0 || g.Validate() != nil {
+			t.Errorf("invalid result from GenerateGrid(%v): %v\n\n%v", shape, g.Validate(), g.String())
+		}
+	}
+	if _, err := GenerateGrid(Shape{3, 3, "123", ""}); err == nil {
+		t.Errorf("no error from GenerateGrid for invalid shape")
+	}
+}
+
+func TestGridMinimalMask(t *testing.T) {
+	shape, _ := ShapeForSize(6)
+	g, _ := GenerateGrid(shape)
+	mask := g.MinimalMask()
+	puzzle := g.ApplyMask(mask)
+	if n := puzzle.NumSolutions(); n != 1 {
+		t.Errorf("incorrect result from MinimalMask: %v solutions for\n%v", n, puzzle.String())
+	}
+	// Removing any remaining clue must allow multiple solutions.
+	for i, clue := range mask {
+		if !clue {
+			continue
+		}
+		attempt := puzzle.Copy()
+		attempt.Cells[i] = Unknown
+		if attempt.NumSolutions() == 1 {
+			t.Errorf("incorrect result from MinimalMask: clue at %v can be removed from\n%v", g.cellRef(i), puzzle.String())
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package sudoku
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Grid is a sudoku grid of any shape.
+//
+// Cells are held in top to bottom, left to right order, and contain either one
+// of the shape's glyphs or Unknown.  Puzzle is the fixed-size equivalent for
+// grids of the Classic shape; see Puzzle.Grid and Grid.Puzzle to convert
+// between them.
+type Grid struct {
+	Shape Shape
+	Cells []byte
+	// Constraints are the rules which the grid's solution must obey.  If nil,
+	// the DefaultConstraints for the shape apply.  Variants append their own
+	// constraints to the defaults.
+	Constraints []Constraint
+}
+
+// NewGrid returns an empty grid of the given shape.
+func NewGrid(shape Shape) *Grid {
+	g := &Grid{Shape: shape, Cells: make([]byte, shape.NumCells())}
+	g.Clear()
+	return g
+}
+
+// ParseGrid reads a grid from a slice of bytes in either the grid or the line
+// format, taking its shape from the number of rows or cells in the input; see
+// ShapeForSize.
+func ParseGrid(input []byte) (*Grid, error) {
+	input = bytes.TrimSpace(input)
+	var size int
+	if n := bytes.Count(input, []byte("\n")); n > 0 {
+		size = n + 1
+	} else {
+		for size*size < len(input) {
+			size++
+		}
+		if size*size != len(input) {
+			return nil, &ParseError{Line: 1, Reason: fmt.Sprintf("%v bytes is not the size of any grid", len(input))}
+		}
+	}
+	shape, err := ShapeForSize(size)
+	if err != nil {
+		return nil, &ParseError{Reason: err.Error()}
+	}
+	g := NewGrid(shape)
+	if err := g.Read(input); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Section is a kind of clue listed after the grid, and a blank line, in the
+// puzzle files of variants such as killer sudoku.  CageSection, LineSection,
+// EdgeSection and OuterSection are the sections for the variants in this
+// package.
+type Section struct {
+	// Name describes the clues in errors, e.g. "cages".
+	Name string
+	// Parse reads the clues for a grid of the given shape, and returns their
+	// constraints alone; ParsePuzzle adds the DefaultConstraints.
+	Parse func(input []byte, shape Shape) ([]Constraint, error)
+	// Format writes the clues among a grid's constraints, in the format read
+	// by Parse.
+	Format func(g *Grid) string
+}
+
+// ParsePuzzle reads a puzzle from a slice of bytes in the format written by
+// Grid.PuzzleString: the grid's givens in either the grid or the line format,
+// then for each section in turn a blank line and its clues.  Blank lines
+// within the last section are ignored.
+//
+// The shape is taken from the size of the grid, as in ParseGrid, and the
+// grid's constraints are the DefaultConstraints followed by those of the
+// clues of each section.
+func ParsePuzzle(input []byte, sections ...Section) (*Grid, error) {
+	names := make([]string, len(sections))
+	for k, s := range sections {
+		names[k] = s.Name
+	}
+	parts, err := splitPuzzle(input, names...)
+	if err != nil {
+		return nil, err
+	}
+	g, err := ParseGrid(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	g.Constraints = DefaultConstraints(g.Shape)
+	for k, s := range sections {
+		cs, err := s.Parse(parts[k+1], g.Shape)
+		if err != nil {
+			return nil, sectionError(err, parts, k+1)
+		}
+		g.Constraints = append(g.Constraints, cs...)
+	}
+	return g, nil
+}
+
+// PuzzleString returns a representation of the grid and the clues of each
+// section in the format consumed by ParsePuzzle.
+func (g *Grid) PuzzleString(sections ...Section) string {
+	s := g.String()
+	for _, section := range sections {
+		s += "\n" + section.Format(g)
+	}
+	return s
+}
+
+// splitPuzzle splits a puzzle file into the grid and a section for each of
+// the names, each after a blank line.  The last section holds the rest of the
+// input.
+func splitPuzzle(input []byte, names ...string) ([][]byte, error) {
+	input = bytes.TrimSpace(bytes.Replace(input, []byte("\r\n"), []byte("\n"), -1))
+	parts := bytes.SplitN(input, []byte("\n\n"), len(names)+1)
+	switch n := len(parts); {
+	case n == 1:
+		return nil, &ParseError{Reason: fmt.Sprintf("expected a blank line between the grid and its %v", names[0])}
+	case n <= len(names):
+		return nil, &ParseError{Reason: fmt.Sprintf("expected a blank line between the %v and the %v", names[n-2], names[n-1])}
+	}
+	return parts, nil
+}
+
+// sectionError adjusts the line number of a *ParseError from part 'k' of a
+// puzzle file split by splitPuzzle, to count from the start of the file.
+func sectionError(err error, parts [][]byte, k int) error {
+	if e, ok := err.(*ParseError); ok && e.Line > 0 {
+		for _, p := range parts[:k] {
+			e.Line += bytes.Count(p, []byte("\n")) + 2
+		}
+	}
+	return err
+}
+
+// Size returns the number of rows and columns in the grid.
+func (g *Grid) Size() int {
+	return g.Shape.Size()
+}
+
+// index returns the index into Cells for a row and column.
+func (g *Grid) index(row, col int) int {
+	return row*g.Size() + col
+}
+
+// cellRef returns the CellRef for an index into Cells.
+func (g *Grid) cellRef(index int) CellRef {
+	return CellRef{index / g.Size(), index % g.Size()}
+}
+
+// Get returns the glyph in a cell.
+func (g *Grid) Get(row, col int) byte {
+	return g.Cells[g.index(row, col)]
+}
+
+// Set changes the glyph in a cell.
+func (g *Grid) Set(row, col int, glyph byte) {
+	g.Cells[g.index(row, col)] = glyph
+}
+
+// Clear sets every cell of the grid to Unknown.
+func (g *Grid) Clear() {
+	for i := range g.Cells {
+		g.Cells[i] = Unknown
+	}
+}
+
+// Copy returns a copy of the grid.
+func (g *Grid) Copy() *Grid {
+	return &Grid{Shape: g.Shape, Cells: append([]byte(nil), g.Cells...), Constraints: g.Constraints}
+}
+
+// Equal returns whether two grids have the same shape and contents.  Their
+// constraints are not compared.
+func (g *Grid) Equal(other *Grid) bool {
+	return g.Shape == other.Shape && bytes.Equal(g.Cells, other.Cells)
+}
+
+// NumUnknowns returns the number of unknown cells in the grid.
+func (g *Grid) NumUnknowns() (count int) {
+	for _, glyph := range g.Cells {
+		if !g.Shape.Known(glyph) {
+			count++
+		}
+	}
+	return
+}
+
+// Read reads in a grid from a slice of bytes, in the same format as
+// Puzzle.Read: one line for each row, with glyphs separated by a single byte
+// and unknown cells indicated by an underscore.  Input consisting of a single
+// line is read in the line format instead; see ReadLine.
+//
+// The grid is not modified if the input is malformed.
+func (g *Grid) Read(input []byte) error {
+	input = bytes.TrimSpace(input)
+	if bytes.IndexByte(input, '\n') < 0 {
+		return g.ReadLine(input)
+	}
+	size := g.Size()
+	lines := bytes.Split(input, []byte("\n"))
+	if len(lines) != size {
+		return &ParseError{Reason: fmt.Sprintf("expected %v lines, got %v", size, len(lines))}
+	}
+	cells := make([]byte, 0, g.Shape.NumCells())
+	for r, line := range lines {
+		line = bytes.TrimRight(line, "\r")
+		// Expecting one byte separating each glyph.
+		length := (size * 2) - 1
+		if len(line) != length {
+			return &ParseError{Line: r + 1, Reason: fmt.Sprintf("expected %v bytes, got %v", length, len(line))}
+		}
+		for c := 0; c < size; c++ {
+			glyph := line[c*2]
+			switch {
+			case g.Shape.Known(glyph):
+				cells = append(cells, glyph)
+			case glyph == '_' || glyph == ' ':
+				cells = append(cells, Unknown)
+			default:
+				return &ParseError{
+					Line:   r + 1,
+					Column: c*2 + 1,
+					Byte:   glyph,
+					Reason: fmt.Sprintf("expected underscore, space or %v, got %q", g.Shape.describe(false), glyph),
+				}
+			}
+		}
+	}
+	copy(g.Cells, cells)
+	return nil
+}
+
+// ReadLine reads in a grid in the line format, from a slice of bytes.
+//
+// The line format is a single line with one glyph for each cell and no
+// separators, giving the rows of the grid from top to bottom.  Unknown cells
+// are indicated by a full stop '.', or by a zero '0' if zero is not one of the
+// shape's glyphs.  Surrounding whitespace is ignored.
+//
+// The grid is not modified if the input is malformed.
+func (g *Grid) ReadLine(input []byte) error {
+	line := bytes.TrimSpace(input)
+	if len(line) != len(g.Cells) {
+		return &ParseError{Line: 1, Reason: fmt.Sprintf("expected %v bytes, got %v", len(g.Cells), len(line))}
+	}
+	cells := make([]byte, len(line))
+	for i, glyph := range line {
+		switch {
+		case g.Shape.Known(glyph):
+			cells[i] = glyph
+		case g.Shape.blank(glyph):
+			cells[i] = Unknown
+		default:
+			return &ParseError{
+				Line:   1,
+				Column: i + 1,
+				Byte:   glyph,
+				Reason: fmt.Sprintf("expected full stop or %v, got %q", g.Shape.describe(!g.Shape.Known('0')), glyph),
+			}
+		}
+	}
+	copy(g.Cells, cells)
+	return nil
+}
+
+// String returns a formatted representation of the grid, in the format
+// consumed by Read.
+func (g *Grid) String() string {
+	var buf bytes.Buffer
+	size := g.Size()
+	for i, glyph := range g.Cells {
+		if g.Shape.Known(glyph) {
+			buf.WriteByte(glyph)
+		} else {
+			buf.WriteByte('_')
+		}
+		if i%size == size-1 {
+			buf.WriteByte('\n')
+		} else {
+			buf.WriteByte(' ')
+		}
+	}
+	return buf.String()
+}
+
+// Line returns a representation of the grid in the line format, with unknown
+// cells represented by full stop (0x2e).
+func (g *Grid) Line() string {
+	buf := make([]byte, len(g.Cells))
+	for i, glyph := range g.Cells {
+		if g.Shape.Known(glyph) {
+			buf[i] = glyph
+		} else {
+			buf[i] = '.'
+		}
+	}
+	return string(buf)
+}
+
+// Validate checks the grid for correctness against each of its constraints
+// in turn, and returns the first error found.  For the default constraints,
+// this is the same as Puzzle.Validate: the error returned for an incorrect
+// grid is a *ConflictError describing the first duplicate found.
+func (g *Grid) Validate() error {
+	for _, c := range g.constraints() {
+		if err := c.Check(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Conflicts returns every duplicated glyph in the grid's units, which by
+// default are its rows, columns and boxes or regions, in that order, or nil if
+// there are none.  Constraints other than units are not checked.
+func (g *Grid) Conflicts() (result []*ConflictError) {
+	for _, c := range g.constraints() {
+		if u, ok := c.(Unit); ok {
+			result = append(result, u.conflicts(g, true)...)
+		}
+	}
+	return
+}
+
+// Grid returns the puzzle as a Grid of the Classic shape.
+func (puz *Puzzle) Grid() *Grid {
+	g := NewGrid(Classic)
+	for i, glyph := range puz {
+		if Known(glyph) {
+			g.Cells[i] = glyph
+		}
+	}
+	return g
+}
+
+// Puzzle returns the grid as a Puzzle.  It returns an error if the grid is not
+// of the Classic shape.
+func (g *Grid) Puzzle() (puz Puzzle, err error) {
+	if g.Shape != Classic {
+		return puz, fmt.Errorf("cannot convert a %v grid to a Puzzle", g.Shape)
+	}
+	copy(puz[:], g.Cells)
+	return
+}
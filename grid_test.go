@@ -0,0 +1,180 @@
+package sudoku
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGridRead(t *testing.T) {
+	shape, _ := ShapeForSize(6)
+	input := "1 _ _ 4 _ _\n" +
+		"_ 5 _ _ _ 3\n" +
+		"_ _ 6 _ 2 _\n" +
+		"_ 4 _ 1 _ _\n" +
+		"3 _ _ _ 5 _\n" +
+		"_ _ 1 _ _ 2\n"
+	g := NewGrid(shape)
+	if err := g.Read([]byte(input)); err != nil {
+		t.Fatalf("Read failed on valid input: %v", err)
+	}
+	if g.Get(1, 5) != '3' || g.Get(0, 1) != Unknown || g.NumUnknowns() != 24 {
+		t.Errorf("incorrect result from Read: got\n%v", g.String())
+	}
+	if g.String() != input {
+		t.Errorf("incorrect result from String: expected\n%v\ngot\n%v", input, g.String())
+	}
+	line := "1..4...5...3..6.2..4.1..3...5...1..2"
+	if g.Line() != line {
+		t.Errorf("incorrect result from Line: expected %q, got %q", line, g.Line())
+	}
+	other := NewGrid(shape)
+	if err := other.Read([]byte(line)); err != nil || !other.Equal(g) {
+		t.Errorf("incorrect result from Read for line input: %v\n%v", err, other.String())
+	}
+
+	// Hexadecimal glyphs, where zero is a glyph rather than a blank.
+	hex, _ := ShapeForSize(16)
+	h := NewGrid(hex)
+	if err := h.ReadLine([]byte("G" + strings.Repeat(".", 255))); err == nil {
+		t.Errorf("no error from ReadLine for invalid glyph")
+	}
+	input16 := "0" + strings.Repeat(".", 255)
+	if err := h.ReadLine([]byte(input16)); err != nil {
+		t.Fatalf("ReadLine failed on valid hex input: %v", err)
+	}
+	if h.Get(0, 0) != '0' || h.NumUnknowns() != 255 {
+		t.Errorf("incorrect result from ReadLine for hex input: got %q with %v unknowns", h.Get(0, 0), h.NumUnknowns())
+	}
+
+	var perr *ParseError
+	err := g.Read([]byte("1 _ _ 4 _ _\n_ 5 _ _ _ 3\n_ _ 7 _ 2 _\n_ 4 _ 1 _ _\n3 _ _ _ 5 _\n_ _ 1 _ _ 2\n"))
+	expect := `malformed input on line 3, column 5: expected underscore, space or digit 1-6, got '7'`
+	if !errors.As(err, &perr) || err.Error() != expect {
+		t.Errorf("incorrect error for invalid glyph: expected %q, got %v", expect, err)
+	}
+	if g.Line() != line {
+		t.Errorf("grid modified by Read of invalid input: got %q", g.Line())
+	}
+}
+
+func TestParseGrid(t *testing.T) {
+	cases := []struct {
+		input string
+		size  int
+	}{
+		{"1..4...5...3..6.2..4.1..3...5...1..2", 6},
+		{"1 2 _ _\n_ _ 1 2\n_ _ _ _\n_ _ _ _", 4},
+		{"..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4..", 9},
+	}
+	for _, c := range cases {
+		g, err := ParseGrid([]byte(c.input))
+		if err != nil {
+			t.Errorf("unexpected error from ParseGrid(%q): %v", c.input, err)
+			continue
+		}
+		if g.Size() != c.size {
+			t.Errorf("incorrect size from ParseGrid(%q): expected %v, got %v", c.input, c.size, g.Size())
+		}
+	}
+	for _, input := range []string{"1234567", "1.3.4.2.3.4.1.2.3.4.1.2.3"} {
+		if _, err := ParseGrid([]byte(input)); err == nil {
+			t.Errorf("no error from ParseGrid(%q)", input)
+		}
+	}
+}
+
+func TestParsePuzzle(t *testing.T) {
+	sections := []Section{CageSection, LineSection, OuterSection}
+	clues := killerCages + "\nthermo R1C5 R1C6\n\nsandwich L1 16\n"
+	g, err := ParsePuzzle([]byte(strings.Repeat(".", GridSize)+"\n\n"+clues), sections...)
+	if err != nil {
+		t.Fatalf("unexpected error from ParsePuzzle: %v", err)
+	}
+	if len(g.Cages()) != 32 || len(g.Lines()) != 1 || len(g.OuterClues()) != 1 {
+		t.Errorf("incorrect constraints from ParsePuzzle: got %v cages, %v lines and %v outside clues", len(g.Cages()), len(g.Lines()), len(g.OuterClues()))
+	}
+	units := 0
+	for _, c := range g.Constraints {
+		if _, ok := c.(Unit); ok {
+			units++
+		}
+	}
+	if units != 3*Size {
+		t.Errorf("incorrect number of units from ParsePuzzle: expected %v, got %v", 3*Size, units)
+	}
+	expect := NewGrid(Classic).String() + "\n" + clues
+	if result := g.PuzzleString(sections...); result != expect {
+		t.Errorf("incorrect result from PuzzleString: expected %q, got %q", expect, result)
+	}
+	if g.Solve(); g.Line() != killerSolution {
+		t.Errorf("incorrect solution: expected %v, got %v", killerSolution, g.Line())
+	}
+
+	// Line numbers in errors count from the start of the input.
+	input := strings.Repeat(".", GridSize) + "\n\n" + strings.Replace(clues, "L1", "L10", 1)
+	_, err = ParsePuzzle([]byte(input), sections...)
+	if e, ok := err.(*ParseError); !ok || e.Line != 38 {
+		t.Errorf("expected ParseError on line 38 from ParsePuzzle, got %v", err)
+	}
+	_, err = ParsePuzzle([]byte(strings.Repeat(".", GridSize)+"\n\n"+killerCages), sections...)
+	if err == nil || err.Error() != "malformed input: expected a blank line between the cages and the lines" {
+		t.Errorf("incorrect error for missing section: got %v", err)
+	}
+
+	// A section returns only the constraints of its clues.
+	dots := Section{
+		Name: "dots",
+		Parse: func(input []byte, shape Shape) ([]Constraint, error) {
+			e, err := NewEdge(shape, BlackDot, 0, 1)
+			return []Constraint{e}, err
+		},
+	}
+	g, err = ParsePuzzle([]byte(strings.Repeat(".", GridSize)+"\n\nthermo R1C5 R1C6\n\nR1C1|b\n"), LineSection, dots)
+	if err != nil || len(g.Constraints) != 3*Size+2 || len(g.Edges()) != 1 {
+		t.Errorf("incorrect result from ParsePuzzle with a custom section: %v, %v", g, err)
+	}
+}
+
+func TestGridConflicts(t *testing.T) {
+	shape, _ := ShapeForSize(6)
+	g := NewGrid(shape)
+	g.ReadLine([]byte("1..4...5...3..6.2..4.1..3...5...1..2"))
+	if err := g.Validate(); err != nil {
+		t.Errorf("unexpected error from Validate for correct grid: %v", err)
+	}
+	g.Set(0, 2, '4')
+	g.Set(2, 0, '1')
+	g.Set(4, 3, '2')
+	conflicts := g.Conflicts()
+	expect := []string{
+		`invalid puzzle: duplicate '4' in row 1 at R1C3, R1C4`,
+		`invalid puzzle: duplicate '1' in column 1 at R1C1, R3C1`,
+		`invalid puzzle: duplicate '2' in subgrid 6 at R5C4, R6C6`,
+	}
+	if len(conflicts) != len(expect) {
+		t.Fatalf("incorrect number of conflicts: expected %v, got %v", len(expect), conflicts)
+	}
+	for i, e := range expect {
+		if conflicts[i].Error() != e {
+			t.Errorf("incorrect conflict %v: expected %q, got %q", i, e, conflicts[i].Error())
+		}
+	}
+}
+
+func TestPuzzleGrid(t *testing.T) {
+	var puz Puzzle
+	puz.ReadLine([]byte("..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4.."))
+	g := puz.Grid()
+	if g.Shape != Classic || g.Line() != puz.Line() {
+		t.Errorf("incorrect result from Grid: got %v %q", g.Shape, g.Line())
+	}
+	back, err := g.Puzzle()
+	if err != nil || !back.Equal(puz) {
+		t.Errorf("incorrect result from Grid.Puzzle: %v\n%v", err, back.String())
+	}
+	shape, _ := ShapeForSize(4)
+	if _, err := NewGrid(shape).Puzzle(); err == nil {
+		t.Errorf("no error from Puzzle for 4×4 grid")
+	}
+}
@@ -0,0 +1,150 @@
+package sudoku
+
+import (
+	"bytes"
+	"io"
+)
+
+// HTMLStyle is a stylesheet for the tables written by HTMLFormat.
+const HTMLStyle = `table.sudoku { border-collapse: collapse; border: 2px solid #000; }
+table.sudoku td { width: 2em; height: 2em; padding: 0; border: 1px solid #999; text-align: center; vertical-align: middle; font: 1.2em sans-serif; }
+table.sudoku td.box-top { border-top: 2px solid #000; }
+table.sudoku td.box-left { border-left: 2px solid #000; }
+table.sudoku td.box-bottom { border-bottom: 2px solid #000; }
+table.sudoku td.box-right { border-right: 2px solid #000; }
+table.sudoku td.given { font-weight: bold; }
+table.sudoku td.solved { color: #1f4fbf; }
+table.sudoku td.highlight { background: #ffee88; }
+table.sudoku td.candidates div { display: grid; grid-template-columns: repeat(3, 1fr); font-size: 0.5em; color: #555; }
+`
+
+// HTMLFormat writes puzzles as an HTML table with class "sudoku".  It cannot
+// be decoded.
+//
+// Each cell has CSS classes describing it: "given" or "solved" for known
+// cells, "candidates" for unknown cells whose candidates are shown,
+// "highlight" for highlighted cells, and "box-top", "box-left", "box-bottom"
+// and "box-right" for cells on each edge of a subgrid.  HTMLStyle is a
+// suitable stylesheet.
+//
+// The zero value, which is registered under the name "html", writes the
+// puzzle alone as a table.
+type HTMLFormat struct {
+	// Solution fills in the unknown cells from the puzzle's solution.
+	Solution bool
+	// Givens marks the cells which hold clues.  Other known cells are shown
+	// as solved, so that a puzzle which has already been solved can be
+	// written without solving it again.  If nil, every known cell is a given.
+	Givens *Mask
+	// Candidates shows the candidates of each unknown cell.
+	Candidates bool
+	// Highlights are the cells to mark with the "highlight" class.
+	Highlights []CellRef
+	// Document writes a complete HTML document including HTMLStyle, rather
+	// than the table alone.
+	Document bool
+}
+
+func (HTMLFormat) Name() string         { return "html" }
+func (HTMLFormat) Extensions() []string { return []string{".html", ".htm"} }
+
+func (HTMLFormat) Decode(input []byte) (Puzzle, error) {
+	return Puzzle{}, ErrEncodeOnly
+}
+
+// exportGrid returns the cells to show when exporting a puzzle, the mask of
+// its givens, and the candidates to show, or nil if they are not wanted.  The
+// givens are taken from 'mask' if it is not nil.
+func exportGrid(puz *Puzzle, solution, candidates bool, mask *Mask) (values Puzzle, givens Mask, cg *CandidateGrid) {
+	values = *puz
+	givens = puz.GetMask()
+	if mask != nil {
+		givens = *mask
+	}
+	if solution {
+		values.Solve()
+	}
+	if candidates {
+		grid := values.CandidateGrid()
+		cg = &grid
+	}
+	return
+}
+
+func (f HTMLFormat) Encode(w io.Writer, puz *Puzzle) error {
+	values, givens, cg := exportGrid(puz, f.Solution, f.Candidates, f.Givens)
+	var highlight Mask
+	for _, ref := range f.Highlights {
+		highlight[cellRefToIndex(ref)] = true
+	}
+
+	var buf bytes.Buffer
+	if f.Document {
+		buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<style>\n")
+		buf.WriteString(HTMLStyle)
+		buf.WriteString("</style>\n</head>\n<body>\n")
+	}
+	buf.WriteString("<table class=\"sudoku\">\n")
+	for r := 0; r < Size; r++ {
+		buf.WriteString("<tr>")
+		for c := 0; c < Size; c++ {
+			i := coordsToIndex(r, c)
+			var classes []string
+			switch {
+			case givens[i]:
+				classes = append(classes, "given")
+			case Known(values[i]):
+				classes = append(classes, "solved")
+			case cg != nil:
+				classes = append(classes, "candidates")
+			}
+			if highlight[i] {
+				classes = append(classes, "highlight")
+			}
+			if r%SubSize == 0 {
+				classes = append(classes, "box-top")
+			}
+			if c%SubSize == 0 {
+				classes = append(classes, "box-left")
+			}
+			if r%SubSize == SubSize-1 {
+				classes = append(classes, "box-bottom")
+			}
+			if c%SubSize == SubSize-1 {
+				classes = append(classes, "box-right")
+			}
+
+			buf.WriteString("<td class=\"")
+			for k, class := range classes {
+				if k > 0 {
+					buf.WriteByte(' ')
+				}
+				buf.WriteString(class)
+			}
+			buf.WriteString("\">")
+			switch {
+			case Known(values[i]):
+				buf.WriteByte(values[i])
+			case cg != nil:
+				// One span for each glyph, so that the candidates line up.
+				buf.WriteString("<div>")
+				for _, g := range Glyphs {
+					buf.WriteString("<span>")
+					if cg.Has(indexToCellRef(i), g) {
+						buf.WriteByte(g)
+					}
+					buf.WriteString("</span>")
+				}
+				buf.WriteString("</div>")
+			}
+			buf.WriteString("</td>")
+		}
+		buf.WriteString("</tr>\n")
+	}
+	buf.WriteString("</table>\n")
+	if f.Document {
+		buf.WriteString("</body>\n</html>\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,84 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHTMLFormat(t *testing.T) {
+	puz := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' ',
+	}
+	var givens Mask
+	givens[2] = true
+	cases := []struct {
+		format HTMLFormat
+		expect []string
+		absent []string
+	}{
+		{
+			HTMLFormat{},
+			[]string{
+				"<table class=\"sudoku\">\n<tr><td class=\"box-top box-left\"></td><td class=\"box-top\"></td><td class=\"given box-top box-right\">8</td>",
+				"<td class=\"given box-left box-bottom\">1</td><td class=\"box-bottom\"></td>",
+				"</tr>\n</table>\n",
+			},
+			[]string{"<html>", "solved", "<span>"},
+		},
+		{
+			HTMLFormat{Solution: true, Highlights: []CellRef{{0, 0}}, Document: true},
+			[]string{
+				"<!DOCTYPE html>",
+				"<style>\n" + HTMLStyle + "</style>",
+				"<td class=\"solved highlight box-top box-left\">7</td>",
+				"</table>\n</body>\n</html>\n",
+			},
+			[]string{"<td class=\"candidates"},
+		},
+		{
+			HTMLFormat{Givens: &givens},
+			[]string{
+				"<td class=\"given box-top box-right\">8</td>",
+				"<td class=\"solved box-top box-right\">6</td>",
+			},
+			nil,
+		},
+		{
+			HTMLFormat{Candidates: true},
+			[]string{
+				"<td class=\"candidates box-top box-left\"><div><span>1</span><span></span><span>3</span><span>4</span><span></span><span></span><span>7</span><span></span><span>9</span></div></td>",
+			},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := c.format.Encode(&buf, &puz); err != nil {
+			t.Fatalf("unexpected error from Encode: %v", err)
+		}
+		out := buf.String()
+		for _, e := range c.expect {
+			if !strings.Contains(out, e) {
+				t.Errorf("incorrect result from %+v: expected output to contain %q, got:\n%v", c.format, e, out)
+			}
+		}
+		for _, e := range c.absent {
+			if strings.Contains(out, e) {
+				t.Errorf("incorrect result from %+v: expected output not to contain %q, got:\n%v", c.format, e, out)
+			}
+		}
+	}
+
+	if _, err := (HTMLFormat{}).Decode([]byte("<table></table>")); err != ErrEncodeOnly {
+		t.Errorf("incorrect error from Decode: expected %v, got %v", ErrEncodeOnly, err)
+	}
+}
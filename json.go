@@ -0,0 +1,222 @@
+package sudoku
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document is the JSON representation of a puzzle together with its state
+// of play.
+//
+// Puzzles are encoded as strings in the line format (see Puzzle.Line), and
+// candidates as an array of 81 strings, each listing the candidate digits for
+// one cell in grid order.  Only "givens" is required.
+//
+// E.g.,
+//
+//	{
+//	  "givens":     "..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4..",
+//	  "values":     "7.8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4..",
+//	  "candidates": ["7", "149", "8", "349", ...],
+//	  "solution":   "718936254294578631653412987145623798829741365367859142436287519571394826982165473",
+//	  "metadata":   {"title": "Extreme"}
+//	}
+type Document struct {
+	// Givens holds the clues of the puzzle.
+	Givens Puzzle `json:"givens"`
+	// Values holds the current contents of the grid: the givens plus any
+	// digits entered by the solver.
+	Values *Puzzle `json:"values,omitempty"`
+	// Candidates holds the solver's pencil marks.
+	Candidates *CandidateGrid `json:"candidates,omitempty"`
+	// Solution holds the completed grid.
+	Solution *Puzzle `json:"solution,omitempty"`
+	// Metadata holds free-form information about the puzzle, such as its
+	// title, author or difficulty.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// UnmarshalJSON decodes a document, and returns an error if it has no
+// givens.
+func (d *Document) UnmarshalJSON(data []byte) error {
+	// The givens are decoded through a pointer, so that their absence can be
+	// told apart from an empty puzzle.
+	type document Document
+	var doc struct {
+		document
+		Givens *Puzzle `json:"givens"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Givens == nil {
+		return errors.New(`document has no "givens"`)
+	}
+	*d = Document(doc.document)
+	d.Givens = *doc.Givens
+	return nil
+}
+
+// MarshalText encodes the puzzle in the line format.
+func (puz Puzzle) MarshalText() ([]byte, error) {
+	return []byte(puz.Line()), nil
+}
+
+// UnmarshalText decodes a puzzle in either the line format or the grid
+// format, as accepted by Read.
+func (puz *Puzzle) UnmarshalText(text []byte) error {
+	return puz.Read(text)
+}
+
+// MarshalJSON encodes the puzzle as a JSON string in the line format.
+func (puz Puzzle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(puz.Line())
+}
+
+// UnmarshalJSON decodes a puzzle from a JSON string in either the line
+// format or the grid format.
+func (puz *Puzzle) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return puz.UnmarshalText([]byte(s))
+}
+
+// MarshalText encodes the mask as 81 bytes in grid order, '1' for true and
+// '0' for false.
+func (m Mask) MarshalText() ([]byte, error) {
+	text := make([]byte, GridSize)
+	for i := 0; i < GridSize; i++ {
+		if m[i] {
+			text[i] = '1'
+		} else {
+			text[i] = '0'
+		}
+	}
+	return text, nil
+}
+
+// UnmarshalText decodes a mask in the format produced by MarshalText.
+func (m *Mask) UnmarshalText(text []byte) error {
+	if len(text) != GridSize {
+		return &ParseError{Reason: fmt.Sprintf("expected %v bytes, got %v", GridSize, len(text))}
+	}
+	var result Mask
+	for i, b := range text {
+		switch b {
+		case '1':
+			result[i] = true
+		case '0':
+		default:
+			return &ParseError{Line: 1, Column: i + 1, Byte: b, Reason: fmt.Sprintf("expected 0 or 1, got %q", b)}
+		}
+	}
+	*m = result
+	return nil
+}
+
+// MarshalJSON encodes the mask as a JSON string in the format produced by
+// MarshalText.
+func (m Mask) MarshalJSON() ([]byte, error) {
+	text, _ := m.MarshalText()
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON decodes a mask from a JSON string in the format produced by
+// MarshalText.
+func (m *Mask) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return m.UnmarshalText([]byte(s))
+}
+
+// ParseCellRef parses a cell reference in the format produced by
+// CellRef.String, e.g. "R1C1".  The letters may be in either case.
+func ParseCellRef(s string) (ref CellRef, err error) {
+	return parseCellRef(s, Size)
+}
+
+// parseCellRef parses a cell reference for a grid with 'size' rows and
+// columns.
+func parseCellRef(s string, size int) (ref CellRef, err error) {
+	upper := strings.ToUpper(s)
+	c := strings.IndexByte(upper, 'C')
+	if len(upper) < 4 || upper[0] != 'R' || c < 2 {
+		return ref, fmt.Errorf("invalid cell reference %q", s)
+	}
+	row, err := strconv.Atoi(upper[1:c])
+	if err != nil || row < 1 || row > size {
+		return ref, fmt.Errorf("invalid row in cell reference %q", s)
+	}
+	col, err := strconv.Atoi(upper[c+1:])
+	if err != nil || col < 1 || col > size {
+		return ref, fmt.Errorf("invalid column in cell reference %q", s)
+	}
+	return CellRef{row - 1, col - 1}, nil
+}
+
+// MarshalText encodes the reference in the format produced by String.
+func (ref CellRef) MarshalText() ([]byte, error) {
+	return []byte(ref.String()), nil
+}
+
+// UnmarshalText decodes a reference in the format accepted by ParseCellRef.
+func (ref *CellRef) UnmarshalText(text []byte) (err error) {
+	*ref, err = ParseCellRef(string(text))
+	return
+}
+
+// MarshalJSON encodes the reference as a JSON string, e.g. "R1C1".
+func (ref CellRef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ref.String())
+}
+
+// UnmarshalJSON decodes a reference from a JSON string in the format
+// accepted by ParseCellRef.
+func (ref *CellRef) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return ref.UnmarshalText([]byte(s))
+}
+
+// MarshalJSON encodes the grid as a JSON array of 81 strings, each listing
+// the candidate digits of one cell in grid order.
+func (cg CandidateGrid) MarshalJSON() ([]byte, error) {
+	marks := make([]string, GridSize)
+	for i := 0; i < GridSize; i++ {
+		marks[i] = string(cg.Get(indexToCellRef(i)))
+	}
+	return json.Marshal(marks)
+}
+
+// UnmarshalJSON decodes a grid from a JSON array in the format produced by
+// MarshalJSON.
+func (cg *CandidateGrid) UnmarshalJSON(data []byte) error {
+	var marks []string
+	if err := json.Unmarshal(data, &marks); err != nil {
+		return err
+	}
+	if len(marks) != GridSize {
+		return fmt.Errorf("invalid candidate grid: expected %v cells, got %v", GridSize, len(marks))
+	}
+	var result CandidateGrid
+	for i, m := range marks {
+		cell := indexToCellRef(i)
+		for j := 0; j < len(m); j++ {
+			if !Known(m[j]) {
+				return fmt.Errorf("invalid candidate grid: unexpected %q in %v", m[j], cell.String())
+			}
+		}
+		result.Set(cell, []byte(m))
+	}
+	*cg = result
+	return nil
+}
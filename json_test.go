@@ -0,0 +1,153 @@
+package sudoku
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPuzzleJSON(t *testing.T) {
+	puz := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' '}
+	data, err := json.Marshal(puz)
+	expect := `"..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4.."`
+	if err != nil || string(data) != expect {
+		t.Errorf("incorrect result from json.Marshal: expected %v, got %s, %v", expect, data, err)
+	}
+
+	var result Puzzle
+	err = json.Unmarshal(data, &result)
+	if err != nil || !result.Equal(puz) {
+		t.Errorf("incorrect result from json.Unmarshal: %v\n%v", err, result.String())
+	}
+
+	// The grid format is accepted too.
+	data, _ = json.Marshal(puz.String())
+	result.Clear()
+	err = json.Unmarshal(data, &result)
+	if err != nil || !result.Equal(puz) {
+		t.Errorf("incorrect result from json.Unmarshal for grid format: %v\n%v", err, result.String())
+	}
+
+	for _, input := range []string{`"..8.."`, `81`} {
+		if json.Unmarshal([]byte(input), &result) == nil {
+			t.Errorf("no error from json.Unmarshal for %v", input)
+		}
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	var mask Mask
+	mask[0] = true
+	mask[80] = true
+	data, err := json.Marshal(mask)
+	expect := `"1` + strings.Repeat("0", GridSize-2) + `1"`
+	if err != nil || string(data) != expect {
+		t.Errorf("incorrect result from json.Marshal: expected %v, got %s, %v", expect, data, err)
+	}
+	var result Mask
+	err = json.Unmarshal(data, &result)
+	if err != nil || !result.Equal(mask) {
+		t.Errorf("incorrect result from json.Unmarshal: %v\n%v", err, result.String())
+	}
+	for _, input := range []string{`"101"`, `"` + strings.Repeat("x", GridSize) + `"`, `true`} {
+		if json.Unmarshal([]byte(input), &result) == nil {
+			t.Errorf("no error from json.Unmarshal for %v", input)
+		}
+	}
+}
+
+func TestParseCellRef(t *testing.T) {
+	cases := []struct {
+		input  string
+		expect CellRef
+	}{
+		{"R1C1", CellRef{0, 0}},
+		{"R9C9", CellRef{8, 8}},
+		{"r4c7", CellRef{3, 6}},
+	}
+	for _, c := range cases {
+		result, err := ParseCellRef(c.input)
+		if err != nil || result != c.expect {
+			t.Errorf("incorrect result from ParseCellRef(%q): expected %v, got %v, %v", c.input, c.expect, result, err)
+		}
+	}
+	for _, input := range []string{"", "R1", "C1R1", "R0C1", "R1C10", "RxC1", "R1C1x"} {
+		if _, err := ParseCellRef(input); err == nil {
+			t.Errorf("no error from ParseCellRef(%q)", input)
+		}
+	}
+}
+
+func TestCellRefJSON(t *testing.T) {
+	refs := []CellRef{{0, 0}, {8, 8}, {3, 6}}
+	data, err := json.Marshal(refs)
+	expect := `["R1C1","R9C9","R4C7"]`
+	if err != nil || string(data) != expect {
+		t.Errorf("incorrect result from json.Marshal: expected %v, got %s, %v", expect, data, err)
+	}
+	var result []CellRef
+	err = json.Unmarshal(data, &result)
+	if err != nil || !equalRefs(result, refs) {
+		t.Errorf("incorrect result from json.Unmarshal: expected %v, got %v, %v", refs, result, err)
+	}
+
+	// As map keys, via encoding.TextMarshaler.
+	data, err = json.Marshal(map[CellRef]int{{1, 2}: 5})
+	if err != nil || string(data) != `{"R2C3":5}` {
+		t.Errorf("incorrect result from json.Marshal for map: got %s, %v", data, err)
+	}
+}
+
+func TestDocumentJSON(t *testing.T) {
+	input := `{
+		"givens": "..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4..",
+		"values": "7.8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4..",
+		"solution": "718936254294578631653412987145623798829741365367859142436287519571394826982165473",
+		"metadata": {"title": "Extreme"}
+	}`
+	var doc Document
+	err := json.Unmarshal([]byte(input), &doc)
+	if err != nil {
+		t.Fatalf("json.Unmarshal failed on valid document: %v", err)
+	}
+	if doc.Givens.NumUnknowns() != 53 || doc.Values == nil || doc.Values[0] != '7' ||
+		doc.Solution == nil || doc.Solution.NumUnknowns() != 0 || doc.Candidates != nil ||
+		doc.Metadata["title"] != "Extreme" {
+		t.Errorf("incorrect result from json.Unmarshal: %+v", doc)
+	}
+
+	cg := doc.Givens.CandidateGrid()
+	doc.Candidates = &cg
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"candidates":["13479","149","8",`) {
+		t.Errorf("incorrect candidates from json.Marshal: %s", data)
+	}
+	var result Document
+	err = json.Unmarshal(data, &result)
+	if err != nil || result.Candidates == nil || *result.Candidates != cg || !result.Givens.Equal(doc.Givens) {
+		t.Errorf("incorrect round trip of Document: %v\n%s", err, data)
+	}
+
+	bad := `{"givens": "` + doc.Givens.Line() + `", "candidates": ["12"]}`
+	if json.Unmarshal([]byte(bad), &result) == nil {
+		t.Errorf("no error from json.Unmarshal for short candidates")
+	}
+	if err := json.Unmarshal([]byte(`{"values": "`+doc.Givens.Line()+`"}`), &result); err == nil {
+		t.Errorf("no error from json.Unmarshal for missing givens")
+	}
+	if err := json.Unmarshal([]byte("{}"), &result); err == nil {
+		t.Errorf("no error from json.Unmarshal for empty document")
+	}
+}
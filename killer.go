@@ -0,0 +1,568 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Cage is the killer sudoku constraint that the values of a group of cells
+// add up to a target sum.  Glyph 'n' of the shape has the value n+1, so the
+// digits of grids up to 9×9 have their face value.
+type Cage struct {
+	// Sum is the total of the values in the cage.
+	Sum int
+	// Members are the indexes of the cage's cells, in grid order.
+	Members []int
+	// Repeats allows a glyph to appear more than once in the cage.  By
+	// default, as in standard killer sudoku, it may not.
+	Repeats bool
+}
+
+// Cells returns the members of the cage.
+func (c Cage) Cells() []int {
+	return c.Members
+}
+
+// Check returns a *ConstraintError if the cage holds a repeated glyph, if its
+// known cells add up to more than its sum, or if it is complete and adds up to
+// less.
+func (c Cage) Check(g *Grid) error {
+	total, known := 0, 0
+	seen := make(map[byte]int)
+	for _, i := range c.Members {
+		n := g.Shape.glyphIndex(g.Cells[i])
+		if n < 0 {
+			continue
+		}
+		total += n + 1
+		known++
+		if j, ok := seen[g.Cells[i]]; ok && !c.Repeats {
+			return &ConstraintError{
+				Constraint: c,
+				Cells:      []CellRef{g.cellRef(j), g.cellRef(i)},
+				Reason:     fmt.Sprintf("duplicate %q in cage", g.Cells[i]),
+			}
+		}
+		seen[g.Cells[i]] = i
+	}
+	if total > c.Sum || (known == len(c.Members) && total != c.Sum) {
+		return &ConstraintError{
+			Constraint: c,
+			Cells:      c.refs(g),
+			Reason:     fmt.Sprintf("cage adds up to %v, expected %v", total, c.Sum),
+		}
+	}
+	return nil
+}
+
+// refs returns the CellRefs of the cage's cells.
+func (c Cage) refs(g *Grid) []CellRef {
+	refs := make([]CellRef, len(c.Members))
+	for k, i := range c.Members {
+		refs[k] = g.cellRef(i)
+	}
+	return refs
+}
+
+// Propagate removes each candidate which is not part of any combination of
+// values adding up to the cage's sum.
+func (c Cage) Propagate(cand []uint64) bool {
+	n := len(c.Members)
+	cells := make([]uint64, n)
+	for k, i := range c.Members {
+		cells[k] = cand[i]
+	}
+	var supported []uint64
+	if c.Repeats {
+		supported = sumSupport(cells, c.Sum)
+	} else {
+		supported = distinctSumSupport(cells, c.Sum)
+	}
+	for k, i := range c.Members {
+		cand[i] &= supported[k]
+		if cand[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sumSupport returns, for each cell, the candidates which appear in some
+// choice of one candidate per cell adding up to 'sum'.
+func sumSupport(cells []uint64, sum int) []uint64 {
+	n := len(cells)
+	lo, hi := make([]int, n+1), make([]int, n+1)
+	for k := n - 1; k >= 0; k-- {
+		if cells[k] == 0 {
+			return make([]uint64, n)
+		}
+		lo[k] = lo[k+1] + bits.TrailingZeros64(cells[k]) + 1
+		hi[k] = hi[k+1] + 64 - bits.LeadingZeros64(cells[k])
+	}
+	supported := make([]uint64, n)
+	chosen := make([]uint64, n)
+	var walk func(k, remain int) bool
+	walk = func(k, remain int) bool {
+		if k == n {
+			return remain == 0
+		}
+		if remain < lo[k] || remain > hi[k] {
+			return false
+		}
+		found := false
+		for c := cells[k]; c != 0; c &= c - 1 {
+			bit := c & -c
+			chosen[k] = bit
+			if walk(k+1, remain-bits.TrailingZeros64(bit)-1) {
+				supported[k] |= bit
+				found = true
+			}
+		}
+		return found
+	}
+	walk(0, sum)
+	return supported
+}
+
+// distinctSumSupport returns, for each cell, the candidates which appear in
+// some choice of different candidates for the cells adding up to 'sum'.
+//
+// It considers each set of values with the right size and total in turn, and
+// keeps the candidates which fit into a matching of the set to the cells.
+func distinctSumSupport(cells []uint64, sum int) []uint64 {
+	n := len(cells)
+	supported := make([]uint64, n)
+	var union uint64
+	for _, c := range cells {
+		union |= c
+	}
+	var values []int
+	for c := union; c != 0; c &= c - 1 {
+		values = append(values, bits.TrailingZeros64(c)+1)
+	}
+	var walk func(start, count, remain int, set uint64)
+	walk = func(start, count, remain int, set uint64) {
+		if count == n {
+			if remain == 0 {
+				for k := range cells {
+					for c := cells[k] & set &^ supported[k]; c != 0; c &= c - 1 {
+						bit := c & -c
+						if matchable(cells, 1<<uint(k), set&^bit) {
+							supported[k] |= bit
+						}
+					}
+				}
+			}
+			return
+		}
+		for j := start; j < len(values); j++ {
+			v := values[j]
+			// The values are in ascending order, so no later choice can fit.
+			if v > remain || len(values)-j < n-count {
+				return
+			}
+			walk(j+1, count+1, remain-v, set|1<<uint(v-1))
+		}
+	}
+	walk(0, 0, sum, 0)
+	return supported
+}
+
+// matchable returns whether every cell not in 'done', a bit mask of cell
+// positions, can take a different one of the values in 'set'.
+func matchable(cells []uint64, done uint64, set uint64) bool {
+	for k, c := range cells {
+		if done&(1<<uint(k)) != 0 {
+			continue
+		}
+		for options := c & set; options != 0; options &= options - 1 {
+			bit := options & -options
+			if matchable(cells, done|1<<uint(k), set&^bit) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// cageSum is a sum constraint derived from the cages of a grid by the 45
+// rule.  It is used only to propagate candidates: its cells need not be a
+// cage, so Check leaves validation to the cages themselves.
+type cageSum struct {
+	Cage
+}
+
+func (c cageSum) Check(g *Grid) error {
+	return nil
+}
+
+// maxDerived is the largest number of cells in a sum derived by the 45 rule.
+const maxDerived = 4
+
+// KillerConstraints returns the DefaultConstraints for a shape followed by
+// the given cages.
+//
+// It also adds the sums implied by the 45 rule: since each complete unit
+// holds every glyph once, its values add up to a known total.  The cells of a
+// unit outside the cages lying wholly within it (the innies) therefore add up
+// to that total less the sum of those cages; and the cells outside a unit of
+// the cages which cover it (the outies) add up to the sum of those cages less
+// the total.
+func KillerConstraints(shape Shape, cages []Cage) []Constraint {
+	return append(DefaultConstraints(shape), cageConstraints(shape, cages)...)
+}
+
+// cageConstraints returns the given cages and the sums implied by the 45
+// rule, without the DefaultConstraints.
+func cageConstraints(shape Shape, cages []Cage) (result []Constraint) {
+	size := shape.Size()
+	total := size * (size + 1) / 2
+	cageOf := make([]int, shape.NumCells())
+	for i := range cageOf {
+		cageOf[i] = -1
+	}
+	for k, c := range cages {
+		result = append(result, c)
+		for _, i := range c.Members {
+			cageOf[i] = k
+		}
+	}
+	for _, u := range DefaultConstraints(shape) {
+		unit := u.(Unit)
+		in := make(map[int]bool)
+		for _, i := range unit.Members {
+			in[i] = true
+		}
+		// Innies: the cells not covered by cages inside the unit.
+		inside := total
+		var innies []int
+		touching := make(map[int]bool)
+		for _, i := range unit.Members {
+			k := cageOf[i]
+			if k < 0 {
+				innies = append(innies, i)
+				continue
+			}
+			touching[k] = true
+		}
+		covered := len(innies) == 0
+		for k := range touching {
+			whole := true
+			for _, i := range cages[k].Members {
+				whole = whole && in[i]
+			}
+			if whole {
+				inside -= cages[k].Sum
+			} else {
+				for _, i := range unit.Members {
+					if cageOf[i] == k {
+						innies = append(innies, i)
+					}
+				}
+			}
+		}
+		if len(innies) > 0 && len(innies) <= maxDerived && inside != total {
+			sort.Ints(innies)
+			result = append(result, cageSum{Cage{Sum: inside, Members: innies}})
+		}
+		// Outies: the cells outside the unit of the cages which cover it.
+		if !covered {
+			continue
+		}
+		outside := -total
+		var outies []int
+		for k := range touching {
+			outside += cages[k].Sum
+			for _, i := range cages[k].Members {
+				if !in[i] {
+					outies = append(outies, i)
+				}
+			}
+		}
+		if len(outies) > 0 && len(outies) <= maxDerived {
+			sort.Ints(outies)
+			result = append(result, cageSum{Cage{Sum: outside, Members: outies, Repeats: true}})
+		}
+	}
+	return result
+}
+
+// Cages returns the cages among the grid's constraints.
+func (g *Grid) Cages() (cages []Cage) {
+	for _, c := range g.Constraints {
+		if cage, ok := c.(Cage); ok {
+			cages = append(cages, cage)
+		}
+	}
+	return
+}
+
+// ParseCages reads cages for a grid of the given shape from a slice of bytes.
+//
+// Each cage is given on a line of its own, as its sum followed by the
+// references of its cells, separated by spaces; e.g. "15 R1C1 R1C2 R2C1".  A
+// cage whose glyphs may repeat ends with the word "repeats".  Blank lines and
+// comment lines beginning with '#' are ignored.
+func ParseCages(input []byte, shape Shape) (cages []Cage, err error) {
+	size := shape.Size()
+	used := make(map[int]bool)
+	for n, line := range bytes.Split(input, []byte("\n")) {
+		if skip(line) {
+			continue
+		}
+		fields := strings.Fields(string(line))
+		sum, err := strconv.Atoi(fields[0])
+		if err != nil || sum < 1 {
+			return nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("invalid cage sum %q", fields[0])}
+		}
+		cage := Cage{Sum: sum}
+		refs := fields[1:]
+		if len(refs) > 0 && refs[len(refs)-1] == "repeats" {
+			cage.Repeats = true
+			refs = refs[:len(refs)-1]
+		}
+		if len(refs) == 0 {
+			return nil, &ParseError{Line: n + 1, Reason: "cage has no cells"}
+		}
+		for _, field := range refs {
+			ref, err := parseCellRef(field, size)
+			if err != nil {
+				return nil, &ParseError{Line: n + 1, Reason: err.Error()}
+			}
+			i := ref.row*size + ref.col
+			if used[i] {
+				return nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("cell %v is in more than one cage", ref.String())}
+			}
+			used[i] = true
+			cage.Members = append(cage.Members, i)
+		}
+		sort.Ints(cage.Members)
+		cages = append(cages, cage)
+	}
+	return
+}
+
+// FormatCages returns the cages of a grid with 'size' rows and columns in the
+// format consumed by ParseCages.
+func FormatCages(cages []Cage, size int) string {
+	var buf bytes.Buffer
+	for _, c := range cages {
+		buf.WriteString(strconv.Itoa(c.Sum))
+		for _, i := range c.Members {
+			ref := CellRef{i / size, i % size}
+			buf.WriteString(" " + ref.String())
+		}
+		if c.Repeats {
+			buf.WriteString(" repeats")
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// ParseKiller reads a killer puzzle from a slice of bytes in the format
+// written by Grid.KillerString: the grid's givens in either the grid or the
+// line format, then a blank line, then its cages in the format consumed by
+// ParseCages.  The shape is taken from the size of the grid, as in ParseGrid,
+// and the grid's constraints are the KillerConstraints for its cages.
+func ParseKiller(input []byte) (*Grid, error) {
+	return ParsePuzzle(input, CageSection)
+}
+
+// KillerString returns a representation of the grid and its cages in the
+// format consumed by ParseKiller.
+func (g *Grid) KillerString() string {
+	return g.PuzzleString(CageSection)
+}
+
+// CageSection is the Section for the cages of a killer puzzle, in the format
+// read by ParseCages.
+var CageSection = Section{
+	Name: "cages",
+	Parse: func(input []byte, shape Shape) ([]Constraint, error) {
+		cages, err := ParseCages(input, shape)
+		if err != nil {
+			return nil, err
+		}
+		return cageConstraints(shape, cages), nil
+	},
+	Format: func(g *Grid) string {
+		return FormatCages(g.Cages(), g.Size())
+	},
+}
+
+// killerJSON is the JSON representation of a killer puzzle.
+type killerJSON struct {
+	Givens string     `json:"givens"`
+	Cages  []cageJSON `json:"cages"`
+}
+
+// cageJSON holds the references of a cage's cells as strings, since they are
+// only parsed once the size of the grid is known.
+type cageJSON struct {
+	Sum     int      `json:"sum"`
+	Cells   []string `json:"cells"`
+	Repeats bool     `json:"repeats,omitempty"`
+}
+
+// MarshalKiller encodes a killer puzzle as JSON: an object holding the
+// givens in the line format, and an array of cages, each with its sum and
+// the references of its cells.
+//
+// E.g.,
+//
+//	{"givens": "....", "cages": [{"sum": 15, "cells": ["R1C1", "R1C2"]}, ...]}
+func MarshalKiller(g *Grid) ([]byte, error) {
+	k := killerJSON{Givens: g.Line()}
+	for _, c := range g.Cages() {
+		cage := cageJSON{Sum: c.Sum, Repeats: c.Repeats}
+		for _, ref := range c.refs(g) {
+			cage.Cells = append(cage.Cells, ref.String())
+		}
+		k.Cages = append(k.Cages, cage)
+	}
+	return json.Marshal(k)
+}
+
+// UnmarshalKiller decodes a killer puzzle from JSON in the form written by
+// MarshalKiller.  The givens are required, and every cage must have a
+// positive sum and at least one cell.
+func UnmarshalKiller(data []byte) (*Grid, error) {
+	var k killerJSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&k); err != nil {
+		return nil, err
+	}
+	if k.Givens == "" {
+		return nil, errors.New(`killer puzzle has no "givens"`)
+	}
+	g, err := ParseGrid([]byte(k.Givens))
+	if err != nil {
+		return nil, err
+	}
+	size := g.Size()
+	used := make(map[int]bool)
+	cages := make([]Cage, len(k.Cages))
+	for n, c := range k.Cages {
+		if c.Sum < 1 {
+			return nil, fmt.Errorf("invalid cage sum %q", strconv.Itoa(c.Sum))
+		}
+		if len(c.Cells) == 0 {
+			return nil, errors.New("cage has no cells")
+		}
+		cages[n] = Cage{Sum: c.Sum, Repeats: c.Repeats}
+		for _, s := range c.Cells {
+			ref, err := parseCellRef(s, size)
+			if err != nil {
+				return nil, err
+			}
+			i := ref.row*size + ref.col
+			if used[i] {
+				return nil, fmt.Errorf("cell %v is in more than one cage", ref.String())
+			}
+			used[i] = true
+			cages[n].Members = append(cages[n].Members, i)
+		}
+		sort.Ints(cages[n].Members)
+	}
+	g.Constraints = KillerConstraints(g.Shape, cages)
+	return g, nil
+}
+
+// GenerateKiller returns a randomly generated solution grid of the given
+// shape, whose constraints are the KillerConstraints for a random layout of
+// cages over it.
+//
+// Cages are grown from random cells to between 'minCage' and 'maxCage' cells
+// each, without repeating a glyph.  Layouts are tried until one is found
+// whose cages alone determine the solution, up to 'attempts' layouts; after
+// that, the last layout is returned, and a unique puzzle needs some givens as
+// well, as chosen by MinimalMask.
+func GenerateKiller(shape Shape, minCage, maxCage, attempts int) (*Grid, error) {
+	return GenerateKillerContext(context.Background(), shape, minCage, maxCage, attempts)
+}
+
+// GenerateKillerContext is like GenerateKiller, but gives up when the context
+// is cancelled or its deadline expires, returning ctx.Err().
+func GenerateKillerContext(ctx context.Context, shape Shape, minCage, maxCage, attempts int) (*Grid, error) {
+	if minCage < 1 || maxCage < minCage {
+		return nil, fmt.Errorf("invalid cage sizes %v to %v", minCage, maxCage)
+	}
+	solution, err := GenerateGridContext(ctx, shape)
+	if err != nil {
+		return nil, err
+	}
+	random := newRand()
+	for a := 0; ; a++ {
+		cages := randomCages(solution, minCage, maxCage, random)
+		solution.Constraints = KillerConstraints(shape, cages)
+		if a+1 >= attempts {
+			return solution, nil
+		}
+		empty := NewGrid(shape)
+		empty.Constraints = solution.Constraints
+		n, err := empty.NumSolutionsContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if n == 1 {
+			return solution, nil
+		}
+	}
+}
+
+// randomCages divides a solution grid into cages, which are grown from random
+// cells into random neighbouring cells.  A cage stops growing when it reaches
+// its target size, or has no neighbour whose glyph it does not already hold.
+// Cages smaller than 'minCage' may result when there is no room to grow.
+func randomCages(solution *Grid, minCage, maxCage int, random *rand.Rand) (cages []Cage) {
+	s := solution.Shape
+	cageOf := make([]int, len(solution.Cells))
+	for i := range cageOf {
+		cageOf[i] = -1
+	}
+	for _, start := range random.Perm(len(solution.Cells)) {
+		if cageOf[start] >= 0 {
+			continue
+		}
+		target := minCage + random.Intn(maxCage-minCage+1)
+		cage := Cage{Members: []int{start}}
+		glyphs := map[byte]bool{solution.Cells[start]: true}
+		cageOf[start] = len(cages)
+		for len(cage.Members) < target {
+			var options []int
+			for _, i := range cage.Members {
+				for _, j := range s.neighbours(i) {
+					if cageOf[j] < 0 && !glyphs[solution.Cells[j]] {
+						options = append(options, j)
+					}
+				}
+			}
+			if len(options) == 0 {
+				break
+			}
+			j := options[random.Intn(len(options))]
+			cage.Members = append(cage.Members, j)
+			glyphs[solution.Cells[j]] = true
+			cageOf[j] = len(cages)
+		}
+		sort.Ints(cage.Members)
+		for _, i := range cage.Members {
+			cage.Sum += s.glyphIndex(solution.Cells[i]) + 1
+		}
+		cages = append(cages, cage)
+	}
+	sort.Slice(cages, func(a, b int) bool {
+		return cages[a].Members[0] < cages[b].Members[0]
+	})
+	return
+}
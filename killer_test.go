@@ -0,0 +1,291 @@
+package sudoku
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const killerCages = `18 R1C1 R1C2 R1C3
+15 R1C4 R1C5 R2C4 R3C4
+17 R1C6 R1C7 R1C8
+11 R1C9 R2C9
+10 R2C1 R2C2 R3C2
+15 R2C3 R3C3
+9 R2C5 R2C6
+11 R2C7 R2C8
+10 R3C1 R4C1
+17 R3C5 R3C6
+17 R3C7 R3C8 R3C9 R4C9
+10 R4C2 R4C3
+12 R4C4 R5C4
+8 R4C5 R5C5
+8 R4C6 R4C7
+13 R4C8 R5C7 R5C8
+18 R5C1 R6C1 R6C2 R6C3
+9 R5C2 R5C3
+12 R5C6 R6C6 R6C7 R7C6
+19 R5C9 R6C8 R6C9
+16 R6C4 R6C5
+18 R7C1 R7C2 R8C2
+8 R7C3 R7C4
+18 R7C5 R8C3 R8C4 R8C5
+16 R7C7 R7C8
+5 R7C9 R8C9
+5 R8C1 R9C1
+19 R8C6 R8C7 R8C8
+12 R9C2 R9C3 R9C4
+9 R9C5
+10 R9C6 R9C7
+10 R9C8 R9C9
+`
+
+const killerSolution = "936714852518263749247589613891456237754832196623971485382645971179328564465197328"
+
+func TestCageCheck(t *testing.T) {
+	g := NewGrid(Classic)
+	cage := Cage{Sum: 10, Members: []int{0, 1, 2}}
+	cases := []struct {
+		line  string
+		valid bool
+	}{
+		{"...", true},
+		{"12.", true},
+		{"127", true},
+		{"126", false},
+		{"9.2", false},
+		{"55.", false},
+	}
+	for _, c := range cases {
+		g.Clear()
+		g.ReadLine([]byte(c.line + strings.Repeat(".", 78)))
+		if err := cage.Check(g); (err == nil) != c.valid {
+			t.Errorf("incorrect result from Check for %q: expected valid %v, got %v", c.line, c.valid, err)
+		}
+	}
+	g.ReadLine([]byte("55." + strings.Repeat(".", 78)))
+	repeats := Cage{Sum: 10, Members: []int{0, 1}, Repeats: true}
+	if err := repeats.Check(g); err != nil {
+		t.Errorf("unexpected error from Check for repeats: %v", err)
+	}
+	expect := "invalid puzzle: duplicate '5' in cage at R1C1, R1C2"
+	if err := cage.Check(g); err == nil || err.Error() != expect {
+		t.Errorf("incorrect error from Check: expected %q, got %v", expect, err)
+	}
+}
+
+func TestCagePropagate(t *testing.T) {
+	const all = 0x1ff
+	cases := []struct {
+		cage   Cage
+		cand   []uint64
+		expect []uint64
+	}{
+		// 3 in two cells can only be 1 and 2.
+		{Cage{Sum: 3, Members: []int{0, 1}}, []uint64{all, all}, []uint64{0x3, 0x3}},
+		// 24 in three cells can only be 7, 8 and 9.
+		{Cage{Sum: 24, Members: []int{0, 1, 2}}, []uint64{all, all, all}, []uint64{0x1c0, 0x1c0, 0x1c0}},
+		// With 1 placed, 10 in three cells leaves 2+7, 3+6 or 4+5.
+		{Cage{Sum: 10, Members: []int{0, 1, 2}}, []uint64{0x1, all, all}, []uint64{0x1, 0x7e, 0x7e}},
+		// Repeats allow 2+2 as well as 1+3.
+		{Cage{Sum: 4, Members: []int{0, 1}, Repeats: true}, []uint64{all, all}, []uint64{0x7, 0x7}},
+		{Cage{Sum: 4, Members: []int{0, 1}}, []uint64{all, all}, []uint64{0x5, 0x5}},
+	}
+	for _, c := range cases {
+		if !c.cage.Propagate(c.cand) {
+			t.Errorf("unexpected contradiction from Propagate for %v", c.cage)
+			continue
+		}
+		if !reflect.DeepEqual(c.cand, c.expect) {
+			t.Errorf("incorrect result from Propagate for %v: expected %#x, got %#x", c.cage, c.expect, c.cand)
+		}
+	}
+	if (Cage{Sum: 18, Members: []int{0, 1}}).Propagate([]uint64{all, all}) {
+		t.Errorf("no contradiction from Propagate for 18 in two different cells")
+	}
+}
+
+func TestKillerConstraints(t *testing.T) {
+	cages := []Cage{
+		{Sum: 6, Members: []int{0, 1, 2}},
+		{Sum: 15, Members: []int{3, 4, 5}},
+		{Sum: 17, Members: []int{6, 7}},
+	}
+	cs := KillerConstraints(Classic, cages)
+	if len(cs) != 27+3+1 {
+		t.Fatalf("incorrect number of constraints: expected 31, got %v", len(cs))
+	}
+	// The innie R1C9 must be 45-38 = 7.
+	expect := cageSum{Cage{Sum: 7, Members: []int{8}}}
+	if !reflect.DeepEqual(cs[30], expect) {
+		t.Errorf("incorrect innie: expected %v, got %v", expect, cs[30])
+	}
+
+	// Covering the row with a cage that sticks out gives an outie.
+	cages = append(cages, Cage{Sum: 12, Members: []int{8, 17}})
+	cs = KillerConstraints(Classic, cages)
+	expect = cageSum{Cage{Sum: 5, Members: []int{17}, Repeats: true}}
+	found := false
+	for _, c := range cs[31:] {
+		found = found || reflect.DeepEqual(c, expect)
+	}
+	if !found {
+		t.Errorf("no outie %v among derived constraints %v", expect, cs[31:])
+	}
+
+	g := NewGrid(Classic)
+	g.Constraints = cs
+	if cages := g.Cages(); len(cages) != 4 {
+		t.Errorf("incorrect number of cages from Cages: expected 4, got %v", len(cages))
+	}
+}
+
+func TestParseCages(t *testing.T) {
+	cages, err := ParseCages([]byte("# cages\n3 R1C1 R1C2\n\n10 r2c1 R3C1 repeats\n"), Classic)
+	if err != nil {
+		t.Fatalf("unexpected error from ParseCages: %v", err)
+	}
+	expect := []Cage{
+		{Sum: 3, Members: []int{0, 1}},
+		{Sum: 10, Members: []int{9, 18}, Repeats: true},
+	}
+	if !reflect.DeepEqual(cages, expect) {
+		t.Errorf("incorrect result from ParseCages: expected %v, got %v", expect, cages)
+	}
+	text := "3 R1C1 R1C2\n10 R2C1 R3C1 repeats\n"
+	if result := FormatCages(cages, Size); result != text {
+		t.Errorf("incorrect result from FormatCages: expected %q, got %q", text, result)
+	}
+
+	invalid := []string{
+		"x R1C1",
+		"0 R1C1",
+		"3",
+		"3 R1C1 R1C10",
+		"3 R1C1\n4 R1C1 R1C2",
+	}
+	for _, input := range invalid {
+		if _, err := ParseCages([]byte(input), Classic); err == nil {
+			t.Errorf("no error from ParseCages for %q", input)
+		}
+	}
+}
+
+func TestParseKiller(t *testing.T) {
+	input := strings.Repeat(".", GridSize) + "\n\n" + killerCages
+	g, err := ParseKiller([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseKiller: %v", err)
+	}
+	if len(g.Cages()) != 32 {
+		t.Errorf("incorrect number of cages: expected 32, got %v", len(g.Cages()))
+	}
+	expect := NewGrid(Classic).String() + "\n" + killerCages
+	if result := g.KillerString(); result != expect {
+		t.Errorf("incorrect result from KillerString: expected %q, got %q", expect, result)
+	}
+
+	data, err := MarshalKiller(g)
+	if err != nil {
+		t.Fatalf("unexpected error from MarshalKiller: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"givens":"....`) || !strings.Contains(string(data), `{"sum":18,"cells":["R1C1","R1C2","R1C3"]}`) {
+		t.Errorf("incorrect result from MarshalKiller: %s", data)
+	}
+	other, err := UnmarshalKiller(data)
+	if err != nil {
+		t.Fatalf("unexpected error from UnmarshalKiller: %v", err)
+	}
+	if !reflect.DeepEqual(other.Cages(), g.Cages()) {
+		t.Errorf("incorrect cages from UnmarshalKiller: expected %v, got %v", g.Cages(), other.Cages())
+	}
+
+	// Cell references beyond the ninth row or column survive the round
+	// trip, and a cell may not be in two cages.
+	hex, _ := ShapeForSize(16)
+	big := NewGrid(hex)
+	big.Constraints = KillerConstraints(big.Shape, []Cage{{Sum: 3, Members: []int{254, 255}}, {Sum: 7, Members: []int{0, 16}}})
+	data, _ = MarshalKiller(big)
+	other, err = UnmarshalKiller(data)
+	if err != nil {
+		t.Fatalf("unexpected error from UnmarshalKiller for 16×16: %v", err)
+	}
+	if !reflect.DeepEqual(other.Cages(), big.Cages()) {
+		t.Errorf("incorrect cages from UnmarshalKiller: expected %v, got %v", big.Cages(), other.Cages())
+	}
+	data = []byte(strings.Replace(string(data), `"R1C1"`, `"R16C15"`, 1))
+	if _, err := UnmarshalKiller(data); err == nil || err.Error() != "cell R16C15 is in more than one cage" {
+		t.Errorf("incorrect error from UnmarshalKiller for repeated cell: got %v", err)
+	}
+	invalid := []struct {
+		input, expect string
+	}{
+		{`{"givens":"` + strings.Repeat(".", 81) + `","cages":[{"sum":5,"cells":[]}]}`, "cage has no cells"},
+		{`{"givens":"` + strings.Repeat(".", 81) + `","cages":[{"sum":0,"cells":["R1C1"]}]}`, `invalid cage sum "0"`},
+		{`{"cages":[{"sum":5,"cells":["R1C1"]}]}`, `killer puzzle has no "givens"`},
+	}
+	for _, c := range invalid {
+		if _, err := UnmarshalKiller([]byte(c.input)); err == nil || err.Error() != c.expect {
+			t.Errorf("incorrect error from UnmarshalKiller(%v): expected %q, got %v", c.input, c.expect, err)
+		}
+	}
+
+	_, err = ParseKiller([]byte(input + "3 R9C9\n"))
+	if e, ok := err.(*ParseError); !ok || e.Line != 35 {
+		t.Errorf("incorrect error for repeated cell: expected ParseError on line 35, got %v", err)
+	}
+}
+
+func TestKillerSolve(t *testing.T) {
+	g, _ := ParseKiller([]byte(strings.Repeat(".", GridSize) + "\n\n" + killerCages))
+	if n := g.NumSolutions(); n != 1 {
+		t.Errorf("incorrect result from NumSolutions: expected 1, got %v", n)
+	}
+	if remain := g.Solve(); remain != 0 {
+		t.Fatalf("incorrect result from Solve: expected 0, got %v", remain)
+	}
+	if result := g.Line(); result != killerSolution {
+		t.Errorf("incorrect solution: expected %v, got %v", killerSolution, result)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("unexpected error from Validate: %v", err)
+	}
+	// R9C5 is a cage of its own, with the sum 9.
+	g.Clear()
+	g.Set(8, 4, '8')
+	if _, ok := g.Validate().(*ConstraintError); !ok {
+		t.Errorf("expected *ConstraintError from Validate for wrong cage sum, got %v", g.Validate())
+	}
+}
+
+func TestGenerateKiller(t *testing.T) {
+	g, err := GenerateKiller(Classic, 2, 4, 5)
+	if err != nil {
+		t.Fatalf("unexpected error from GenerateKiller: %v", err)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("invalid result from GenerateKiller: %v", err)
+	}
+	seen := make(map[int]bool)
+	for _, c := range g.Cages() {
+		if len(c.Members) > 4 {
+			t.Errorf("cage too large: %v", c)
+		}
+		for _, i := range c.Members {
+			if seen[i] {
+				t.Errorf("cell %v in more than one cage", i)
+			}
+			seen[i] = true
+		}
+	}
+	if len(seen) != GridSize {
+		t.Errorf("incorrect number of cells in cages: expected %v, got %v", GridSize, len(seen))
+	}
+	puzzle := g.ApplyMask(g.MinimalMask())
+	if n := puzzle.NumSolutions(); n != 1 {
+		t.Errorf("incorrect number of solutions for killer puzzle: expected 1, got %v", n)
+	}
+	if _, err := GenerateKiller(Classic, 3, 2, 1); err == nil {
+		t.Errorf("no error from GenerateKiller for invalid cage sizes")
+	}
+}
@@ -0,0 +1,82 @@
+package sudoku
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// LaTeXFormat writes puzzles as LaTeX source, drawing the grid with a TikZ
+// picture.  It cannot be decoded.
+//
+// Givens are set in bold, solved cells in blue, and candidates in a tiny
+// font arranged like the keys of a phone keypad.  The picture requires
+// \usepackage{tikz} in the preamble.
+//
+// The zero value, which is registered under the name "latex", writes the
+// puzzle alone as a tikzpicture environment.
+type LaTeXFormat struct {
+	// Solution fills in the unknown cells from the puzzle's solution.
+	Solution bool
+	// Givens marks the cells which hold clues.  Other known cells are shown
+	// as solved, so that a puzzle which has already been solved can be
+	// written without solving it again.  If nil, every known cell is a given.
+	Givens *Mask
+	// Candidates shows the candidates of each unknown cell.
+	Candidates bool
+	// Highlights are the cells to shade.
+	Highlights []CellRef
+	// Document writes a complete standalone LaTeX document, rather than the
+	// tikzpicture environment alone.
+	Document bool
+}
+
+func (LaTeXFormat) Name() string         { return "latex" }
+func (LaTeXFormat) Extensions() []string { return []string{".tex"} }
+
+func (LaTeXFormat) Decode(input []byte) (Puzzle, error) {
+	return Puzzle{}, ErrEncodeOnly
+}
+
+func (f LaTeXFormat) Encode(w io.Writer, puz *Puzzle) error {
+	values, givens, cg := exportGrid(puz, f.Solution, f.Candidates, f.Givens)
+
+	// TikZ places the origin at the bottom left, so row 'r' occupies the
+	// unit square from y = 8-r to y = 9-r.
+	var buf bytes.Buffer
+	if f.Document {
+		buf.WriteString("\\documentclass[tikz]{standalone}\n\\begin{document}\n")
+	}
+	buf.WriteString("\\begin{tikzpicture}[scale=0.6]\n")
+	for _, ref := range f.Highlights {
+		fmt.Fprintf(&buf, "  \\fill[yellow!30] (%v,%v) rectangle +(1,1);\n", ref.col, Size-1-ref.row)
+	}
+	fmt.Fprintf(&buf, "  \\draw[very thin] (0,0) grid (%v,%v);\n", Size, Size)
+	fmt.Fprintf(&buf, "  \\draw[very thick,step=%v] (0,0) grid (%v,%v);\n", SubSize, Size, Size)
+	for i := 0; i < GridSize; i++ {
+		r, c := indexToCoords(i)
+		x, y := float64(c)+0.5, float64(Size-1-r)+0.5
+		switch {
+		case givens[i]:
+			fmt.Fprintf(&buf, "  \\node at (%v,%v) {\\textbf{%c}};\n", x, y, values[i])
+		case Known(values[i]):
+			fmt.Fprintf(&buf, "  \\node[text=blue] at (%v,%v) {%c};\n", x, y, values[i])
+		case cg != nil:
+			for k, g := range Glyphs {
+				if !cg.Has(indexToCellRef(i), g) {
+					continue
+				}
+				third := 1.0 / SubSize
+				cx := float64(c) + third*(float64(k%SubSize)+0.5)
+				cy := float64(Size-r) - third*(float64(k/SubSize)+0.5)
+				fmt.Fprintf(&buf, "  \\node[font=\\tiny,text=gray] at (%.3f,%.3f) {%c};\n", cx, cy, g)
+			}
+		}
+	}
+	buf.WriteString("\\end{tikzpicture}\n")
+	if f.Document {
+		buf.WriteString("\\end{document}\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,81 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLaTeXFormat(t *testing.T) {
+	puz := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' ',
+	}
+	var givens Mask
+	givens[2] = true
+	cases := []struct {
+		format LaTeXFormat
+		expect []string
+		absent []string
+	}{
+		{
+			LaTeXFormat{},
+			[]string{
+				"\\begin{tikzpicture}[scale=0.6]\n  \\draw[very thin] (0,0) grid (9,9);\n  \\draw[very thick,step=3] (0,0) grid (9,9);\n",
+				"  \\node at (2.5,8.5) {\\textbf{8}};\n",
+				"  \\node at (6.5,0.5) {\\textbf{4}};\n\\end{tikzpicture}\n",
+			},
+			[]string{"documentclass", "text=blue", "\\tiny"},
+		},
+		{
+			LaTeXFormat{Solution: true, Highlights: []CellRef{{8, 0}}, Document: true},
+			[]string{
+				"\\documentclass[tikz]{standalone}\n\\begin{document}\n",
+				"  \\fill[yellow!30] (0,0) rectangle +(1,1);\n",
+				"  \\node[text=blue] at (0.5,8.5) {7};\n",
+				"\\end{tikzpicture}\n\\end{document}\n",
+			},
+			[]string{"\\tiny"},
+		},
+		{
+			LaTeXFormat{Givens: &givens},
+			[]string{
+				"  \\node at (2.5,8.5) {\\textbf{8}};\n",
+				"  \\node[text=blue] at (5.5,8.5) {6};\n",
+			},
+			nil,
+		},
+		{
+			LaTeXFormat{Candidates: true},
+			[]string{
+				"  \\node[font=\\tiny,text=gray] at (0.167,8.833) {1};\n",
+				"  \\node[font=\\tiny,text=gray] at (0.833,8.167) {9};\n",
+			},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := c.format.Encode(&buf, &puz); err != nil {
+			t.Fatalf("unexpected error from Encode: %v", err)
+		}
+		out := buf.String()
+		for _, e := range c.expect {
+			if !strings.Contains(out, e) {
+				t.Errorf("incorrect result from %+v: expected output to contain %q, got:\n%v", c.format, e, out)
+			}
+		}
+		for _, e := range c.absent {
+			if strings.Contains(out, e) {
+				t.Errorf("incorrect result from %+v: expected output not to contain %q, got:\n%v", c.format, e, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package sudoku
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// LineType names a kind of line constraint.
+type LineType string
+
+const (
+	// ThermoLine (thermometer) requires the values to increase strictly from
+	// the bulb, the first cell of the path, to the other end.
+	ThermoLine LineType = "thermo"
+	// ArrowLine requires the values along the arrow's shaft to add up to the
+	// value in its circle, the first cell of the path.  Values may repeat
+	// along the shaft, unless they share a unit.
+	ArrowLine LineType = "arrow"
+	// WhisperLine (German whispers) requires neighbouring values along the
+	// path to differ by more than half the size of the grid: by at least 5
+	// in a 9×9 grid.
+	WhisperLine LineType = "whisper"
+	// RenbanLine requires the path to hold a set of different, consecutive
+	// values, in any order.
+	RenbanLine LineType = "renban"
+	// PalindromeLine requires the path to read the same from either end.
+	PalindromeLine LineType = "palindrome"
+)
+
+// LineTypes lists the supported line types.
+var LineTypes = []LineType{ThermoLine, ArrowLine, WhisperLine, RenbanLine, PalindromeLine}
+
+// Line is a constraint on the values along a path of cells, drawn as a line
+// through their centres.  Each cell of the path is next to the one before it,
+// orthogonally or diagonally, and no cell appears twice.
+//
+// Lines should be made with NewLine, which checks the path and prepares the
+// relations between its cells.
+type Line struct {
+	Type LineType
+	// Path lists the indexes of the line's cells from one end to the other.
+	Path []int
+	// pairs holds the relation between pairs of cells along the path, for
+	// the types which are made up of such relations.
+	pairs Relation
+	// cells lists the cells of the path in grid order.
+	cells []int
+}
+
+// NewLine returns a line of the given type along a path of cells in a grid
+// of the given shape.  It returns an error if the type is unknown, or the
+// path is unsuitable for it.
+func NewLine(shape Shape, t LineType, path []int) (Line, error) {
+	size := shape.Size()
+	l := Line{Type: t, Path: path, cells: append([]int(nil), path...)}
+	sort.Ints(l.cells)
+	if len(path) < 2 {
+		return l, fmt.Errorf("%v line needs at least two cells", t)
+	}
+	seen := make(map[int]bool)
+	for k, i := range path {
+		if i < 0 || i >= size*size {
+			return l, fmt.Errorf("cell %v is outside the grid", i)
+		}
+		if seen[i] {
+			return l, fmt.Errorf("%v line visits %v twice", t, indexRef(i, size))
+		}
+		seen[i] = true
+		if k > 0 {
+			dr, dc := i/size-path[k-1]/size, i%size-path[k-1]%size
+			if dr < -1 || dr > 1 || dc < -1 || dc > 1 {
+				return l, fmt.Errorf("%v line jumps from %v to %v", t, indexRef(path[k-1], size), indexRef(i, size))
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for k := 1; k < len(path); k++ {
+		pairs = append(pairs, [2]int{path[k-1], path[k]})
+	}
+	switch t {
+	case ThermoLine:
+		l.pairs = NewRelation(string(t), shape, pairs, func(a, b int) bool {
+			return a < b
+		})
+	case WhisperLine:
+		gap := size/2 + 1
+		l.pairs = NewRelation(string(t), shape, pairs, func(a, b int) bool {
+			return a-b >= gap || b-a >= gap
+		})
+	case PalindromeLine:
+		pairs = nil
+		for k := 0; k < len(path)/2; k++ {
+			pairs = append(pairs, [2]int{path[k], path[len(path)-1-k]})
+		}
+		l.pairs = NewRelation(string(t), shape, pairs, func(a, b int) bool {
+			return a == b
+		})
+	case RenbanLine:
+		if len(path) > size {
+			return l, fmt.Errorf("renban line has more than %v cells", size)
+		}
+	case ArrowLine:
+	default:
+		return l, fmt.Errorf("unknown line type %q", t)
+	}
+	return l, nil
+}
+
+// indexRef returns the CellRef of a cell index in a grid with 'size' rows and
+// columns.
+func indexRef(i, size int) *CellRef {
+	return &CellRef{i / size, i % size}
+}
+
+// Cells returns the cells of the line in grid order, rather than along its
+// path.
+func (l Line) Cells() []int {
+	return l.cells
+}
+
+// Check returns a *ConstraintError if the known cells along the line break
+// its rule.
+func (l Line) Check(g *Grid) error {
+	switch l.Type {
+	case ArrowLine:
+		return l.checkArrow(g)
+	case RenbanLine:
+		return l.checkRenban(g)
+	}
+	if err := l.pairs.Check(g); err != nil {
+		e := err.(*ConstraintError)
+		e.Constraint = l
+		sort.Slice(e.Cells, func(a, b int) bool {
+			return g.index(e.Cells[a].row, e.Cells[a].col) < g.index(e.Cells[b].row, e.Cells[b].col)
+		})
+		return e
+	}
+	return nil
+}
+
+// checkArrow checks that the shaft of an arrow adds up to no more than its
+// circle, and to exactly its circle once it is complete.
+func (l Line) checkArrow(g *Grid) error {
+	circle := g.Shape.glyphIndex(g.Cells[l.Path[0]])
+	if circle < 0 {
+		return nil
+	}
+	total, known := 0, 0
+	for _, i := range l.Path[1:] {
+		if n := g.Shape.glyphIndex(g.Cells[i]); n >= 0 {
+			total += n + 1
+			known++
+		}
+	}
+	unknown := len(l.Path) - 1 - known
+	if total+unknown > circle+1 || (unknown == 0 && total != circle+1) {
+		return &ConstraintError{
+			Constraint: l,
+			Cells:      l.refs(g),
+			Reason:     fmt.Sprintf("arrow adds up to %v, expected %v", total, circle+1),
+		}
+	}
+	return nil
+}
+
+// checkRenban checks that the known values of a renban are different, and
+// close enough together to be part of a consecutive set.
+func (l Line) checkRenban(g *Grid) error {
+	first, last := -1, -1
+	seen := make(map[byte]int)
+	for _, i := range l.Path {
+		n := g.Shape.glyphIndex(g.Cells[i])
+		if n < 0 {
+			continue
+		}
+		if j, ok := seen[g.Cells[i]]; ok {
+			return &ConstraintError{
+				Constraint: l,
+				Cells:      sortedRefs(g, j, i),
+				Reason:     fmt.Sprintf("duplicate %q on renban", g.Cells[i]),
+			}
+		}
+		seen[g.Cells[i]] = i
+		if first < 0 || n < g.Shape.glyphIndex(g.Cells[first]) {
+			first = i
+		}
+		if last < 0 || n > g.Shape.glyphIndex(g.Cells[last]) {
+			last = i
+		}
+	}
+	if first >= 0 && g.Shape.glyphIndex(g.Cells[last])-g.Shape.glyphIndex(g.Cells[first]) >= len(l.Path) {
+		return &ConstraintError{
+			Constraint: l,
+			Cells:      sortedRefs(g, first, last),
+			Reason:     fmt.Sprintf("renban of %v cells holds %q and %q", len(l.Path), g.Cells[first], g.Cells[last]),
+		}
+	}
+	return nil
+}
+
+// refs returns the CellRefs of the line's cells, in grid order.
+func (l Line) refs(g *Grid) []CellRef {
+	return sortedRefs(g, l.Path...)
+}
+
+// sortedRefs returns the CellRefs of the given cells, in grid order.
+func sortedRefs(g *Grid, cells ...int) []CellRef {
+	sorted := append([]int(nil), cells...)
+	sort.Ints(sorted)
+	refs := make([]CellRef, len(sorted))
+	for k, i := range sorted {
+		refs[k] = g.cellRef(i)
+	}
+	return refs
+}
+
+// Propagate removes the candidates along the line which cannot be part of
+// any set of values obeying its rule.
+func (l Line) Propagate(cand []uint64) bool {
+	switch l.Type {
+	case ArrowLine:
+		return l.propagateArrow(cand)
+	case RenbanLine:
+		return l.propagateRenban(cand)
+	}
+	return l.pairs.Propagate(cand)
+}
+
+// propagateArrow keeps the candidates of the circle which the shaft can add
+// up to, and the candidates of the shaft which add up to one of them.
+func (l Line) propagateArrow(cand []uint64) bool {
+	shaft := make([]uint64, len(l.Path)-1)
+	for k, i := range l.Path[1:] {
+		shaft[k] = cand[i]
+	}
+	var circle uint64
+	supported := make([]uint64, len(shaft))
+	for c := cand[l.Path[0]]; c != 0; c &= c - 1 {
+		n := bits.TrailingZeros64(c)
+		s := sumSupport(shaft, n+1)
+		if s[0] == 0 {
+			continue
+		}
+		circle |= 1 << uint(n)
+		for k := range s {
+			supported[k] |= s[k]
+		}
+	}
+	if circle == 0 {
+		return false
+	}
+	cand[l.Path[0]] = circle
+	for k, i := range l.Path[1:] {
+		cand[i] = supported[k]
+	}
+	return true
+}
+
+// propagateRenban keeps the candidates which fit into a matching of some set
+// of consecutive values to the cells of the line.
+func (l Line) propagateRenban(cand []uint64) bool {
+	n := len(l.Path)
+	cells := make([]uint64, n)
+	var union uint64
+	for k, i := range l.Path {
+		cells[k] = cand[i]
+		union |= cand[i]
+	}
+	supported := make([]uint64, n)
+	for start := 0; start+n <= 64-bits.LeadingZeros64(union); start++ {
+		set := (uint64(1)<<uint(n) - 1) << uint(start)
+		for k := range cells {
+			for c := cells[k] & set &^ supported[k]; c != 0; c &= c - 1 {
+				bit := c & -c
+				if matchable(cells, 1<<uint(k), set&^bit) {
+					supported[k] |= bit
+				}
+			}
+		}
+	}
+	for k, i := range l.Path {
+		cand[i] = supported[k]
+		if cand[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LineConstraints returns the DefaultConstraints for a shape followed by the
+// given lines.
+func LineConstraints(shape Shape, lines []Line) []Constraint {
+	return append(DefaultConstraints(shape), lineConstraints(lines)...)
+}
+
+// lineConstraints returns the given lines as constraints.
+func lineConstraints(lines []Line) (result []Constraint) {
+	for _, l := range lines {
+		result = append(result, l)
+	}
+	return result
+}
+
+// Lines returns the lines among the grid's constraints.
+func (g *Grid) Lines() (lines []Line) {
+	for _, c := range g.Constraints {
+		if l, ok := c.(Line); ok {
+			lines = append(lines, l)
+		}
+	}
+	return
+}
+
+// ParseLines reads lines for a grid of the given shape from a slice of bytes.
+//
+// Each line is given on a line of its own, as its type followed by the
+// references of its cells in order along the path, separated by spaces; e.g.
+// "thermo R1C1 R1C2 R2C3".  The circle of an arrow is its first cell.  Blank
+// lines and comment lines beginning with '#' are ignored.
+func ParseLines(input []byte, shape Shape) (lines []Line, err error) {
+	size := shape.Size()
+	for n, text := range bytes.Split(input, []byte("\n")) {
+		if skip(text) {
+			continue
+		}
+		fields := strings.Fields(string(text))
+		var path []int
+		for _, field := range fields[1:] {
+			ref, err := parseCellRef(field, size)
+			if err != nil {
+				return nil, &ParseError{Line: n + 1, Reason: err.Error()}
+			}
+			path = append(path, ref.row*size+ref.col)
+		}
+		l, err := NewLine(shape, LineType(strings.ToLower(fields[0])), path)
+		if err != nil {
+			return nil, &ParseError{Line: n + 1, Reason: err.Error()}
+		}
+		lines = append(lines, l)
+	}
+	return
+}
+
+// FormatLines returns the lines of a grid with 'size' rows and columns in the
+// format consumed by ParseLines.
+func FormatLines(lines []Line, size int) string {
+	var buf bytes.Buffer
+	for _, l := range lines {
+		buf.WriteString(string(l.Type))
+		for _, i := range l.Path {
+			buf.WriteString(" " + indexRef(i, size).String())
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// ParseLinePuzzle reads a puzzle with line constraints from a slice of bytes
+// in the format written by Grid.LinePuzzleString: the grid's givens in either
+// the grid or the line format, then a blank line, then its lines in the
+// format consumed by ParseLines.  The shape is taken from the size of the
+// grid, as in ParseGrid, and the grid's constraints are the LineConstraints
+// for its lines.
+func ParseLinePuzzle(input []byte) (*Grid, error) {
+	return ParsePuzzle(input, LineSection)
+}
+
+// LinePuzzleString returns a representation of the grid and its lines in the
+// format consumed by ParseLinePuzzle.
+func (g *Grid) LinePuzzleString() string {
+	return g.PuzzleString(LineSection)
+}
+
+// LineSection is the Section for line constraints, in the format read by
+// ParseLines.
+var LineSection = Section{
+	Name: "lines",
+	Parse: func(input []byte, shape Shape) ([]Constraint, error) {
+		lines, err := ParseLines(input, shape)
+		if err != nil {
+			return nil, err
+		}
+		return lineConstraints(lines), nil
+	},
+	Format: func(g *Grid) string {
+		return FormatLines(g.Lines(), g.Size())
+	},
+}
@@ -0,0 +1,213 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// linesFixture describes lines which agree with killerSolution.
+const linesFixture = `thermo R2C2 R3C2 R3C3 R2C3
+arrow R4C2 R5C1 R6C2
+whisper R7C2 R8C1 R9C2
+renban R5C7 R4C7 R4C8
+palindrome R6C7 R7C8 R8C9
+`
+
+func TestNewLine(t *testing.T) {
+	cases := []struct {
+		t    LineType
+		path []int
+		err  string
+	}{
+		{ThermoLine, []int{0, 1, 2}, ""},
+		{ArrowLine, []int{40, 30, 20}, ""},
+		{ThermoLine, []int{0}, "thermo line needs at least two cells"},
+		{WhisperLine, []int{0, 2}, "whisper line jumps from R1C1 to R1C3"},
+		{RenbanLine, []int{0, 1, 0}, "renban line visits R1C1 twice"},
+		{PalindromeLine, []int{80, 81}, "cell 81 is outside the grid"},
+		{RenbanLine, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 17}, "renban line has more than 9 cells"},
+		{LineType("snake"), []int{0, 1}, `unknown line type "snake"`},
+	}
+	for _, c := range cases {
+		l, err := NewLine(Classic, c.t, c.path)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("unexpected error from NewLine(%v, %v): %v", c.t, c.path, err)
+		case c.err != "" && (err == nil || err.Error() != c.err):
+			t.Errorf("incorrect error from NewLine(%v, %v): expected %q, got %v", c.t, c.path, c.err, err)
+		case c.err == "" && len(l.Cells()) != len(c.path):
+			t.Errorf("incorrect cells for %v: expected %v, got %v", c.t, len(c.path), l.Cells())
+		}
+	}
+}
+
+func TestLineCheck(t *testing.T) {
+	lines, err := ParseLines([]byte(linesFixture), Classic)
+	if err != nil {
+		t.Fatalf("unexpected error from ParseLines: %v", err)
+	}
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(killerSolution))
+	for _, l := range lines {
+		if err := l.Check(g); err != nil {
+			t.Errorf("unexpected error from Check for %v: %v", l.Type, err)
+		}
+	}
+
+	cases := []struct {
+		line   int
+		cells  map[int]byte
+		expect string
+	}{
+		// Thermometer R2C2 R3C2 R3C3 R2C3 with R3C3 below R3C2.
+		{0, map[int]byte{19: '5', 20: '3'}, "invalid puzzle: thermo rule broken by '5' and '3' at R3C2, R3C3"},
+		{1, map[int]byte{28: '9', 36: '8', 46: '3'}, "invalid puzzle: arrow adds up to 11, expected 9 at R4C2, R5C1, R6C2"},
+		{1, map[int]byte{28: '9', 36: '3', 46: '4'}, "invalid puzzle: arrow adds up to 7, expected 9 at R4C2, R5C1, R6C2"},
+		{2, map[int]byte{55: '8', 63: '4'}, "invalid puzzle: whisper rule broken by '8' and '4' at R7C2, R8C1"},
+		{3, map[int]byte{42: '2', 34: '5'}, "invalid puzzle: renban of 3 cells holds '2' and '5' at R4C8, R5C7"},
+		{3, map[int]byte{42: '2', 33: '2'}, "invalid puzzle: duplicate '2' on renban at R4C7, R5C7"},
+		{4, map[int]byte{51: '4', 71: '5'}, "invalid puzzle: palindrome rule broken by '4' and '5' at R6C7, R8C9"},
+	}
+	for _, c := range cases {
+		g.Clear()
+		for i, glyph := range c.cells {
+			g.Cells[i] = glyph
+		}
+		err := lines[c.line].Check(g)
+		if err == nil || err.Error() != c.expect {
+			t.Errorf("incorrect result from Check: expected %q, got %v", c.expect, err)
+		}
+	}
+}
+
+func TestLinePropagate(t *testing.T) {
+	full := func() []uint64 {
+		cand := make([]uint64, 81)
+		for i := range cand {
+			cand[i] = 0x1ff
+		}
+		return cand
+	}
+
+	// A thermometer of nine cells can only hold 1 to 9 in order.
+	thermo, _ := NewLine(Classic, ThermoLine, []int{0, 1, 2, 3, 4, 5, 6, 7, 8})
+	cand := full()
+	for k := 0; k < 9; k++ {
+		thermo.Propagate(cand)
+	}
+	for k := 0; k < 9; k++ {
+		if cand[k] != 1<<uint(k) {
+			t.Errorf("incorrect candidates for thermometer cell %v: expected %09b, got %09b", k, 1<<uint(k), cand[k])
+		}
+	}
+
+	// A whisper never holds 5 in a 9×9 grid.
+	whisper, _ := NewLine(Classic, WhisperLine, []int{0, 1})
+	cand = full()
+	whisper.Propagate(cand)
+	if expected := uint64(0x1ff &^ (1 << 4)); cand[0] != expected || cand[1] != expected {
+		t.Errorf("incorrect whisper candidates: expected %09b, got %09b and %09b", expected, cand[0], cand[1])
+	}
+
+	// An arrow of two cells needs a circle of at least 2, and a shaft below 9.
+	arrow, _ := NewLine(Classic, ArrowLine, []int{0, 1, 2})
+	cand = full()
+	arrow.Propagate(cand)
+	if cand[0] != 0x1fe || cand[1] != 0xff || cand[2] != 0xff {
+		t.Errorf("incorrect arrow candidates: got %09b, %09b and %09b", cand[0], cand[1], cand[2])
+	}
+	cand[0] = 1 << 1
+	if !arrow.Propagate(cand) || cand[1] != 1 || cand[2] != 1 {
+		t.Errorf("incorrect arrow candidates for a circle of 2: got %09b and %09b", cand[1], cand[2])
+	}
+
+	// A renban of three cells with a 9 holds 7, 8 and 9.
+	renban, _ := NewLine(Classic, RenbanLine, []int{0, 1, 2})
+	cand = full()
+	cand[1] = 1 << 8
+	renban.Propagate(cand)
+	if expected := uint64(1<<6 | 1<<7); cand[0] != expected || cand[2] != expected {
+		t.Errorf("incorrect renban candidates: expected %09b, got %09b and %09b", expected, cand[0], cand[2])
+	}
+	cand[0], cand[2] = 1<<7, 1<<7
+	if renban.Propagate(cand) {
+		t.Errorf("expected failure from Propagate for repeated renban glyph")
+	}
+
+	// The ends of a palindrome share their candidates.
+	palindrome, _ := NewLine(Classic, PalindromeLine, []int{0, 10, 20})
+	cand = full()
+	cand[0] = 1<<2 | 1<<3
+	cand[20] = 1<<3 | 1<<4
+	palindrome.Propagate(cand)
+	if cand[0] != 1<<3 || cand[20] != 1<<3 || cand[10] != 0x1ff {
+		t.Errorf("incorrect palindrome candidates: got %09b, %09b and %09b", cand[0], cand[10], cand[20])
+	}
+}
+
+func TestParseLinePuzzle(t *testing.T) {
+	lines, _ := ParseLines([]byte(linesFixture), Classic)
+	if result := FormatLines(lines, Size); result != linesFixture {
+		t.Errorf("incorrect result from FormatLines: expected %q, got %q", linesFixture, result)
+	}
+
+	solution := NewGrid(Classic)
+	solution.ReadLine([]byte(killerSolution))
+	solution.Constraints = LineConstraints(Classic, lines)
+	if err := solution.Validate(); err != nil {
+		t.Fatalf("unexpected error from Validate: %v", err)
+	}
+	puzzle := solution.ApplyMask(solution.MinimalMask())
+	if n := puzzle.NumSolutions(); n != 1 {
+		t.Fatalf("incorrect result from NumSolutions: expected 1, got %v", n)
+	}
+
+	g, err := ParseLinePuzzle([]byte(puzzle.LinePuzzleString()))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseLinePuzzle: %v", err)
+	}
+	if !g.Equal(puzzle) || len(g.Lines()) != len(lines) {
+		t.Errorf("incorrect result from ParseLinePuzzle: expected\n%v\ngot\n%v", puzzle.LinePuzzleString(), g.LinePuzzleString())
+	}
+	g.Solve()
+	if result := g.Line(); result != killerSolution {
+		t.Errorf("incorrect solution: expected %v, got %v", killerSolution, result)
+	}
+
+	_, err = ParseLinePuzzle([]byte(strings.Repeat(".", 81) + "\n\nthermo R1C1 R3C3\n"))
+	if e, ok := err.(*ParseError); !ok || e.Line != 3 {
+		t.Errorf("expected ParseError on line 3 from ParseLinePuzzle, got %v", err)
+	}
+	if _, err := ParseLinePuzzle([]byte(strings.Repeat(".", 81))); err == nil {
+		t.Errorf("expected error from ParseLinePuzzle without lines")
+	}
+}
+
+func TestRenderLines(t *testing.T) {
+	lines, _ := ParseLines([]byte(linesFixture), Classic)
+	var puz Puzzle
+	var buf bytes.Buffer
+	if err := puz.RenderSVG(&buf, &RenderOptions{Lines: lines}); err != nil {
+		t.Fatalf("unexpected error from RenderSVG: %v", err)
+	}
+	out := buf.String()
+	// A bulb and two corners for the thermometer, the arrow's circle, and one
+	// corner for each of the other lines.
+	if n := strings.Count(out, "<circle"); n != 7 {
+		t.Errorf("incorrect number of circles from RenderSVG: expected 7, got %v", n)
+	}
+	if !strings.Contains(out, `fill="none" stroke="#999999"`) {
+		t.Errorf("expected an empty circle for the arrow, got:\n%v", out)
+	}
+	// One line for each step along each line, and two for the arrowhead.
+	if n, expected := strings.Count(out, "<line"), 2*(Size+1)+3+2+2+2+2+2; n != expected {
+		t.Errorf("incorrect number of lines from RenderSVG: expected %v, got %v", expected, n)
+	}
+
+	img := puz.RenderImage(&RenderOptions{Lines: lines})
+	// The bulb of the thermometer, at the centre of R2C2.
+	if r, g, b, _ := img.At(80, 80).RGBA(); r>>8 != 0xbb || g>>8 != 0xbb || b>>8 != 0xbb {
+		t.Errorf("incorrect colour for the thermometer bulb: got %x %x %x", r>>8, g>>8, b>>8)
+	}
+}
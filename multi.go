@@ -0,0 +1,440 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layout describes how the grids of a multi-grid puzzle overlap on a board.
+//
+// The position of each grid is given in boxes rather than cells, so that the
+// grids overlap in whole boxes, which belong to every grid covering them.
+// The predefined layouts are drawn for grids three boxes wide and three boxes
+// high, as in the Classic shape.
+type Layout struct {
+	Name string
+	// Grids gives the row and column of the top left box of each grid,
+	// counted from zero.
+	Grids [][2]int
+}
+
+var (
+	// SamuraiLayout has four grids at the corners of the board, each sharing
+	// its inner corner box with a fifth grid in the centre.
+	SamuraiLayout = Layout{"samurai", [][2]int{{0, 0}, {0, 4}, {2, 2}, {4, 0}, {4, 4}}}
+	// ButterflyLayout has four grids on a board four boxes square, each
+	// offset from its neighbours by one box.
+	ButterflyLayout = Layout{"butterfly", [][2]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}}}
+	// TwodokuLayout has two grids sharing a corner box.
+	TwodokuLayout = Layout{"twodoku", [][2]int{{0, 0}, {2, 2}}}
+)
+
+// Layouts lists the predefined layouts.
+var Layouts = []Layout{SamuraiLayout, ButterflyLayout, TwodokuLayout}
+
+// LookupLayout returns the predefined layout with the given name, or an
+// error if there is none.
+func LookupLayout(name string) (Layout, error) {
+	for _, l := range Layouts {
+		if l.Name == strings.ToLower(name) {
+			return l, nil
+		}
+	}
+	return Layout{}, fmt.Errorf("unknown layout %q", name)
+}
+
+// predefined returns whether the layout is one of those in Layouts.
+func (l Layout) predefined() bool {
+	for _, p := range Layouts {
+		if l.Name == p.Name && fmt.Sprint(l.Grids) == fmt.Sprint(p.Grids) {
+			return true
+		}
+	}
+	return false
+}
+
+// Multi is a puzzle made of several overlapping grids of one shape, such as
+// a Samurai sudoku.  Each grid must obey the default constraints, and the
+// cells shared between grids hold the same glyph in each.
+//
+// A Multi should be made with NewMulti, which works out which cells are
+// shared.
+type Multi struct {
+	Shape  Shape
+	Layout Layout
+	// Cells holds each cell of the board covered by any grid, in top to
+	// bottom, left to right order, and contains either one of the shape's
+	// glyphs or Unknown.  Cells shared between grids appear once.
+	Cells []byte
+	// width and height are the dimensions of the board in cells, and board
+	// holds the index within Cells of each position on the board, or -1 for
+	// a position outside every grid.
+	width, height int
+	board         []int
+	// grids holds the index within Cells of each cell of each grid, in the
+	// grid's own order.
+	grids [][]int
+}
+
+// NewMulti returns an empty puzzle of grids of the given shape, placed
+// according to the layout.  It returns an error if the shape is a jigsaw,
+// which has no boxes to share, or if the layout is one of the predefined
+// layouts and the shape's boxes are not 3×3.  It also returns an error if
+// the layout has no grids, places a grid off the board or places two grids in
+// the same position.
+func NewMulti(shape Shape, layout Layout) (*Multi, error) {
+	if err := shape.Validate(); err != nil {
+		return nil, err
+	}
+	if shape.Regions != "" {
+		return nil, fmt.Errorf("layout %q needs a grid with rectangular boxes", layout.Name)
+	}
+	if layout.predefined() && (shape.BoxWidth != SubSize || shape.BoxHeight != SubSize) {
+		return nil, fmt.Errorf("layout %q needs a grid with %v×%v boxes", layout.Name, SubSize, SubSize)
+	}
+	if len(layout.Grids) == 0 {
+		return nil, fmt.Errorf("layout %q has no grids", layout.Name)
+	}
+	size := shape.Size()
+	m := &Multi{Shape: shape, Layout: layout}
+	seen := make(map[[2]int]bool)
+	for k, pos := range layout.Grids {
+		if pos[0] < 0 || pos[1] < 0 {
+			return nil, fmt.Errorf("grid %v of layout %q is off the board", k+1, layout.Name)
+		}
+		if seen[pos] {
+			return nil, fmt.Errorf("grids of layout %q overlap completely at box %v, %v", layout.Name, pos[0], pos[1])
+		}
+		seen[pos] = true
+		if bottom := pos[0]*shape.BoxHeight + size; bottom > m.height {
+			m.height = bottom
+		}
+		if right := pos[1]*shape.BoxWidth + size; right > m.width {
+			m.width = right
+		}
+	}
+
+	m.board = make([]int, m.width*m.height)
+	for p := range m.board {
+		m.board[p] = -1
+	}
+	for _, pos := range layout.Grids {
+		top, left := m.origin(pos)
+		for r := top; r < top+size; r++ {
+			for c := left; c < left+size; c++ {
+				m.board[r*m.width+c] = 0
+			}
+		}
+	}
+	n := 0
+	for p, i := range m.board {
+		if i == 0 {
+			m.board[p] = n
+			n++
+		}
+	}
+	m.Cells = make([]byte, n)
+	m.Clear()
+	for _, pos := range layout.Grids {
+		top, left := m.origin(pos)
+		cells := make([]int, 0, shape.NumCells())
+		for r := top; r < top+size; r++ {
+			for c := left; c < left+size; c++ {
+				cells = append(cells, m.board[r*m.width+c])
+			}
+		}
+		m.grids = append(m.grids, cells)
+	}
+	return m, nil
+}
+
+// origin returns the row and column on the board of the top left cell of the
+// grid whose top left box is at 'pos'.
+func (m *Multi) origin(pos [2]int) (row, col int) {
+	return pos[0] * m.Shape.BoxHeight, pos[1] * m.Shape.BoxWidth
+}
+
+// NumGrids returns the number of grids in the puzzle.
+func (m *Multi) NumGrids() int {
+	return len(m.grids)
+}
+
+// Dimensions returns the number of rows and columns of the board.
+func (m *Multi) Dimensions() (rows, cols int) {
+	return m.height, m.width
+}
+
+// Grid returns a copy of the cells of grid 'k', counted from zero, with the
+// default constraints.
+func (m *Multi) Grid(k int) *Grid {
+	g := NewGrid(m.Shape)
+	for j, i := range m.grids[k] {
+		g.Cells[j] = m.Cells[i]
+	}
+	return g
+}
+
+// SetGrid copies the cells of a grid of the puzzle's shape into grid 'k',
+// counted from zero, including those it shares with other grids.
+func (m *Multi) SetGrid(k int, g *Grid) {
+	for j, i := range m.grids[k] {
+		m.Cells[i] = g.Cells[j]
+	}
+}
+
+// Clear sets every cell of the puzzle to Unknown.
+func (m *Multi) Clear() {
+	for i := range m.Cells {
+		m.Cells[i] = Unknown
+	}
+}
+
+// Copy returns a copy of the puzzle.
+func (m *Multi) Copy() *Multi {
+	c := *m
+	c.Cells = append([]byte(nil), m.Cells...)
+	return &c
+}
+
+// Equal returns whether two puzzles have the same shape, layout and
+// contents.
+func (m *Multi) Equal(other *Multi) bool {
+	return m.Shape == other.Shape && m.Layout.Name == other.Layout.Name &&
+		fmt.Sprint(m.Layout.Grids) == fmt.Sprint(other.Layout.Grids) &&
+		bytes.Equal(m.Cells, other.Cells)
+}
+
+// NumUnknowns returns the number of unknown cells in the puzzle, counting
+// each shared cell once.
+func (m *Multi) NumUnknowns() (count int) {
+	for _, glyph := range m.Cells {
+		if !m.Shape.Known(glyph) {
+			count++
+		}
+	}
+	return
+}
+
+// Constraints returns the units of every grid, with their members given as
+// indexes within Cells.  A box shared between grids is included once.
+func (m *Multi) Constraints() []Constraint {
+	var result []Constraint
+	shared := make(map[string]bool)
+	for _, cells := range m.grids {
+		for _, c := range DefaultConstraints(m.Shape) {
+			u := c.(Unit)
+			members := make([]int, len(u.Members))
+			for k, i := range u.Members {
+				members[k] = cells[i]
+			}
+			if u.Type == SubGridUnit {
+				key := fmt.Sprint(members)
+				if shared[key] {
+					continue
+				}
+				shared[key] = true
+			}
+			result = append(result, Unit{Type: u.Type, Index: u.Index, Members: members})
+		}
+	}
+	return result
+}
+
+// grid returns the whole puzzle as a single Grid holding every cell, whose
+// constraints are those of every grid.  Its cells are shared with the
+// puzzle's.
+func (m *Multi) grid() *Grid {
+	return &Grid{Shape: m.Shape, Cells: m.Cells, Constraints: m.Constraints()}
+}
+
+// Validate checks each grid of the puzzle in turn, and returns the first
+// error found as a *GridError.
+func (m *Multi) Validate() error {
+	for k := range m.grids {
+		if err := m.Grid(k).Validate(); err != nil {
+			return &GridError{Grid: k, Err: err}
+		}
+	}
+	return nil
+}
+
+// Solve attempts to solve every grid of the puzzle together, and returns the
+// number of unknown cells remaining, in the same manner as Grid.Solve.
+func (m *Multi) Solve() (remain int) {
+	return m.grid().Solve()
+}
+
+// SolveContext is like Solve, but abandons the search when the context is
+// cancelled or its deadline expires, in the same manner as Grid.SolveContext.
+func (m *Multi) SolveContext(ctx context.Context) (remain int, err error) {
+	return m.grid().SolveContext(ctx)
+}
+
+// NumSolutions returns the number of solutions to the puzzle as a whole, or
+// two if it has more than one.  The puzzle is not modified.
+func (m *Multi) NumSolutions() int {
+	return m.grid().NumSolutions()
+}
+
+// NumSolutionsContext is like NumSolutions, but abandons the count when the
+// context is cancelled or its deadline expires, in the same manner as
+// Grid.NumSolutionsContext.
+func (m *Multi) NumSolutionsContext(ctx context.Context) (int, error) {
+	return m.grid().NumSolutionsContext(ctx)
+}
+
+// multiCheckLimit bounds the time spent by Multi.MinimalMask checking whether
+// one clue can be removed.  With few clues left, the search over a large board
+// occasionally takes far longer than usual, and keeping the clue is always
+// safe.
+const multiCheckLimit = 250 * time.Millisecond
+
+// MinimalMask returns a clue mask for the given solution, with one value for
+// each of its cells, such that the puzzle as a whole has a unique solution;
+// see Grid.MinimalMask.  A single grid of the result may have several
+// solutions on its own.
+//
+// The mask is minimal unless checking whether some clue could be removed took
+// too long, in which case the clue is kept.
+func (m *Multi) MinimalMask() []bool {
+	mask, _ := m.MinimalMaskContext(context.Background())
+	return mask
+}
+
+// MinimalMaskContext is like MinimalMask, but stops removing clues when the
+// context is cancelled or its deadline expires, in the same manner as
+// Grid.MinimalMaskContext.
+func (m *Multi) MinimalMaskContext(ctx context.Context) ([]bool, error) {
+	return m.grid().minimalMask(ctx, multiCheckLimit)
+}
+
+// ApplyMask returns a copy of the puzzle in which each cell is hidden unless
+// it is true in the mask.
+func (m *Multi) ApplyMask(mask []bool) *Multi {
+	result := m.Copy()
+	for i := range result.Cells {
+		if !mask[i] {
+			result.Cells[i] = Unknown
+		}
+	}
+	return result
+}
+
+// GenerateMulti returns a randomly generated solution to a puzzle of grids of
+// the given shape, placed according to the layout.
+func GenerateMulti(shape Shape, layout Layout) (*Multi, error) {
+	return GenerateMultiContext(context.Background(), shape, layout)
+}
+
+// GenerateMultiContext is like GenerateMulti, but gives up when the context
+// is cancelled or its deadline expires, in which case it returns ctx.Err().
+func GenerateMultiContext(ctx context.Context, shape Shape, layout Layout) (*Multi, error) {
+	m, err := NewMulti(shape, layout)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := m.grid().fillRandom(ctx)
+	if !ok {
+		if err == nil {
+			err = fmt.Errorf("layout %q cannot be filled", layout.Name)
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// GenerateMultiPuzzle returns a randomly generated puzzle of grids of the
+// given shape, placed according to the layout, along with its solution.  The
+// puzzle as a whole has a unique solution; see Multi.MinimalMask.
+func GenerateMultiPuzzle(shape Shape, layout Layout) (puzzle, solution *Multi, err error) {
+	return GenerateMultiPuzzleContext(context.Background(), shape, layout)
+}
+
+// GenerateMultiPuzzleContext is like GenerateMultiPuzzle, but gives up when
+// the context is cancelled or its deadline expires.
+//
+// If the solution is complete by then, it returns ctx.Err() along with the
+// solution and a puzzle which still has a unique solution but may not be
+// minimal.  Otherwise it returns ctx.Err() alone.
+func GenerateMultiPuzzleContext(ctx context.Context, shape Shape, layout Layout) (puzzle, solution *Multi, err error) {
+	solution, err = GenerateMultiContext(ctx, shape, layout)
+	if err != nil {
+		return nil, nil, err
+	}
+	mask, err := solution.MinimalMaskContext(ctx)
+	return solution.ApplyMask(mask), solution, err
+}
+
+// String returns a drawing of the board, in the format consumed by
+// ParseMulti: one line for each row of the board, with a glyph or an
+// underscore for each cell, separated by single spaces.  Positions outside
+// every grid are left blank, and trailing blanks are trimmed.
+func (m *Multi) String() string {
+	var buf bytes.Buffer
+	line := make([]byte, 2*m.width)
+	for r := 0; r < m.height; r++ {
+		for c := 0; c < m.width; c++ {
+			glyph := byte(' ')
+			if i := m.board[r*m.width+c]; i >= 0 {
+				glyph = '_'
+				if m.Shape.Known(m.Cells[i]) {
+					glyph = m.Cells[i]
+				}
+			}
+			line[2*c], line[2*c+1] = glyph, ' '
+		}
+		buf.Write(bytes.TrimRight(line, " "))
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// ParseMulti reads a puzzle of grids of the given shape, placed according to
+// the layout, from a drawing of the board as written by Multi.String.
+//
+// Each line holds one row of the board, with one byte for each position
+// followed by a separator.  Unknown cells are indicated by an underscore or
+// a space, and positions outside every grid must be blank.  Lines may stop
+// short after the last cell they hold.
+func ParseMulti(input []byte, shape Shape, layout Layout) (*Multi, error) {
+	m, err := NewMulti(shape, layout)
+	if err != nil {
+		return nil, &ParseError{Reason: err.Error()}
+	}
+	input = bytes.Trim(bytes.Replace(input, []byte("\r\n"), []byte("\n"), -1), "\n")
+	lines := bytes.Split(input, []byte("\n"))
+	if len(lines) != m.height {
+		return nil, &ParseError{Reason: fmt.Sprintf("expected %v lines, got %v", m.height, len(lines))}
+	}
+	for r, line := range lines {
+		if len(line) > 2*m.width {
+			return nil, &ParseError{Line: r + 1, Reason: fmt.Sprintf("expected at most %v bytes, got %v", 2*m.width, len(line))}
+		}
+		for c := 0; c < m.width; c++ {
+			glyph := byte(' ')
+			if 2*c < len(line) {
+				glyph = line[2*c]
+			}
+			i := m.board[r*m.width+c]
+			switch {
+			case i < 0 && glyph == ' ':
+			case i < 0:
+				return nil, &ParseError{Line: r + 1, Column: 2*c + 1, Byte: glyph, Reason: "expected a space outside every grid"}
+			case shape.Known(glyph):
+				m.Cells[i] = glyph
+			case glyph == '_' || glyph == ' ':
+			default:
+				return nil, &ParseError{
+					Line:   r + 1,
+					Column: 2*c + 1,
+					Byte:   glyph,
+					Reason: fmt.Sprintf("expected underscore, space or %v, got %q", shape.describe(false), glyph),
+				}
+			}
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,207 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewMulti(t *testing.T) {
+	cases := []struct {
+		layout     Layout
+		grids      int
+		rows, cols int
+		cells      int
+	}{
+		{SamuraiLayout, 5, 21, 21, 5*81 - 4*9},
+		{ButterflyLayout, 4, 12, 12, 144},
+		{TwodokuLayout, 2, 15, 15, 2*81 - 9},
+	}
+	for _, c := range cases {
+		m, err := NewMulti(Classic, c.layout)
+		if err != nil {
+			t.Fatalf("unexpected error from NewMulti(%v): %v", c.layout.Name, err)
+		}
+		if n := m.NumGrids(); n != c.grids {
+			t.Errorf("incorrect number of grids for %v: expected %v, got %v", c.layout.Name, c.grids, n)
+		}
+		if rows, cols := m.Dimensions(); rows != c.rows || cols != c.cols {
+			t.Errorf("incorrect dimensions for %v: expected %v×%v, got %v×%v", c.layout.Name, c.rows, c.cols, rows, cols)
+		}
+		if n := len(m.Cells); n != c.cells || m.NumUnknowns() != n {
+			t.Errorf("incorrect cells for %v: expected %v unknown, got %v with %v unknown", c.layout.Name, c.cells, n, m.NumUnknowns())
+		}
+	}
+
+	shape, _ := JigsawShape(Size, BoxRegions(Classic))
+	small, _ := ShapeForSize(4)
+	errors := []struct {
+		shape  Shape
+		layout Layout
+		err    string
+	}{
+		{shape, TwodokuLayout, `layout "twodoku" needs a grid with rectangular boxes`},
+		{small, SamuraiLayout, `layout "samurai" needs a grid with 3×3 boxes`},
+		{Classic, Layout{Name: "empty"}, `layout "empty" has no grids`},
+		{Classic, Layout{"off", [][2]int{{0, 0}, {-1, 2}}}, `grid 2 of layout "off" is off the board`},
+		{Classic, Layout{"same", [][2]int{{1, 1}, {1, 1}}}, `grids of layout "same" overlap completely at box 1, 1`},
+	}
+	for _, c := range errors {
+		_, err := NewMulti(c.shape, c.layout)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("incorrect error from NewMulti(%v): expected %q, got %v", c.layout.Name, c.err, err)
+		}
+	}
+
+	if _, err := LookupLayout("Samurai"); err != nil {
+		t.Errorf("unexpected error from LookupLayout: %v", err)
+	}
+	if _, err := LookupLayout("flower"); err == nil {
+		t.Errorf("expected error from LookupLayout for an unknown layout")
+	}
+}
+
+func TestMultiSharedCells(t *testing.T) {
+	m, _ := NewMulti(Classic, SamuraiLayout)
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(killerSolution))
+	m.SetGrid(2, g)
+	// The centre grid's corner boxes are the inner corner boxes of the
+	// others.
+	cases := []struct {
+		grid, row, col int
+		expect         byte
+	}{
+		{0, 6, 6, '9'},
+		{1, 8, 0, '6'},
+		{3, 0, 8, '2'},
+		{4, 2, 2, '8'},
+		{0, 5, 5, Unknown},
+	}
+	for _, c := range cases {
+		if glyph := m.Grid(c.grid).Get(c.row, c.col); glyph != c.expect {
+			t.Errorf("incorrect glyph in grid %v at R%vC%v: expected %q, got %q", c.grid+1, c.row+1, c.col+1, c.expect, glyph)
+		}
+	}
+	if n, expected := m.NumUnknowns(), len(m.Cells)-81; n != expected {
+		t.Errorf("incorrect result from NumUnknowns: expected %v, got %v", expected, n)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("unexpected error from Validate: %v", err)
+	}
+
+	// A glyph repeated in a shared box breaks every grid containing it, but
+	// the error refers to the first.
+	row := m.Grid(0)
+	row.Set(6, 0, row.Get(6, 6))
+	m.SetGrid(0, row)
+	err := m.Validate()
+	if e, ok := err.(*GridError); !ok || e.Grid != 0 {
+		t.Fatalf("expected GridError for grid 0 from Validate, got %v", err)
+	}
+	expect := "grid 1: invalid puzzle: duplicate '9' in row 7 at R7C1, R7C7"
+	if err.Error() != expect {
+		t.Errorf("incorrect result from Validate: expected %q, got %q", expect, err)
+	}
+}
+
+func TestParseMulti(t *testing.T) {
+	m, _ := NewMulti(Classic, TwodokuLayout)
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(killerSolution))
+	m.SetGrid(0, g)
+	s := m.String()
+	lines := strings.Split(s, "\n")
+	if expect := "9 3 6 7 1 4 8 5 2"; lines[0] != expect {
+		t.Errorf("incorrect first line from String: expected %q, got %q", expect, lines[0])
+	}
+	if expect := "3 8 2 6 4 5 9 7 1 _ _ _ _ _ _"; lines[6] != expect {
+		t.Errorf("incorrect seventh line from String: expected %q, got %q", expect, lines[6])
+	}
+	if expect := "            _ _ _ _ _ _ _ _ _"; lines[14] != expect {
+		t.Errorf("incorrect last line from String: expected %q, got %q", expect, lines[14])
+	}
+
+	parsed, err := ParseMulti([]byte(s), Classic, TwodokuLayout)
+	if err != nil {
+		t.Fatalf("unexpected error from ParseMulti: %v", err)
+	}
+	if !parsed.Equal(m) {
+		t.Errorf("incorrect result from ParseMulti: expected\n%v\ngot\n%v", m, parsed)
+	}
+
+	errors := []struct {
+		input string
+		line  int
+	}{
+		{strings.Join(lines[:14], "\n"), 0},
+		{strings.Replace(s, "            _", "1           _", 1), 10},
+		{strings.Replace(s, "9 3 6", "9 x 6", 1), 1},
+		{strings.Replace(s, "9 3 6 7 1 4 8 5 2", "9 3 6 7 1 4 8 5 2 1 1 1 1 1 1 1", 1), 1},
+	}
+	for _, c := range errors {
+		_, err := ParseMulti([]byte(c.input), Classic, TwodokuLayout)
+		if e, ok := err.(*ParseError); !ok || e.Line != c.line {
+			t.Errorf("expected ParseError on line %v from ParseMulti, got %v", c.line, err)
+		}
+	}
+}
+
+func TestGenerateMultiPuzzle(t *testing.T) {
+	for _, layout := range []Layout{TwodokuLayout, ButterflyLayout} {
+		puzzle, solution, err := GenerateMultiPuzzle(Classic, layout)
+		if err != nil {
+			t.Fatalf("unexpected error from GenerateMultiPuzzle(%v): %v", layout.Name, err)
+		}
+		if solution.NumUnknowns() != 0 {
+			t.Errorf("incomplete solution from GenerateMultiPuzzle(%v):\n%v", layout.Name, solution)
+		}
+		if err := solution.Validate(); err != nil {
+			t.Errorf("invalid solution from GenerateMultiPuzzle(%v): %v", layout.Name, err)
+		}
+		if n := puzzle.NumSolutions(); n != 1 {
+			t.Errorf("incorrect number of solutions for GenerateMultiPuzzle(%v): expected 1, got %v", layout.Name, n)
+		}
+		attempt := puzzle.Copy()
+		if remain := attempt.Solve(); remain != 0 || !attempt.Equal(solution) {
+			t.Errorf("incorrect solution for GenerateMultiPuzzle(%v): expected\n%v\ngot\n%v", layout.Name, solution, attempt)
+		}
+	}
+
+	// An empty samurai has many solutions.
+	m, _ := NewMulti(Classic, SamuraiLayout)
+	if n := m.NumSolutions(); n != 2 {
+		t.Errorf("incorrect result from NumSolutions: expected 2, got %v", n)
+	}
+}
+
+func TestRenderMulti(t *testing.T) {
+	m, _ := NewMulti(Classic, TwodokuLayout)
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(killerSolution))
+	m.SetGrid(1, g)
+	var buf bytes.Buffer
+	if err := m.RenderSVG(&buf, nil); err != nil {
+		t.Fatalf("unexpected error from RenderSVG: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, `<svg xmlns="http://www.w3.org/2000/svg" width="640" height="640"`) {
+		t.Errorf("incorrect dimensions from RenderSVG, got:\n%v", out)
+	}
+	if n := strings.Count(out, "<text"); n != 81 {
+		t.Errorf("incorrect number of glyphs from RenderSVG: expected 81, got %v", n)
+	}
+	if n, expected := strings.Count(out, "<line"), 2*2*(Size+1); n != expected {
+		t.Errorf("incorrect number of lines from RenderSVG: expected %v, got %v", expected, n)
+	}
+	// The second grid's first glyph sits in the centre of board row and
+	// column seven.
+	if !strings.Contains(out, `<text x="280" y="280"`) {
+		t.Errorf("expected a glyph at the centre of R7C7, got:\n%v", out)
+	}
+
+	img := m.RenderImage(nil)
+	if b := img.Bounds(); b.Dx() != 640 || b.Dy() != 640 {
+		t.Errorf("incorrect size from RenderImage: expected 640×640, got %v×%v", b.Dx(), b.Dy())
+	}
+}
@@ -0,0 +1,498 @@
+package sudoku
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OuterKind names a kind of clue written outside the grid.
+type OuterKind string
+
+const (
+	// SandwichClue gives the sum of the values lying between the smallest and
+	// largest values of a row or column: between the 1 and the 9 of a 9×9
+	// grid.
+	SandwichClue OuterKind = "sandwich"
+	// LittleKillerClue gives the sum of the values along a diagonal, which
+	// may repeat unless they share a unit.
+	LittleKillerClue OuterKind = "little-killer"
+	// SkyscraperClue gives the number of cells of a row or column visible
+	// from its end, taking each value as the height of a building, so that
+	// a cell is hidden by any larger value nearer the clue.
+	SkyscraperClue OuterKind = "skyscraper"
+)
+
+// OuterKinds lists the supported kinds of outside clue.
+var OuterKinds = []OuterKind{SandwichClue, LittleKillerClue, SkyscraperClue}
+
+// Side is a side of the grid, next to which an outside clue is written.
+type Side byte
+
+const (
+	TopSide    Side = 'T'
+	BottomSide Side = 'B'
+	LeftSide   Side = 'L'
+	RightSide  Side = 'R'
+)
+
+// OuterClue is a clue written outside the grid, about the cells of a row,
+// column or diagonal leading away from it.
+//
+// Outside clues should be made with NewOuterClue, which checks the position
+// and finds the cells it refers to.
+type OuterClue struct {
+	Kind OuterKind
+	// Side and Index give the position of the clue: the side of the grid,
+	// and the row or column, counted from zero, next to which it is written.
+	Side  Side
+	Index int
+	// Step gives the direction of a little killer diagonal: +1 if it leads
+	// towards higher numbered columns, from the top or bottom, or rows, from
+	// the left or right; and -1 if it leads towards lower numbered ones.  It
+	// is zero for other kinds.
+	Step  int
+	Value int
+	// path lists the cells of the clue, starting from the one nearest it,
+	// and cells lists them in grid order.
+	path, cells []int
+}
+
+// NewOuterClue returns an outside clue for a grid of the given shape.  It
+// returns an error if the kind is unknown, or the position or the value is
+// impossible.
+func NewOuterClue(shape Shape, kind OuterKind, side Side, index, step, value int) (OuterClue, error) {
+	size := shape.Size()
+	c := OuterClue{Kind: kind, Side: side, Index: index, Step: step, Value: value}
+	if index < 0 || index >= size {
+		return c, fmt.Errorf("no row or column %v on side %c", index+1, side)
+	}
+	if (kind == LittleKillerClue) != (step == 1 || step == -1) {
+		return c, fmt.Errorf("%v clue has step %v", kind, step)
+	}
+	// The clue's cells run from (row, col) in steps of (dr, dc).
+	var row, col, dr, dc int
+	switch side {
+	case TopSide:
+		row, col, dr, dc = 0, index, 1, step
+	case BottomSide:
+		row, col, dr, dc = size-1, index, -1, step
+	case LeftSide:
+		row, col, dr, dc = index, 0, step, 1
+	case RightSide:
+		row, col, dr, dc = index, size-1, step, -1
+	default:
+		return c, fmt.Errorf("unknown side %q", byte(side))
+	}
+	for ; row >= 0 && row < size && col >= 0 && col < size; row, col = row+dr, col+dc {
+		c.path = append(c.path, row*size+col)
+	}
+	c.cells = append([]int(nil), c.path...)
+	sort.Ints(c.cells)
+
+	switch kind {
+	case SandwichClue:
+		max := (size - 2) * (size + 1) / 2
+		if value < 0 || value > max {
+			return c, fmt.Errorf("sandwich clue %v is not between 0 and %v", value, max)
+		}
+	case LittleKillerClue:
+		if value < len(c.path) || value > len(c.path)*size {
+			return c, fmt.Errorf("little killer clue %v is impossible for %v cells", value, len(c.path))
+		}
+	case SkyscraperClue:
+		if value < 1 || value > size {
+			return c, fmt.Errorf("skyscraper clue %v is not between 1 and %v", value, size)
+		}
+	default:
+		return c, fmt.Errorf("unknown outside clue %q", kind)
+	}
+	return c, nil
+}
+
+// Position returns the position of the clue in the form read by
+// ParseOuterClues: the side, the row or column counted from one, and for
+// little killer clues the sign of the step; e.g. "T3" or "L1+".
+func (c OuterClue) Position() string {
+	s := string(c.Side) + strconv.Itoa(c.Index+1)
+	switch c.Step {
+	case 1:
+		s += "+"
+	case -1:
+		s += "-"
+	}
+	return s
+}
+
+// Cells returns the cells of the row, column or diagonal the clue refers to,
+// in grid order.
+func (c OuterClue) Cells() []int {
+	return c.cells
+}
+
+// values returns the value of each of the clue's cells in a grid, in order
+// from the clue, or zero for unknown cells.
+func (c OuterClue) values(g *Grid) []int {
+	values := make([]int, len(c.path))
+	for k, i := range c.path {
+		values[k] = g.Shape.glyphIndex(g.Cells[i]) + 1
+	}
+	return values
+}
+
+// Check returns a *ConstraintError if the known cells of the clue's row,
+// column or diagonal contradict it.
+func (c OuterClue) Check(g *Grid) error {
+	values := c.values(g)
+	var result int
+	var broken bool
+	switch c.Kind {
+	case SandwichClue:
+		result, broken = c.checkSandwich(values, g.Size())
+	case LittleKillerClue:
+		result, broken = c.checkSum(values)
+	case SkyscraperClue:
+		result, broken = c.checkSkyscraper(values, g.Size())
+	}
+	if !broken {
+		return nil
+	}
+	return &ConstraintError{
+		Constraint: c,
+		Cells:      sortedRefs(g, c.path...),
+		Reason:     fmt.Sprintf("%v clue %v is %v, expected %v", c.Kind, c.Position(), result, c.Value),
+	}
+}
+
+// checkSandwich returns the sum between the smallest and largest values,
+// once they and the cells between them are known, or the sum of the known
+// cells between them if that is already too large.
+func (c OuterClue) checkSandwich(values []int, size int) (sum int, broken bool) {
+	lo, hi := -1, -1
+	for k, v := range values {
+		switch v {
+		case 1:
+			lo = k
+		case size:
+			hi = k
+		}
+	}
+	if lo < 0 || hi < 0 {
+		return 0, false
+	}
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	complete := true
+	for _, v := range values[lo+1 : hi] {
+		sum += v
+		complete = complete && v > 0
+	}
+	return sum, sum > c.Value || (complete && sum != c.Value)
+}
+
+// checkSum returns the sum of the known values, which is broken if it is too
+// large, or if every value is known and it is wrong.
+func (c OuterClue) checkSum(values []int) (sum int, broken bool) {
+	complete := true
+	for _, v := range values {
+		sum += v
+		complete = complete && v > 0
+	}
+	return sum, sum > c.Value || (complete && sum != c.Value)
+}
+
+// checkSkyscraper returns the number of values visible among the known values
+// nearest the clue, which is broken if it is too many, or if it is too few
+// once the largest value is among them.
+func (c OuterClue) checkSkyscraper(values []int, size int) (seen int, broken bool) {
+	max := 0
+	for _, v := range values {
+		if v == 0 {
+			return seen, seen > c.Value
+		}
+		if v > max {
+			max = v
+			seen++
+		}
+		if v == size {
+			break
+		}
+	}
+	return seen, seen != c.Value
+}
+
+// Propagate removes the candidates of the clue's cells which cannot be part
+// of any arrangement of values agreeing with it.
+func (c OuterClue) Propagate(cand []uint64) bool {
+	cells := make([]uint64, len(c.path))
+	for k, i := range c.path {
+		cells[k] = cand[i]
+	}
+	var supported []uint64
+	switch c.Kind {
+	case SandwichClue:
+		supported = c.sandwichSupport(cells)
+	case LittleKillerClue:
+		supported = sumSupport(cells, c.Value)
+	case SkyscraperClue:
+		supported = c.skyscraperSupport(cells)
+	}
+	for k, i := range c.path {
+		cand[i] &= supported[k]
+		if cand[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sandwichSupport returns, for each cell of a row or column, the candidates
+// which appear in some placement of the smallest and largest values with
+// different values between them adding up to the clue.
+func (c OuterClue) sandwichSupport(cells []uint64) []uint64 {
+	n := len(cells)
+	lo, hi := uint64(1), uint64(1)<<uint(n-1)
+	supported := make([]uint64, n)
+	for i := range cells {
+		if cells[i]&lo == 0 {
+			continue
+		}
+		for j := range cells {
+			if j == i || cells[j]&hi == 0 {
+				continue
+			}
+			a, b := i, j
+			if a > b {
+				a, b = b, a
+			}
+			between := make([]uint64, b-a-1)
+			for k := range between {
+				between[k] = cells[a+1+k] &^ (lo | hi)
+			}
+			var sums []uint64
+			if len(between) == 0 {
+				if c.Value != 0 {
+					continue
+				}
+			} else if sums = distinctSumSupport(between, c.Value); sums[0] == 0 {
+				continue
+			}
+			outside := true
+			for k := range cells {
+				if (k < a || k > b) && cells[k]&^(lo|hi) == 0 {
+					outside = false
+				}
+			}
+			if !outside {
+				continue
+			}
+			supported[i] |= lo
+			supported[j] |= hi
+			for k := range cells {
+				switch {
+				case k > a && k < b:
+					supported[k] |= sums[k-a-1]
+				case k < a || k > b:
+					supported[k] |= cells[k] &^ (lo | hi)
+				}
+			}
+		}
+	}
+	return supported
+}
+
+// skyscraperBudget limits the arrangements of a row or column considered by
+// skyscraperSupport.
+const skyscraperBudget = 20000
+
+// skyscraperSupport returns, for each cell of a row or column in order from
+// the clue, the candidates which appear in some arrangement of different
+// values with the right number visible.
+//
+// Each cell may hold at most the values which leave room for enough larger
+// values after it.  Within that bound, the arrangements are searched
+// exhaustively, unless there are too many, in which case the bound alone is
+// applied.
+func (c OuterClue) skyscraperSupport(cells []uint64) []uint64 {
+	n := len(cells)
+	bounded := make([]uint64, n)
+	for k := range cells {
+		// At most k cells before it are visible, and after it only the
+		// larger values, so the cell k places from the clue can hold no
+		// more than n-Value+1+k.
+		limit := n - c.Value + 1 + k
+		if limit > n {
+			limit = n
+		}
+		bounded[k] = cells[k] & (uint64(1)<<uint(limit) - 1)
+	}
+
+	supported := make([]uint64, n)
+	budget := skyscraperBudget
+	var walk func(k int, used uint64, max, seen int) bool
+	walk = func(k int, used uint64, max, seen int) bool {
+		if budget--; budget < 0 {
+			return false
+		}
+		// The largest value is always visible, so until it is placed at
+		// least one more value is still to be seen.
+		if seen > c.Value || (max < n && seen == c.Value) || seen+n-k < c.Value {
+			return false
+		}
+		if max == n {
+			// Nothing further can be seen, so the remaining cells need only
+			// take the values left over.
+			if seen != c.Value || !matchable(bounded[k:], 0, ^used) {
+				return false
+			}
+			for j := k; j < n; j++ {
+				supported[j] |= bounded[j] &^ used
+			}
+			return true
+		}
+		found := false
+		for options := bounded[k] &^ used; options != 0; options &= options - 1 {
+			bit := options & -options
+			v := bits.TrailingZeros64(bit) + 1
+			m, s := max, seen
+			if v > max {
+				m, s = v, seen+1
+			}
+			if walk(k+1, used|bit, m, s) {
+				supported[k] |= bit
+				found = true
+			}
+		}
+		return found
+	}
+	walk(0, 0, 0, 0)
+	if budget < 0 {
+		return bounded
+	}
+	return supported
+}
+
+// OuterConstraints returns the DefaultConstraints for a shape followed by the
+// given outside clues.
+func OuterConstraints(shape Shape, clues []OuterClue) []Constraint {
+	return append(DefaultConstraints(shape), outerConstraints(clues)...)
+}
+
+// outerConstraints returns the given outside clues as constraints.
+func outerConstraints(clues []OuterClue) (result []Constraint) {
+	for _, c := range clues {
+		result = append(result, c)
+	}
+	return result
+}
+
+// OuterClues returns the outside clues among the grid's constraints.
+func (g *Grid) OuterClues() (clues []OuterClue) {
+	for _, c := range g.Constraints {
+		if clue, ok := c.(OuterClue); ok {
+			clues = append(clues, clue)
+		}
+	}
+	return
+}
+
+// ParseOuterClues reads outside clues for a grid of the given shape from a
+// slice of bytes.
+//
+// Each clue is given on a line of its own, as its kind, its position and its
+// value, separated by spaces.  The position is the side of the grid, 'T',
+// 'B', 'L' or 'R', followed by the row or column next to which the clue is
+// written, counted from one.  For little killer clues, it ends with '+' or
+// '-' for a diagonal leading towards higher or lower numbered rows or
+// columns.  For example:
+//
+//	sandwich T3 15
+//	skyscraper L1 4
+//	little-killer B2+ 23
+//
+// Blank lines and comment lines beginning with '#' are ignored.
+func ParseOuterClues(input []byte, shape Shape) (clues []OuterClue, err error) {
+	for n, line := range bytes.Split(input, []byte("\n")) {
+		if skip(line) {
+			continue
+		}
+		fields := strings.Fields(string(line))
+		if len(fields) != 3 {
+			return nil, &ParseError{Line: n + 1, Reason: "expected a kind, a position and a value"}
+		}
+		pos := strings.ToUpper(fields[1])
+		if len(pos) < 2 {
+			return nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("invalid position %q", fields[1])}
+		}
+		step := 0
+		switch pos[len(pos)-1] {
+		case '+':
+			step = 1
+		case '-':
+			step = -1
+		}
+		if step != 0 {
+			pos = pos[:len(pos)-1]
+		}
+		index, err := strconv.Atoi(pos[1:])
+		if err != nil {
+			return nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("invalid position %q", fields[1])}
+		}
+		value, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, &ParseError{Line: n + 1, Reason: fmt.Sprintf("invalid value %q", fields[2])}
+		}
+		c, err := NewOuterClue(shape, OuterKind(strings.ToLower(fields[0])), Side(pos[0]), index-1, step, value)
+		if err != nil {
+			return nil, &ParseError{Line: n + 1, Reason: err.Error()}
+		}
+		clues = append(clues, c)
+	}
+	return
+}
+
+// FormatOuterClues returns outside clues in the format consumed by
+// ParseOuterClues.
+func FormatOuterClues(clues []OuterClue) string {
+	var buf bytes.Buffer
+	for _, c := range clues {
+		fmt.Fprintf(&buf, "%v %v %v\n", c.Kind, c.Position(), c.Value)
+	}
+	return buf.String()
+}
+
+// ParseOuterPuzzle reads a puzzle with outside clues from a slice of bytes in
+// the format written by Grid.OuterPuzzleString: the grid's givens in either
+// the grid or the line format, then a blank line, then its clues in the
+// format consumed by ParseOuterClues.  The shape is taken from the size of
+// the grid, as in ParseGrid, and the grid's constraints are the
+// OuterConstraints for its clues.
+func ParseOuterPuzzle(input []byte) (*Grid, error) {
+	return ParsePuzzle(input, OuterSection)
+}
+
+// OuterPuzzleString returns a representation of the grid and its outside
+// clues in the format consumed by ParseOuterPuzzle.
+func (g *Grid) OuterPuzzleString() string {
+	return g.PuzzleString(OuterSection)
+}
+
+// OuterSection is the Section for outside clues, in the format read by
+// ParseOuterClues.
+var OuterSection = Section{
+	Name: "clues",
+	Parse: func(input []byte, shape Shape) ([]Constraint, error) {
+		clues, err := ParseOuterClues(input, shape)
+		if err != nil {
+			return nil, err
+		}
+		return outerConstraints(clues), nil
+	},
+	Format: func(g *Grid) string {
+		return FormatOuterClues(g.OuterClues())
+	},
+}
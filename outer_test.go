@@ -0,0 +1,220 @@
+package sudoku
+
+import (
+	"strings"
+	"testing"
+)
+
+// outerFixture holds outside clues which agree with killerSolution.
+const outerFixture = `sandwich L1 16
+sandwich L2 30
+sandwich T5 35
+sandwich T8 3
+skyscraper L3 5
+skyscraper R8 4
+skyscraper B1 5
+skyscraper T5 4
+little-killer T1+ 48
+little-killer T4- 27
+little-killer T9+ 2
+`
+
+func TestNewOuterClue(t *testing.T) {
+	cases := []struct {
+		kind             OuterKind
+		side             Side
+		index, step, val int
+		cells            []int
+		err              string
+	}{
+		{SandwichClue, TopSide, 2, 0, 10, []int{2, 11, 20, 29, 38, 47, 56, 65, 74}, ""},
+		{SkyscraperClue, RightSide, 0, 0, 3, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, ""},
+		{LittleKillerClue, BottomSide, 7, 1, 10, []int{71, 79}, ""},
+		{LittleKillerClue, LeftSide, 6, -1, 30, []int{6, 14, 22, 30, 38, 46, 54}, ""},
+		{SandwichClue, TopSide, 9, 0, 10, nil, "no row or column 10 on side T"},
+		{SandwichClue, TopSide, 0, 1, 10, nil, "sandwich clue has step 1"},
+		{LittleKillerClue, TopSide, 0, 0, 10, nil, "little-killer clue has step 0"},
+		{SandwichClue, Side('X'), 0, 0, 10, nil, "unknown side 'X'"},
+		{SandwichClue, TopSide, 0, 0, 36, nil, "sandwich clue 36 is not between 0 and 35"},
+		{SkyscraperClue, TopSide, 0, 0, 0, nil, "skyscraper clue 0 is not between 1 and 9"},
+		{LittleKillerClue, BottomSide, 8, 1, 10, nil, "little killer clue 10 is impossible for 1 cells"},
+		{OuterKind("frame"), TopSide, 0, 0, 10, nil, `unknown outside clue "frame"`},
+	}
+	for _, c := range cases {
+		clue, err := NewOuterClue(Classic, c.kind, c.side, c.index, c.step, c.val)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("unexpected error from NewOuterClue(%v, %c%v): %v", c.kind, c.side, c.index, err)
+		case c.err != "" && (err == nil || err.Error() != c.err):
+			t.Errorf("incorrect error from NewOuterClue(%v, %c%v): expected %q, got %v", c.kind, c.side, c.index, c.err, err)
+		case c.err == "" && !equalInts(clue.Cells(), c.cells):
+			t.Errorf("incorrect cells for %v %v: expected %v, got %v", c.kind, clue.Position(), c.cells, clue.Cells())
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOuterClueCheck(t *testing.T) {
+	clues, err := ParseOuterClues([]byte(outerFixture), Classic)
+	if err != nil {
+		t.Fatalf("unexpected error from ParseOuterClues: %v", err)
+	}
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(killerSolution))
+	for _, c := range clues {
+		if err := c.Check(g); err != nil {
+			t.Errorf("unexpected error from Check for %v %v: %v", c.Kind, c.Position(), err)
+		}
+	}
+
+	cases := []struct {
+		clue   int
+		cells  map[int]byte
+		expect string
+	}{
+		// The sandwich clue L1 of 16 is broken by a complete sandwich of 9,
+		// or by an incomplete one which is already too large.
+		{0, map[int]byte{0: '9', 1: '8', 2: '7', 4: '1'}, ""},
+		{0, map[int]byte{0: '9', 1: '3', 2: '6', 3: '1'}, "sandwich clue L1 is 9, expected 16"},
+		{0, map[int]byte{0: '9', 1: '8', 2: '4', 3: '5', 5: '1'}, "sandwich clue L1 is 17, expected 16"},
+		// The skyscraper clue L3 of 5 is broken by too many visible, or too
+		// few once the 9 is seen.
+		{4, map[int]byte{18: '2', 19: '4', 20: '7', 21: '5', 22: '8', 23: '9'}, ""},
+		{4, map[int]byte{18: '1', 19: '2', 20: '3', 21: '4', 22: '5', 23: '6'}, "skyscraper clue L3 is 6, expected 5"},
+		{4, map[int]byte{18: '8', 19: '9'}, "skyscraper clue L3 is 2, expected 5"},
+		// The little killer clue T1+ of 48 runs down the main diagonal.
+		{8, map[int]byte{0: '9', 10: '5'}, ""},
+		{8, map[int]byte{0: '9', 10: '9', 20: '9', 30: '9', 40: '9', 50: '9'}, "little-killer clue T1+ is 54, expected 48"},
+	}
+	for _, c := range cases {
+		g.Clear()
+		for i, glyph := range c.cells {
+			g.Cells[i] = glyph
+		}
+		err := clues[c.clue].Check(g)
+		switch {
+		case c.expect == "" && err != nil:
+			t.Errorf("unexpected error from Check for %v: %v", clues[c.clue].Position(), err)
+		case c.expect != "" && (err == nil || !strings.HasPrefix(err.Error(), "invalid puzzle: "+c.expect+" at ")):
+			t.Errorf("incorrect result from Check: expected %q, got %v", c.expect, err)
+		}
+	}
+}
+
+func TestOuterCluePropagate(t *testing.T) {
+	full := func() []uint64 {
+		cand := make([]uint64, 81)
+		for i := range cand {
+			cand[i] = 0x1ff
+		}
+		return cand
+	}
+
+	// A sandwich of 35 needs the 1 and 9 at the ends of the row.
+	sandwich, _ := NewOuterClue(Classic, SandwichClue, LeftSide, 0, 0, 35)
+	cand := full()
+	sandwich.Propagate(cand)
+	if ends := uint64(1 | 1<<8); cand[0] != ends || cand[8] != ends {
+		t.Errorf("incorrect sandwich candidates: expected %09b, got %09b and %09b", ends, cand[0], cand[8])
+	}
+	if middle := uint64(0x1ff &^ (1 | 1<<8)); cand[4] != middle {
+		t.Errorf("incorrect sandwich candidates: expected %09b, got %09b", middle, cand[4])
+	}
+	// A sandwich of 0 puts the 1 and 9 next to each other.
+	sandwich, _ = NewOuterClue(Classic, SandwichClue, TopSide, 0, 0, 0)
+	cand = full()
+	cand[0] = 1
+	sandwich.Propagate(cand)
+	if cand[9] != 1<<8 {
+		t.Errorf("incorrect sandwich candidates for R2C1: expected %09b, got %09b", 1<<8, cand[9])
+	}
+
+	// A skyscraper clue of 1 puts the 9 nearest the clue, and 9 puts the
+	// values in order.
+	sky, _ := NewOuterClue(Classic, SkyscraperClue, RightSide, 2, 0, 1)
+	cand = full()
+	sky.Propagate(cand)
+	if cand[26] != 1<<8 {
+		t.Errorf("incorrect skyscraper candidates for R3C9: expected %09b, got %09b", 1<<8, cand[26])
+	}
+	sky, _ = NewOuterClue(Classic, SkyscraperClue, BottomSide, 0, 0, 9)
+	cand = full()
+	sky.Propagate(cand)
+	for k := 0; k < Size; k++ {
+		if i := 72 - k*Size; cand[i] != 1<<uint(k) {
+			t.Errorf("incorrect skyscraper candidates for cell %v: expected %09b, got %09b", i, 1<<uint(k), cand[i])
+		}
+	}
+	// A skyscraper clue of 3 with the 9 in the third cell needs the first
+	// two cells in order.
+	sky, _ = NewOuterClue(Classic, SkyscraperClue, LeftSide, 0, 0, 3)
+	cand = full()
+	cand[2] = 1 << 8
+	sky.Propagate(cand)
+	if cand[0] != 0x7f || cand[1] != 0xfe {
+		t.Errorf("incorrect skyscraper candidates: expected %09b and %09b, got %09b and %09b", 0x7f, 0xfe, cand[0], cand[1])
+	}
+
+	// A little killer of two cells adding up to 17 holds 8 and 9.
+	lk, _ := NewOuterClue(Classic, LittleKillerClue, TopSide, 7, 1, 17)
+	cand = full()
+	if !lk.Propagate(cand) || cand[7] != 1<<7|1<<8 || cand[17] != 1<<7|1<<8 {
+		t.Errorf("incorrect little killer candidates: got %09b and %09b", cand[7], cand[17])
+	}
+}
+
+func TestParseOuterPuzzle(t *testing.T) {
+	clues, _ := ParseOuterClues([]byte(outerFixture), Classic)
+	if result := FormatOuterClues(clues); result != outerFixture {
+		t.Errorf("incorrect result from FormatOuterClues: expected %q, got %q", outerFixture, result)
+	}
+
+	solution := NewGrid(Classic)
+	solution.ReadLine([]byte(killerSolution))
+	solution.Constraints = OuterConstraints(Classic, clues)
+	puzzle := solution.ApplyMask(solution.MinimalMask())
+	if n := puzzle.NumSolutions(); n != 1 {
+		t.Fatalf("incorrect result from NumSolutions: expected 1, got %v", n)
+	}
+	g, err := ParseOuterPuzzle([]byte(puzzle.OuterPuzzleString()))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseOuterPuzzle: %v", err)
+	}
+	if !g.Equal(puzzle) || len(g.OuterClues()) != len(clues) {
+		t.Errorf("incorrect result from ParseOuterPuzzle: expected\n%v\ngot\n%v", puzzle.OuterPuzzleString(), g.OuterPuzzleString())
+	}
+	g.Solve()
+	if result := g.Line(); result != killerSolution {
+		t.Errorf("incorrect solution: expected %v, got %v", killerSolution, result)
+	}
+
+	errors := []string{
+		"sandwich L1\n",
+		"sandwich X1 10\n",
+		"sandwich L 10\n",
+		"sandwich L1 ten\n",
+		"little-killer T1 10\n",
+		"sandwich + 10\n",
+	}
+	for _, input := range errors {
+		_, err := ParseOuterClues([]byte("# clues\n"+input), Classic)
+		if e, ok := err.(*ParseError); !ok || e.Line != 2 {
+			t.Errorf("expected ParseError on line 2 from ParseOuterClues(%q), got %v", input, err)
+		}
+	}
+	_, err = ParseOuterPuzzle([]byte(strings.Repeat(".", 81) + "\n\nsandwich T10 5\n"))
+	if e, ok := err.(*ParseError); !ok || e.Line != 3 {
+		t.Errorf("expected ParseError on line 3 from ParseOuterPuzzle, got %v", err)
+	}
+}
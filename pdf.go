@@ -0,0 +1,219 @@
+package sudoku
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pdfFont selects one of the standard PDF fonts, which every PDF reader
+// provides, so that no font data needs to be embedded.
+type pdfFont int
+
+const (
+	pdfRegular pdfFont = iota
+	pdfBold
+)
+
+// Widths of the printable ASCII characters, from ' ' to '~', in thousandths
+// of the font size, taken from the Adobe font metrics for Helvetica and
+// Helvetica-Bold.
+var pdfWidths = [2][95]int{
+	{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// pdfText returns the text with every character outside of printable ASCII
+// replaced by '?'.
+func pdfText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// textWidth returns the width of the text, in points, when set in the given
+// font and size.
+func textWidth(s string, size float64, font pdfFont) float64 {
+	total := 0
+	for _, b := range []byte(pdfText(s)) {
+		total += pdfWidths[font][b-' ']
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfNum formats a number for a PDF content stream.
+func pdfNum(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// pdfColour formats the red, green and blue components of a colour for a PDF
+// content stream.  PDF colours have no alpha channel, so translucent colours
+// are drawn as though they had been laid over white.
+func pdfColour(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	a := float64(n.A) / 0xff
+	comp := func(v uint8) string {
+		return pdfNum(1 - a + a*float64(v)/0xff)
+	}
+	return comp(n.R) + " " + comp(n.G) + " " + comp(n.B)
+}
+
+// pdfPage is a page of a PDF document under construction.  Its drawing
+// methods take coordinates in points from the top left corner of the page.
+type pdfPage struct {
+	height  float64
+	content bytes.Buffer
+}
+
+// fillRect draws a filled rectangle.
+func (p *pdfPage) fillRect(x, y, w, h float64, c color.Color) {
+	fmt.Fprintf(&p.content, "%v rg %v %v %v %v re f\n",
+		pdfColour(c), pdfNum(x), pdfNum(p.height-y-h), pdfNum(w), pdfNum(h))
+}
+
+// line draws a straight line.
+func (p *pdfPage) line(x1, y1, x2, y2, width float64, c color.Color, dashed bool) {
+	if dashed {
+		fmt.Fprintf(&p.content, "[%v %v] 0 d\n", pdfNum(width*3), pdfNum(width*2))
+	}
+	fmt.Fprintf(&p.content, "%v RG %v w %v %v m %v %v l S\n",
+		pdfColour(c), pdfNum(width), pdfNum(x1), pdfNum(p.height-y1), pdfNum(x2), pdfNum(p.height-y2))
+	if dashed {
+		p.content.WriteString("[] 0 d\n")
+	}
+}
+
+// circle draws a circle, filled or outlined or both, from four Bézier
+// curves.  Either of 'fill' and 'stroke' may be nil, to leave it out.
+func (p *pdfPage) circle(x, y, r float64, fill, stroke color.Color, width float64) {
+	y = p.height - y
+	k := r * 0.5523
+	fmt.Fprintf(&p.content, "%v %v m %v %v %v %v %v %v c %v %v %v %v %v %v c %v %v %v %v %v %v c %v %v %v %v %v %v c\n",
+		pdfNum(x+r), pdfNum(y),
+		pdfNum(x+r), pdfNum(y+k), pdfNum(x+k), pdfNum(y+r), pdfNum(x), pdfNum(y+r),
+		pdfNum(x-k), pdfNum(y+r), pdfNum(x-r), pdfNum(y+k), pdfNum(x-r), pdfNum(y),
+		pdfNum(x-r), pdfNum(y-k), pdfNum(x-k), pdfNum(y-r), pdfNum(x), pdfNum(y-r),
+		pdfNum(x+k), pdfNum(y-r), pdfNum(x+r), pdfNum(y-k), pdfNum(x+r), pdfNum(y))
+	switch {
+	case fill != nil && stroke != nil:
+		fmt.Fprintf(&p.content, "%v rg %v RG %v w B\n", pdfColour(fill), pdfColour(stroke), pdfNum(width))
+	case fill != nil:
+		fmt.Fprintf(&p.content, "%v rg f\n", pdfColour(fill))
+	case stroke != nil:
+		fmt.Fprintf(&p.content, "%v RG %v w S\n", pdfColour(stroke), pdfNum(width))
+	default:
+		p.content.WriteString("n\n")
+	}
+}
+
+// text draws a string with its baseline starting at (x, y).
+func (p *pdfPage) text(x, y, size float64, font pdfFont, s string, c color.Color) {
+	s = pdfText(s)
+	s = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+	fmt.Fprintf(&p.content, "BT %v rg /F%v %v Tf %v %v Td (%v) Tj ET\n",
+		pdfColour(c), int(font)+1, pdfNum(size), pdfNum(x), pdfNum(p.height-y), s)
+}
+
+// pdfDocument is a PDF document under construction, made up of pages of
+// equal size.
+type pdfDocument struct {
+	width, height float64
+	pages         []*pdfPage
+}
+
+// newPage adds a blank page to the end of the document.
+func (d *pdfDocument) newPage() *pdfPage {
+	p := &pdfPage{height: d.height}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// writeTo writes the document to 'w'.
+func (d *pdfDocument) writeTo(w io.Writer) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(format string, args ...interface{}) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%v 0 obj\n", len(offsets))
+		fmt.Fprintf(&buf, format, args...)
+		buf.WriteString("\nendobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%v 0 R", 5+2*i))
+	}
+	object("<< /Type /Pages /Kids [%v] /Count %v >>", strings.Join(kids, " "), len(d.pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, p := range d.pages {
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %v %v] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %v 0 R >>",
+			pdfNum(d.width), pdfNum(d.height), 6+2*i)
+		object("<< /Length %v >>\nstream\n%vendstream", p.content.Len(), p.content.String())
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %v\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %v /Root 1 0 R >>\nstartxref\n%v\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// drawPDF draws the layout onto a page, with its top left corner at (x, y)
+// and scaled by 'scale'.
+func (l *layout) drawPDF(p *pdfPage, x, y, scale float64) {
+	shapes := func(rects []layoutRect, circles []layoutCircle) {
+		for _, r := range rects {
+			p.fillRect(x+r.x*scale, y+r.y*scale, r.w*scale, r.h*scale, r.fill)
+		}
+		for _, c := range circles {
+			p.circle(x+c.x*scale, y+c.y*scale, c.r*scale, c.fill, c.stroke, c.width*scale)
+		}
+	}
+	shapes(l.rects, l.circles)
+	for _, ln := range l.lines {
+		p.line(x+ln.x1*scale, y+ln.y1*scale, x+ln.x2*scale, y+ln.y2*scale, ln.width*scale, ln.stroke, ln.dashed)
+	}
+	shapes(l.markRects, l.markCircles)
+	for _, t := range l.texts {
+		font := pdfRegular
+		if t.bold {
+			font = pdfBold
+		}
+		size := t.size * scale
+		s := string(t.glyph)
+		// Digits are about 0.72 of the font size high, so this puts their
+		// centre on the centre of the text.
+		p.text(x+t.x*scale-textWidth(s, size, font)/2, y+t.y*scale+size*0.36, size, font, s, t.fill)
+	}
+}
@@ -0,0 +1,80 @@
+package sudoku
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTextWidth(t *testing.T) {
+	cases := []struct {
+		text   string
+		size   float64
+		font   pdfFont
+		expect float64
+	}{
+		{"", 10, pdfRegular, 0},
+		{"1", 10, pdfRegular, 5.56},
+		{"Puzzle", 10, pdfRegular, 30.01},
+		{"Puzzle", 10, pdfBold, 31.12},
+		{"é", 10, pdfRegular, 5.56},
+	}
+	for _, c := range cases {
+		result := textWidth(c.text, c.size, c.font)
+		if pdfNum(result) != pdfNum(c.expect) {
+			t.Errorf("incorrect result from textWidth(%q): expected %v, got %v", c.text, c.expect, result)
+		}
+	}
+}
+
+func TestPDFDocument(t *testing.T) {
+	doc := &pdfDocument{width: 200, height: 100}
+	p := doc.newPage()
+	p.fillRect(10, 10, 20, 30, color.RGBA{0xff, 0x00, 0x00, 0xff})
+	p.line(0, 0, 200, 100, 2, color.RGBA{0x00, 0x00, 0x00, 0x80}, true)
+	p.text(5, 50, 12, pdfBold, `a (b) \c`, color.Black)
+	p.circle(100, 40, 10, nil, color.Black, 1)
+	doc.newPage()
+
+	var buf bytes.Buffer
+	if err := doc.writeTo(&buf); err != nil {
+		t.Fatalf("unexpected error from writeTo: %v", err)
+	}
+	out := buf.String()
+	expect := []string{
+		"1 0 0 rg 10 60 20 30 re f\n",
+		"[6 4] 0 d\n0.5 0.5 0.5 RG 2 w 0 100 m 200 0 l S\n[] 0 d\n",
+		`BT 0 0 0 rg /F2 12 Tf 5 50 Td (a \(b\) \\c) Tj ET`,
+		"110 60 m 110 ",
+		"0 0 0 RG 1 w S\n",
+		"/Count 2",
+		"/MediaBox [0 0 200 100]",
+	}
+	for _, e := range expect {
+		if !strings.Contains(out, e) {
+			t.Errorf("incorrect result from writeTo: expected output to contain %q, got:\n%v", e, out)
+		}
+	}
+
+	// Every entry in the cross-reference table must point at its object.
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatalf("incorrect result from writeTo: no startxref in:\n%v", out)
+	}
+	xref, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(out[xref:], "xref\n0 9\n") {
+		t.Fatalf("incorrect startxref: expected xref table, got %q", out[xref:xref+10])
+	}
+	entries := strings.Split(out[xref:], "\n")[3:11]
+	for i, entry := range entries {
+		off, _ := strconv.Atoi(entry[:10])
+		prefix := fmt.Sprintf("%v 0 obj\n", i+1)
+		if !strings.HasPrefix(out[off:], prefix) {
+			t.Errorf("incorrect xref entry %v: expected %q, got %q", i+1, prefix, out[off:off+len(prefix)])
+		}
+	}
+}
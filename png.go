@@ -0,0 +1,171 @@
+package sudoku
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// blend paints a colour over one pixel of an image, where 'cover' is the
+// fraction of the pixel covered, from 0 to 1.
+func blend(img *image.RGBA, x, y int, c color.NRGBA, cover float64) {
+	if !(image.Point{x, y}.In(img.Rect)) || cover <= 0 {
+		return
+	}
+	a := math.Min(cover, 1) * float64(c.A) / 0xff
+	i := img.PixOffset(x, y)
+	pix := img.Pix[i : i+4]
+	for k, v := range []uint8{c.R, c.G, c.B} {
+		pix[k] = uint8(float64(pix[k])*(1-a) + float64(v)*a + 0.5)
+	}
+	pix[3] = uint8(float64(pix[3])*(1-a) + 0xff*a + 0.5)
+}
+
+// overlap returns the length of the overlap between the ranges [a0, a1) and
+// [b0, b1).
+func overlap(a0, a1, b0, b1 float64) float64 {
+	return math.Max(0, math.Min(a1, b1)-math.Max(a0, b0))
+}
+
+// fillRect paints a rectangle, anti-aliasing its edges.
+func fillRect(img *image.RGBA, x, y, w, h float64, c color.Color) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	for py := int(math.Floor(y)); float64(py) < y+h; py++ {
+		cy := overlap(y, y+h, float64(py), float64(py+1))
+		for px := int(math.Floor(x)); float64(px) < x+w; px++ {
+			blend(img, px, py, n, cy*overlap(x, x+w, float64(px), float64(px+1)))
+		}
+	}
+}
+
+// drawLine paints a straight line with square-cut ends, anti-aliasing its
+// edges.  Dashed lines use the same pattern as RenderSVG.
+func drawLine(img *image.RGBA, ln layoutLine) {
+	n := color.NRGBAModel.Convert(ln.stroke).(color.NRGBA)
+	half := ln.width / 2
+	dx, dy := ln.x2-ln.x1, ln.y2-ln.y1
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	ux, uy := dx/length, dy/length
+	x0, x1 := math.Min(ln.x1, ln.x2)-half-1, math.Max(ln.x1, ln.x2)+half+1
+	y0, y1 := math.Min(ln.y1, ln.y2)-half-1, math.Max(ln.y1, ln.y2)+half+1
+	for py := int(y0); float64(py) <= y1; py++ {
+		for px := int(x0); float64(px) <= x1; px++ {
+			// Measure the centre of the pixel along and across the line.
+			cx, cy := float64(px)+0.5-ln.x1, float64(py)+0.5-ln.y1
+			along := cx*ux + cy*uy
+			across := math.Abs(cy*ux - cx*uy)
+			if ln.dashed && math.Mod(along, ln.width*5) >= ln.width*3 {
+				continue
+			}
+			cover := math.Min(half+0.5-across, math.Min(along+0.5, length-along+0.5))
+			blend(img, px, py, n, cover)
+		}
+	}
+}
+
+// drawCircle paints a circle, filled or outlined or both, anti-aliasing its
+// edges.
+func drawCircle(img *image.RGBA, c layoutCircle) {
+	var fill, stroke color.NRGBA
+	if c.fill != nil {
+		fill = color.NRGBAModel.Convert(c.fill).(color.NRGBA)
+	}
+	if c.stroke != nil {
+		stroke = color.NRGBAModel.Convert(c.stroke).(color.NRGBA)
+	}
+	half := c.width / 2
+	outer := c.r + half + 1
+	for py := int(c.y - outer); float64(py) <= c.y+outer; py++ {
+		for px := int(c.x - outer); float64(px) <= c.x+outer; px++ {
+			d := math.Hypot(float64(px)+0.5-c.x, float64(py)+0.5-c.y)
+			if c.fill != nil {
+				blend(img, px, py, fill, c.r+0.5-d)
+			}
+			if c.stroke != nil {
+				blend(img, px, py, stroke, half+0.5-math.Abs(d-c.r))
+			}
+		}
+	}
+}
+
+// drawGlyph paints a glyph from the bitmap font, scaled to the size of the
+// text and centred on its position.  Bold glyphs are drawn with wider
+// strokes.  Glyphs missing from the font are drawn as '?'.
+func drawGlyph(img *image.RGBA, t layoutText) {
+	bitmap, ok := bitmapFont[t.glyph]
+	if !ok {
+		bitmap = bitmapFont['?']
+	}
+	// Match the height of the digits in the vector renderers.
+	h := t.size * 0.72
+	w := h * fontWidth / fontHeight
+	px, py := w/fontWidth, h/fontHeight
+	left, top := t.x-w/2, t.y-h/2
+	grow := 0.0
+	if t.bold {
+		grow = px * 0.3
+	}
+	for r, row := range bitmap {
+		for c := 0; c < fontWidth; c++ {
+			if row[c] == '#' {
+				fillRect(img, left+float64(c)*px-grow, top+float64(r)*py, px+grow*2, py, t.fill)
+			}
+		}
+	}
+}
+
+// drawImage paints the layout onto a new image.
+func (l *layout) drawImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(l.width)), int(math.Ceil(l.height))))
+	for _, r := range l.rects {
+		fillRect(img, r.x, r.y, r.w, r.h, r.fill)
+	}
+	for _, c := range l.circles {
+		drawCircle(img, c)
+	}
+	for _, ln := range l.lines {
+		drawLine(img, ln)
+	}
+	for _, r := range l.markRects {
+		fillRect(img, r.x, r.y, r.w, r.h, r.fill)
+	}
+	for _, c := range l.markCircles {
+		drawCircle(img, c)
+	}
+	for _, t := range l.texts {
+		drawGlyph(img, t)
+	}
+	return img
+}
+
+// RenderImage draws the puzzle as a raster image.  The image is laid out in
+// the same way as by RenderSVG, at one pixel per unit of RenderOptions.
+//
+// See RenderOptions for the configuration of the drawing.  A nil 'opts' is
+// equivalent to the zero RenderOptions.
+func (puz *Puzzle) RenderImage(opts *RenderOptions) image.Image {
+	return newLayout(puz, opts).drawImage()
+}
+
+// RenderPNG draws the puzzle as a raster image, and writes it to 'w' in PNG
+// format.
+func (puz *Puzzle) RenderPNG(w io.Writer, opts *RenderOptions) error {
+	return png.Encode(w, puz.RenderImage(opts))
+}
+
+// RenderImage draws the board of a multi-grid puzzle as a raster image, laid
+// out in the same way as by Multi.RenderSVG.
+func (m *Multi) RenderImage(opts *RenderOptions) image.Image {
+	return newMultiLayout(m, opts).drawImage()
+}
+
+// RenderPNG draws the board of a multi-grid puzzle as a raster image, and
+// writes it to 'w' in PNG format.
+func (m *Multi) RenderPNG(w io.Writer, opts *RenderOptions) error {
+	return png.Encode(w, m.RenderImage(opts))
+}
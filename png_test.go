@@ -0,0 +1,75 @@
+package sudoku
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestBitmapFont(t *testing.T) {
+	for _, g := range Glyphs {
+		if _, ok := bitmapFont[g]; !ok {
+			t.Errorf("missing glyph %q from bitmapFont", g)
+		}
+	}
+	for g, bitmap := range bitmapFont {
+		for _, row := range bitmap {
+			if len(row) != fontWidth {
+				t.Errorf("incorrect width of glyph %q: expected %v, got %v", g, fontWidth, len(row))
+			}
+		}
+	}
+}
+
+func TestRenderImage(t *testing.T) {
+	var puz Puzzle
+	for i := range puz {
+		puz[i] = Unknown
+	}
+	puz[0] = '8'
+	highlight := color.RGBA{0xff, 0xee, 0x88, 0xff}
+	img := puz.RenderImage(&RenderOptions{
+		CellSize:   40,
+		Highlights: []Highlight{{CellRef{8, 8}, highlight}},
+	})
+
+	if b := img.Bounds(); b != image.Rect(0, 0, 400, 400) {
+		t.Errorf("incorrect bounds from RenderImage: expected %v, got %v", image.Rect(0, 0, 400, 400), b)
+	}
+	cases := []struct {
+		x, y   int
+		expect color.Color
+	}{
+		// Margin.
+		{5, 5, defaultBackground},
+		// Empty cell.
+		{90, 90, defaultBackground},
+		// Highlighted cell.
+		{360, 360, highlight},
+		// Thick line around the grid.
+		{20, 200, defaultGrid},
+		// The centre of the '8' in R1C1.
+		{40, 40, defaultGiven},
+	}
+	for _, c := range cases {
+		r1, g1, b1, a1 := img.At(c.x, c.y).RGBA()
+		r2, g2, b2, a2 := c.expect.RGBA()
+		if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+			t.Errorf("incorrect pixel at (%v, %v) from RenderImage: expected %v, got %v", c.x, c.y, c.expect, img.At(c.x, c.y))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := puz.RenderPNG(&buf, nil); err != nil {
+		t.Fatalf("unexpected error from RenderPNG: %v", err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error decoding RenderPNG output: %v", err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Errorf("incorrect bounds from RenderPNG: expected %v, got %v", img.Bounds(), decoded.Bounds())
+	}
+}
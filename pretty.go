@@ -0,0 +1,214 @@
+package sudoku
+
+import (
+	"strings"
+)
+
+// PrettyOptions configures the output of Puzzle.Pretty.  The zero value is
+// ready to use, and shows the puzzle alone without colour.
+type PrettyOptions struct {
+	// Colour enables ANSI escape sequences which show givens in bold, solved
+	// cells in blue, candidates dimmed, and any cells in conflict in red.
+	Colour bool
+	// Givens marks the cells which hold clues.  Other known cells are shown
+	// as solved.  If nil, every known cell is a given.
+	Givens *Mask
+	// Candidates, if not nil, are shown in each unknown cell, arranged like
+	// the keys of a phone keypad.
+	Candidates *CandidateGrid
+}
+
+// ANSI escape parameters for each kind of cell.
+const (
+	ansiGiven     = "1"
+	ansiSolved    = "34"
+	ansiCandidate = "2"
+	ansiConflict  = "1;31"
+)
+
+// boxStyle holds the box-drawing characters for one horizontal border of a
+// grid: the left and right ends, the line itself, and the joins with thin
+// and thick vertical borders.
+type boxStyle struct {
+	left, fill, thin, thick, right string
+}
+
+var (
+	// Borders for grids which separate only the subgrids.
+	plainTop    = boxStyle{"┌", "─", "─", "┬", "┐"}
+	plainMiddle = boxStyle{"├", "─", "─", "┼", "┤"}
+	plainBottom = boxStyle{"└", "─", "─", "┴", "┘"}
+	// Borders for grids which separate every cell, with heavy lines around
+	// the subgrids.
+	cellTop    = boxStyle{"┏", "━", "┯", "┳", "┓"}
+	cellThin   = boxStyle{"┠", "─", "┼", "╂", "┨"}
+	cellThick  = boxStyle{"┣", "━", "┿", "╋", "┫"}
+	cellBottom = boxStyle{"┗", "━", "┷", "┻", "┛"}
+)
+
+// border returns a horizontal border line, for cells of 'width' characters.
+// If 'inner' is set, the border has a join for every cell; otherwise cells
+// are separated by spaces, and only the subgrids are marked.
+func (s boxStyle) border(width int, inner bool) string {
+	var buf strings.Builder
+	buf.WriteString(s.left)
+	for c := 0; c < Size; c++ {
+		if inner {
+			buf.WriteString(strings.Repeat(s.fill, width))
+		} else {
+			buf.WriteString(strings.Repeat(s.fill, width+1))
+			if c%SubSize == SubSize-1 {
+				buf.WriteString(s.fill)
+			}
+		}
+		switch {
+		case c == Size-1:
+			buf.WriteString(s.right)
+		case c%SubSize == SubSize-1:
+			buf.WriteString(s.thick)
+		case inner:
+			buf.WriteString(s.thin)
+		}
+	}
+	buf.WriteByte('\n')
+	return buf.String()
+}
+
+// prettyGrid draws a grid of cells with box-drawing characters.  Each cell is
+// given as rows of text, all the same width when displayed, and is wrapped
+// in the ANSI escape sequence given in 'colours', if that is not empty.
+//
+// Grids of single-row cells separate only the subgrids, while grids with
+// taller cells separate every cell.
+func prettyGrid(cells *[GridSize][]string, colours *[GridSize]string) string {
+	height := len(cells[0])
+	width := len([]rune(cells[0][0]))
+	inner := height > 1
+	top, thin, thick, bottom := plainTop, plainMiddle, plainMiddle, plainBottom
+	vthin, vthick := " ", "│"
+	if inner {
+		top, thin, thick, bottom = cellTop, cellThin, cellThick, cellBottom
+		vthin, vthick = "│", "┃"
+	}
+
+	var buf strings.Builder
+	buf.WriteString(top.border(width, inner))
+	for r := 0; r < Size; r++ {
+		if r > 0 {
+			if r%SubSize == 0 {
+				buf.WriteString(thick.border(width, inner))
+			} else if inner {
+				buf.WriteString(thin.border(width, inner))
+			}
+		}
+		for line := 0; line < height; line++ {
+			buf.WriteString(vthick)
+			for c := 0; c < Size; c++ {
+				i := coordsToIndex(r, c)
+				if !inner {
+					buf.WriteByte(' ')
+				}
+				if colours[i] != "" {
+					buf.WriteString("\x1b[" + colours[i] + "m" + cells[i][line] + "\x1b[0m")
+				} else {
+					buf.WriteString(cells[i][line])
+				}
+				switch {
+				case c%SubSize == SubSize-1 && !inner:
+					buf.WriteString(" " + vthick)
+				case c%SubSize == SubSize-1:
+					buf.WriteString(vthick)
+				case inner:
+					buf.WriteString(vthin)
+				}
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteString(bottom.border(width, inner))
+	return buf.String()
+}
+
+// Pretty returns the puzzle drawn with box-drawing characters around each
+// subgrid, for display in a terminal.  Unknown cells are shown as '.'.
+//
+// See PrettyOptions for the configuration of the output.  A nil 'opts' is
+// equivalent to the zero PrettyOptions.
+func (puz *Puzzle) Pretty(opts *PrettyOptions) string {
+	if opts == nil {
+		opts = &PrettyOptions{}
+	}
+	var conflicts Mask
+	for _, conflict := range puz.Conflicts() {
+		for _, cell := range conflict.Cells {
+			conflicts[cellRefToIndex(cell)] = true
+		}
+	}
+
+	var cells [GridSize][]string
+	var colours [GridSize]string
+	for i := 0; i < GridSize; i++ {
+		glyph := puz[i]
+		if opts.Candidates == nil {
+			if Known(glyph) {
+				cells[i] = []string{string(glyph)}
+			} else {
+				cells[i] = []string{"."}
+			}
+		} else {
+			cells[i] = prettyCandidates(opts.Candidates, indexToCellRef(i), glyph)
+		}
+		if !opts.Colour {
+			continue
+		}
+		switch {
+		case conflicts[i]:
+			colours[i] = ansiConflict
+		case !Known(glyph):
+			colours[i] = ansiCandidate
+		case opts.Givens == nil || opts.Givens[i]:
+			colours[i] = ansiGiven
+		default:
+			colours[i] = ansiSolved
+		}
+	}
+	return prettyGrid(&cells, &colours)
+}
+
+// prettyCandidates returns the rows of text for a cell when candidates are
+// shown.  Known cells show their glyph in the middle.
+func prettyCandidates(cg *CandidateGrid, cell CellRef, glyph byte) []string {
+	rows := make([]string, SubSize)
+	for r := range rows {
+		line := []byte(strings.Repeat(" ", 2*SubSize+1))
+		if Known(glyph) {
+			if r == SubSize/2 {
+				line[SubSize] = glyph
+			}
+		} else {
+			for c := 0; c < SubSize; c++ {
+				g := Glyphs[r*SubSize+c]
+				if cg.Has(cell, g) {
+					line[2*c+1] = g
+				}
+			}
+		}
+		rows[r] = string(line)
+	}
+	return rows
+}
+
+// Pretty returns the mask drawn with box-drawing characters around each
+// subgrid, for display in a terminal.
+func (m *Mask) Pretty() string {
+	var cells [GridSize][]string
+	var colours [GridSize]string
+	for i := 0; i < GridSize; i++ {
+		if m[i] {
+			cells[i] = []string{"✓"}
+		} else {
+			cells[i] = []string{"✗"}
+		}
+	}
+	return prettyGrid(&cells, &colours)
+}
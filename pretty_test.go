@@ -0,0 +1,92 @@
+package sudoku
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPuzzlePretty(t *testing.T) {
+	puz := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' ',
+	}
+	expect := "┌───────┬───────┬───────┐\n" +
+		"│ . . 8 │ . . 6 │ 2 5 . │\n" +
+		"│ . . . │ . 7 . │ . 3 . │\n" +
+		"│ . . . │ . 1 2 │ 9 8 . │\n" +
+		"├───────┼───────┼───────┤\n" +
+		"│ . . 5 │ . . 3 │ . . . │\n" +
+		"│ . 2 . │ 7 . 1 │ . 6 . │\n" +
+		"│ . . . │ 8 . . │ 1 . . │\n" +
+		"├───────┼───────┼───────┤\n" +
+		"│ . 3 6 │ 2 8 . │ . . . │\n" +
+		"│ . 7 . │ . 9 . │ . . . │\n" +
+		"│ . 8 2 │ 1 . . │ 4 . . │\n" +
+		"└───────┴───────┴───────┘\n"
+	if result := puz.Pretty(nil); result != expect {
+		t.Errorf("incorrect result from Pretty: expected\n%v\ngot\n%v", expect, result)
+	}
+
+	// Colours: a given, a solved cell and a conflict.
+	givens := puz.GetMask()
+	puz[0] = '1'
+	puz[1] = '8'
+	result := puz.Pretty(&PrettyOptions{Colour: true, Givens: &givens})
+	for _, e := range []string{
+		"│ \x1b[34m1\x1b[0m \x1b[1;31m8\x1b[0m \x1b[1;31m8\x1b[0m │ \x1b[2m.\x1b[0m",
+		"\x1b[1m7\x1b[0m",
+	} {
+		if !strings.Contains(result, e) {
+			t.Errorf("incorrect result from Pretty with colour: expected output to contain %q, got\n%v", e, result)
+		}
+	}
+
+	// Candidates.
+	puz[0], puz[1] = Unknown, Unknown
+	cg := puz.CandidateGrid()
+	result = puz.Pretty(&PrettyOptions{Candidates: &cg})
+	lines := strings.Split(result, "\n")
+	expectLines := []string{
+		"┏━━━━━━━┯━━━━━━━┯━━━━━━━┳━━━━━━━┯━━━━━━━┯━━━━━━━┳━━━━━━━┯━━━━━━━┯━━━━━━━┓",
+		"┃ 1   3 │ 1     │       ┃     3 │     3 │       ┃       │       │ 1     ┃",
+		"┃ 4     │ 4     │   8   ┃ 4     │ 4     │   6   ┃   2   │   5   │ 4     ┃",
+		"┃ 7   9 │     9 │       ┃     9 │       │       ┃       │       │ 7     ┃",
+		"┠───────┼───────┼───────╂───────┼───────┼───────╂───────┼───────┼───────┨",
+	}
+	for i, e := range expectLines {
+		if lines[i] != e {
+			t.Errorf("incorrect line %v from Pretty with candidates: expected %q, got %q", i, e, lines[i])
+		}
+	}
+	// 27 rows of candidates, 10 borders and the empty string after the final
+	// newline.
+	if len(lines) != 38 {
+		t.Errorf("incorrect number of lines from Pretty with candidates: expected 38, got %v", len(lines))
+	}
+}
+
+func TestMaskPretty(t *testing.T) {
+	var m Mask
+	m[0] = true
+	m[80] = true
+	lines := strings.Split(m.Pretty(), "\n")
+	expect := map[int]string{
+		0:  "┌───────┬───────┬───────┐",
+		1:  "│ ✓ ✗ ✗ │ ✗ ✗ ✗ │ ✗ ✗ ✗ │",
+		4:  "├───────┼───────┼───────┤",
+		11: "│ ✗ ✗ ✗ │ ✗ ✗ ✗ │ ✗ ✗ ✓ │",
+		12: "└───────┴───────┴───────┘",
+	}
+	for i, e := range expect {
+		if lines[i] != e {
+			t.Errorf("incorrect line %v from Mask.Pretty: expected %q, got %q", i, e, lines[i])
+		}
+	}
+}
@@ -2,7 +2,6 @@ package sudoku
 
 import (
 	"bytes"
-	"fmt"
 )
 
 // SubSize is the number of rows and columns of a sudoku subgrid.
@@ -47,31 +46,33 @@ func Known(glyph byte) bool {
 // _ 4 _ _ 7 8 _ 1 _
 // 6 _ 8 _ _ 2 _ 4 _
 // _ 1 2 _ 4 5 _ 7 8
+//
+// Input consisting of a single line is read in the line format instead; see
+// ReadLine.
 func (puz *Puzzle) Read(input []byte) error {
-	ending := []byte("\n")
-	lines := bytes.Split(bytes.TrimSpace(input), ending)
-	if len(lines) != Size {
-		return fmt.Errorf("malformed input: expected %v lines, got %v", Size, len(lines))
+	g := NewGrid(Classic)
+	if err := g.Read(input); err != nil {
+		return err
 	}
-	i := 0
-	for r, line := range lines {
-		// Expecting one byte separating each puzzle glyph.
-		length := (Size * 2) - 1
-		if len(line) != length {
-			return fmt.Errorf("malformed input on line %v: expected %v bytes, got %v", r+1, len(line), length)
-		}
-		for c := 0; c < Size; c++ {
-			glyph := line[c*2]
-			if Known(glyph) {
-				puz[i] = glyph
-			} else if glyph == '_' || glyph == ' ' {
-				puz[i] = Unknown
-			} else {
-				return fmt.Errorf("malformed input on line %v: expected underscore, space or digit 1-9 in column %v, got %q", r+1, c+1, glyph)
-			}
-			i++
-		}
+	copy(puz[:], g.Cells)
+	return nil
+}
+
+// ReadLine reads in a puzzle definition in the line format, from a slice of
+// bytes.
+//
+// The line format is a single line of 81 glyphs with no separators, giving
+// the rows of the puzzle from top to bottom.  Unknown values are indicated by
+// a full stop '.' or a zero '0'.  Surrounding whitespace is ignored.
+//
+// E.g., the following is valid puzzle input:
+// 1.3..6.8..5..8.12.7.91.3.56.3..67.9.5.78...3.8.1.3.5.7.4..78.1.6.8..2.4..12.45.78
+func (puz *Puzzle) ReadLine(input []byte) error {
+	g := NewGrid(Classic)
+	if err := g.ReadLine(input); err != nil {
+		return err
 	}
+	copy(puz[:], g.Cells)
 	return nil
 }
 
@@ -304,30 +305,22 @@ func (puz *Puzzle) GetMask() (mask Mask) {
 // Validate a puzzle for correctness.
 //
 // A puzzle is incorrect if it contains the same glyph more than once on any
-// line, any column, or in any of the nine 3×3 subgrids.
+// line, any column, or in any of the nine 3×3 subgrids.  The error returned
+// for an incorrect puzzle is a *ConflictError describing the first duplicate
+// found; see Conflicts to find all of them.
+//
+// It is a wrapper for Grid.Validate.
 func (puz *Puzzle) Validate() error {
-	// Rows
-	for i := 0; i < Size; i++ {
-		dup := findDuplicate(puz.Row(i))
-		if dup != 0 {
-			return fmt.Errorf("invalid puzzle: duplicate %q in row %v", dup, i+1)
-		}
-	}
-	// Columns
-	for i := 0; i < Size; i++ {
-		dup := findDuplicate(puz.Column(i))
-		if dup != 0 {
-			return fmt.Errorf("invalid puzzle: duplicate %q in column %v", dup, i+1)
-		}
-	}
-	// Subgrids
-	for i := 0; i < Size; i++ {
-		dup := findDuplicate(puz.SubGrid(i))
-		if dup != 0 {
-			return fmt.Errorf("invalid puzzle: duplicate %q in subgrid %v", dup, i+1)
-		}
-	}
-	return nil
+	return puz.Grid().Validate()
+}
+
+// Conflicts returns every duplicated glyph in the puzzle's rows, columns and
+// subgrids, in that order, or nil if the puzzle is correct.
+//
+// Each glyph duplicated within a unit is reported once, listing all the cells
+// in the unit which contain it.
+func (puz *Puzzle) Conflicts() []*ConflictError {
+	return puz.Grid().Conflicts()
 }
 
 // String returns a formatted representation of a puzzle.
@@ -338,21 +331,17 @@ func (puz *Puzzle) Validate() error {
 //
 // This format can be consumed by the Read() method.
 func (puz *Puzzle) String() string {
-	var buf bytes.Buffer
-	for i := 0; i < GridSize; i++ {
-		glyph := puz[i]
-		if !Known(glyph) {
-			buf.WriteByte('_')
-		} else {
-			buf.WriteByte(puz[i])
-		}
-		if i % Size == Size-1 {
-			buf.WriteByte('\n')
-		} else {
-			buf.WriteByte(' ')
-		}
-	}
-	return buf.String()
+	return puz.Grid().String()
+}
+
+// Line returns a representation of a puzzle in the line format.
+//
+// The result is 81 glyphs long, with no separators and no trailing newline.
+// Nulls and unknowns are represented by full stop (0x2e).
+//
+// This format can be consumed by the ReadLine() and Read() methods.
+func (puz *Puzzle) Line() string {
+	return puz.Grid().Line()
 }
 
 // Clear sets all bytes of the puzzle to Null.
@@ -2,6 +2,7 @@ package sudoku
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -78,6 +79,12 @@ func TestPuzzleRead(t *testing.T) {
 	if err == nil {
 		t.Errorf("no error for invalid glyph in row 6")
 	}
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Errorf("incorrect error type for invalid glyph: expected *ParseError, got %T", err)
+	} else if perr.Line != 6 || perr.Column != 9 || perr.Byte != 'A' {
+		t.Errorf("incorrect ParseError for invalid glyph: expected line 6, column 9, byte 'A', got line %v, column %v, byte %q", perr.Line, perr.Column, perr.Byte)
+	}
 
 	// Invalid puzzle input (too few glyphs)
 	err = puz.Read([]byte(
@@ -93,6 +100,61 @@ func TestPuzzleRead(t *testing.T) {
 	if err == nil {
 		t.Errorf("no error for insufficient glyphs in row 6")
 	}
+	expect := "malformed input on line 6: expected 17 bytes, got 15"
+	if !errors.As(err, &perr) || err.Error() != expect {
+		t.Errorf("incorrect error for insufficient glyphs: expected %q, got %v", expect, err)
+	}
+}
+
+func TestPuzzleReadLine(t *testing.T) {
+	expect := Puzzle{
+		'1', ' ', '3', ' ', ' ', '6', ' ', '8', ' ',
+		' ', '5', ' ', ' ', '8', ' ', '1', '2', ' ',
+		'7', ' ', '9', '1', ' ', '3', ' ', '5', '6',
+		' ', '3', ' ', ' ', '6', '7', ' ', '9', ' ',
+		'5', ' ', '7', '8', ' ', ' ', ' ', '3', ' ',
+		'8', ' ', '1', ' ', '3', ' ', '5', ' ', '7',
+		' ', '4', ' ', ' ', '7', '8', ' ', '1', ' ',
+		'6', ' ', '8', ' ', ' ', '2', ' ', '4', ' ',
+		' ', '1', '2', ' ', '4', '5', ' ', '7', '8'}
+	var puz Puzzle
+	inputs := []string{
+		"1.3..6.8..5..8.12.7.91.3.56.3..67.9.5.78...3.8.1.3.5.7.4..78.1.6.8..2.4..12.45.78",
+		"103006080050080120709103056030067090507800030801030507040078010608002040012045078\n",
+	}
+	for _, input := range inputs {
+		err := puz.ReadLine([]byte(input))
+		if err != nil {
+			t.Errorf("ReadLine failed on valid input %q: %v", input, err)
+		}
+		if !puz.Equal(expect) {
+			t.Errorf("incorrect result from ReadLine: expected:\n%v\n\ngot:\n%v", expect.String(), puz.String())
+		}
+		puz.Clear()
+		err = puz.Read([]byte(input))
+		if err != nil {
+			t.Errorf("Read failed on valid line input %q: %v", input, err)
+		}
+		if !puz.Equal(expect) {
+			t.Errorf("incorrect result from Read for line input: expected:\n%v\n\ngot:\n%v", expect.String(), puz.String())
+		}
+	}
+
+	// Too short
+	err := puz.ReadLine([]byte("1.3..6.8."))
+	if err == nil {
+		t.Errorf("no error for short line input")
+	}
+
+	// Invalid glyph
+	err = puz.ReadLine([]byte("1.3..6.8..5..8.12.7.91.3.56.3..67.9.5.78...3.8.1.3.5.7.4..78.1.6.8..2.4..12.45.7x"))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Column != 81 || perr.Byte != 'x' {
+		t.Errorf("incorrect error for invalid glyph in line input: %v", err)
+	}
+	if !puz.Equal(expect) {
+		t.Errorf("puzzle modified by failed ReadLine:\n%v", puz.String())
+	}
 }
 
 func TestPuzzleRow(t *testing.T) {
@@ -580,6 +642,60 @@ func TestPuzzleValidate(t *testing.T) {
 	puz[71] = orig
 }
 
+func TestPuzzleConflicts(t *testing.T) {
+	puz := Puzzle{
+		'1', ' ', '3', ' ', ' ', '6', ' ', '8', ' ',
+		' ', '5', ' ', ' ', '8', ' ', '1', '2', ' ',
+		'7', ' ', '9', '1', ' ', '3', ' ', '5', '6',
+		' ', '3', ' ', ' ', '6', '7', ' ', '9', ' ',
+		'5', ' ', '7', '8', ' ', ' ', ' ', '3', ' ',
+		'8', ' ', '1', ' ', '3', ' ', '5', ' ', '7',
+		' ', '4', ' ', ' ', '7', '8', ' ', '1', ' ',
+		'6', ' ', '8', ' ', ' ', '2', ' ', '4', ' ',
+		' ', '1', '2', ' ', '4', '5', ' ', '7', '8'}
+	if result := puz.Conflicts(); result != nil {
+		t.Errorf("conflicts for valid puzzle: %v", result)
+	}
+
+	// A 9 in R4C6 duplicates the 9 in row 4, and a 7 in R1C1 duplicates the
+	// 7 in column 1 and subgrid 1.
+	puz[32] = '9'
+	puz[0] = '7'
+	err := puz.Validate()
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("incorrect error type from Validate: expected *ConflictError, got %T", err)
+	}
+	if conflict.Unit != RowUnit || conflict.Index != 3 || conflict.Glyph != '9' {
+		t.Errorf("incorrect conflict from Validate: expected '9' in row 4, got %q in %v %v", conflict.Glyph, conflict.Unit, conflict.Index+1)
+	}
+	expect := []CellRef{{3, 5}, {3, 7}}
+	if !equalRefs(conflict.Cells, expect) {
+		t.Errorf("incorrect conflict cells from Validate: expected %v, got %v", expect, conflict.Cells)
+	}
+
+	result := puz.Conflicts()
+	expects := []struct {
+		unit  UnitType
+		index int
+		glyph byte
+		cells []CellRef
+	}{
+		{RowUnit, 3, '9', []CellRef{{3, 5}, {3, 7}}},
+		{ColumnUnit, 0, '7', []CellRef{{0, 0}, {2, 0}}},
+		{SubGridUnit, 0, '7', []CellRef{{0, 0}, {2, 0}}},
+	}
+	if len(result) != len(expects) {
+		t.Fatalf("incorrect number of conflicts: expected %v, got %v: %v", len(expects), len(result), result)
+	}
+	for i, e := range expects {
+		c := result[i]
+		if c.Unit != e.unit || c.Index != e.index || c.Glyph != e.glyph || !equalRefs(c.Cells, e.cells) {
+			t.Errorf("incorrect conflict %v: expected %q in %v %v at %v, got %v", i, e.glyph, e.unit, e.index+1, e.cells, c)
+		}
+	}
+}
+
 func TestPuzzleString(t *testing.T) {
 	puz := Puzzle{
 		'1', ' ', '3', ' ', ' ', '6', ' ', '8', ' ',
@@ -607,6 +723,24 @@ func TestPuzzleString(t *testing.T) {
 	}
 }
 
+func TestPuzzleLine(t *testing.T) {
+	puz := Puzzle{
+		'1', ' ', '3', ' ', ' ', '6', ' ', '8', ' ',
+		' ', '5', ' ', ' ', '8', ' ', '1', '2', ' ',
+		'7', ' ', '9', '1', ' ', '3', ' ', '5', '6',
+		' ', '3', ' ', ' ', '6', '7', ' ', '9', ' ',
+		'5', ' ', '7', '8', ' ', ' ', ' ', '3', ' ',
+		'8', ' ', '1', ' ', '3', ' ', '5', ' ', '7',
+		' ', '4', ' ', ' ', '7', '8', ' ', '1', ' ',
+		'6', ' ', '8', ' ', ' ', '2', ' ', '4', ' ',
+		' ', '1', '2', ' ', '4', '5', ' ', '7',   0}
+	expect := "1.3..6.8..5..8.12.7.91.3.56.3..67.9.5.78...3.8.1.3.5.7.4..78.1.6.8..2.4..12.45.7."
+	result := puz.Line()
+	if result != expect {
+		t.Errorf("incorrect result from Line: expected %q, got %q", expect, result)
+	}
+}
+
 func TestPuzzleClear(t *testing.T) {
 	puz := Puzzle{
 		'1', ' ', '3', ' ', ' ', '6', ' ', '8', ' ',
@@ -0,0 +1,339 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// RegionMap assigns each cell of a grid to a region, for jigsaw puzzles whose
+// boxes are replaced by irregular regions.
+//
+// It holds one byte for each cell, in grid order, giving the 0-based index of
+// the cell's region.  A string is used so that shapes with regions can still
+// be compared with ==.
+type RegionMap string
+
+// regionLetters are the letters used for regions in the jigsaw format, in
+// order of region index.
+const regionLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghij"
+
+// NewRegionMap returns the region map for the given region index of each
+// cell, in grid order.
+func NewRegionMap(regions []int) RegionMap {
+	buf := make([]byte, len(regions))
+	for i, r := range regions {
+		buf[i] = byte(r)
+	}
+	return RegionMap(buf)
+}
+
+// BoxRegions returns the region map equivalent to the boxes of a shape.
+func BoxRegions(s Shape) RegionMap {
+	size := s.Size()
+	regions := make([]int, s.NumCells())
+	for i := range regions {
+		regions[i] = s.Box(i/size, i%size)
+	}
+	return NewRegionMap(regions)
+}
+
+// Region returns the index of the region containing the cell with the given
+// index into the grid.
+func (m RegionMap) Region(index int) int {
+	return int(m[index])
+}
+
+// String returns the region map as rows of region letters separated by
+// spaces, one line for each row of the grid, in the format consumed by
+// ParseRegionMap.
+func (m RegionMap) String() string {
+	size := 0
+	for size*size < len(m) {
+		size++
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(m); i++ {
+		buf.WriteByte(regionLetter(m.Region(i)))
+		if i%size == size-1 {
+			buf.WriteByte('\n')
+		} else {
+			buf.WriteByte(' ')
+		}
+	}
+	return buf.String()
+}
+
+// Line returns the region map as a single line of region letters, one for
+// each cell with no separators.
+func (m RegionMap) Line() string {
+	buf := make([]byte, len(m))
+	for i := range buf {
+		buf[i] = regionLetter(m.Region(i))
+	}
+	return string(buf)
+}
+
+// regionLetter returns the letter for a region in the jigsaw format.
+func regionLetter(region int) byte {
+	if region < len(regionLetters) {
+		return regionLetters[region]
+	}
+	return '?'
+}
+
+// ParseRegionMap reads a region map from a slice of bytes, giving a letter
+// for each cell: 'A' for the first region, 'B' for the second and so on,
+// continuing with lower case letters after 'Z'.  The input is either one line
+// for each row, with the letters separated by a single byte, or a single line
+// with one letter for each cell and no separators.
+func ParseRegionMap(input []byte) (RegionMap, error) {
+	input = bytes.TrimSpace(input)
+	var letters []byte
+	if bytes.IndexByte(input, '\n') < 0 {
+		letters = input
+	} else {
+		lines := bytes.Split(input, []byte("\n"))
+		size := len(lines)
+		for r, line := range lines {
+			line = bytes.TrimRight(line, "\r")
+			length := (size * 2) - 1
+			if len(line) != length {
+				return "", &ParseError{Line: r + 1, Reason: fmt.Sprintf("expected %v bytes, got %v", length, len(line))}
+			}
+			for c := 0; c < size; c++ {
+				letters = append(letters, line[c*2])
+			}
+		}
+	}
+	regions := make([]int, len(letters))
+	for i, letter := range letters {
+		regions[i] = strings.IndexByte(regionLetters, letter)
+		if regions[i] < 0 {
+			line, col := 1, i+1
+			if len(letters) != len(input) {
+				// The letters were read from one line per row.
+				size := bytes.Count(input, []byte("\n")) + 1
+				line, col = i/size+1, (i%size)*2+1
+			}
+			return "", &ParseError{Line: line, Column: col, Byte: letter, Reason: fmt.Sprintf("expected region letter, got %q", letter)}
+		}
+	}
+	return NewRegionMap(regions), nil
+}
+
+// JigsawShape returns the shape of a jigsaw grid with 'size' rows and columns
+// and the given regions, using DefaultGlyphs.
+func JigsawShape(size int, regions RegionMap) (Shape, error) {
+	s := Shape{BoxWidth: size, BoxHeight: 1, Glyphs: DefaultGlyphs(size), Regions: regions}
+	return s, s.Validate()
+}
+
+// validateRegions checks that the shape's regions each contain one cell for
+// each glyph, and that the cells of each region are connected.
+func (s Shape) validateRegions() error {
+	size := s.Size()
+	if len(s.Regions) != s.NumCells() {
+		return fmt.Errorf("expected %v cells in region map, got %v", s.NumCells(), len(s.Regions))
+	}
+	cells := make([][]int, size)
+	for i := range s.Regions {
+		r := s.Regions.Region(i)
+		if r >= size {
+			ref := CellRef{i / size, i % size}
+			return fmt.Errorf("invalid region %v for cell %v: must be less than %v", r, ref.String(), size)
+		}
+		cells[r] = append(cells[r], i)
+	}
+	for r, region := range cells {
+		if len(region) != size {
+			return fmt.Errorf("region %v has %v cells, expected %v", r, len(region), size)
+		}
+		if !s.connected(region) {
+			return fmt.Errorf("region %v is not connected", r)
+		}
+	}
+	return nil
+}
+
+// connected returns whether the given cells, which must all be in the same
+// region, form a single orthogonally connected group.
+func (s Shape) connected(cells []int) bool {
+	if len(cells) == 0 {
+		return true
+	}
+	region := s.Regions.Region(cells[0])
+	seen := map[int]bool{cells[0]: true}
+	queue := []int{cells[0]}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range s.neighbours(i) {
+			if !seen[j] && s.Regions.Region(j) == region {
+				seen[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+	return len(seen) == len(cells)
+}
+
+// neighbours returns the indexes of the cells orthogonally adjacent to a cell.
+func (s Shape) neighbours(index int) []int {
+	size := s.Size()
+	row, col := index/size, index%size
+	result := make([]int, 0, 4)
+	if row > 0 {
+		result = append(result, index-size)
+	}
+	if row < size-1 {
+		result = append(result, index+size)
+	}
+	if col > 0 {
+		result = append(result, index-1)
+	}
+	if col < size-1 {
+		result = append(result, index+1)
+	}
+	return result
+}
+
+// RandomRegions returns a randomly shaped region map for a grid with 'size'
+// rows and columns, in which every region is connected and has 'size' cells.
+//
+// The layout begins as the boxes of ShapeForSize, or the rows of the grid if
+// the size has no box shape, and is then repeatedly distorted by exchanging
+// cells along the borders between regions.  Not every such layout can be solved; see
+// GenerateJigsaw.
+func RandomRegions(size int, random *rand.Rand) RegionMap {
+	s := Shape{BoxWidth: size, BoxHeight: 1}
+	if boxes, err := ShapeForSize(size); err == nil {
+		s.Regions = BoxRegions(boxes)
+	} else {
+		regions := make([]int, size*size)
+		for i := range regions {
+			regions[i] = i / size
+		}
+		s.Regions = NewRegionMap(regions)
+	}
+	regions := []byte(s.Regions)
+	for n := 0; n < 20*len(regions); n++ {
+		// Move a cell 'a' into a neighbouring region, and some cell 'b' of
+		// that region which borders the first region the other way.
+		a := random.Intn(len(regions))
+		adjacent := s.neighbours(a)
+		ra, rb := regions[a], regions[adjacent[random.Intn(len(adjacent))]]
+		if ra == rb {
+			continue
+		}
+		var border []int
+		for i, r := range regions {
+			if r != rb {
+				continue
+			}
+			for _, j := range s.neighbours(i) {
+				if j != a && regions[j] == ra {
+					border = append(border, i)
+					break
+				}
+			}
+		}
+		if len(border) == 0 {
+			continue
+		}
+		b := border[random.Intn(len(border))]
+		regions[a], regions[b] = rb, ra
+		s.Regions = RegionMap(regions)
+		if !s.connected(s.regionCells(int(ra))) || !s.connected(s.regionCells(int(rb))) {
+			regions[a], regions[b] = ra, rb
+		}
+	}
+	return RegionMap(regions)
+}
+
+// regionCells returns the indexes of the cells in a region.
+func (s Shape) regionCells(region int) (cells []int) {
+	for i := range s.Regions {
+		if s.Regions.Region(i) == region {
+			cells = append(cells, i)
+		}
+	}
+	return
+}
+
+// jigsawAttempt is how long GenerateJigsawContext spends looking for a
+// solution to one region layout before trying another.
+const jigsawAttempt = time.Second
+
+// GenerateJigsaw returns a randomly generated solution to a jigsaw grid with
+// 'size' rows and columns and randomly shaped regions; see RandomRegions.
+func GenerateJigsaw(size int) (*Grid, error) {
+	return GenerateJigsawContext(context.Background(), size)
+}
+
+// GenerateJigsawContext is like GenerateJigsaw, but gives up when the context
+// is cancelled or its deadline expires, returning ctx.Err().
+//
+// Region layouts which have no solution, or for which none is found quickly,
+// are discarded in favour of a new layout.
+func GenerateJigsawContext(ctx context.Context, size int) (*Grid, error) {
+	random := newRand()
+	for {
+		shape, err := JigsawShape(size, RandomRegions(size, random))
+		if err != nil {
+			return nil, err
+		}
+		attempt, cancel := context.WithTimeout(ctx, jigsawAttempt)
+		g, err := GenerateGridContext(attempt, shape)
+		cancel()
+		if g != nil {
+			return g, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ParseJigsaw reads a jigsaw grid from a slice of bytes in the format written
+// by Grid.JigsawString: the grid's glyphs in either the grid or the line
+// format, then a blank line, then its regions in either of the formats
+// consumed by ParseRegionMap.  The shape is taken from the size of the grid, using
+// DefaultGlyphs.
+func ParseJigsaw(input []byte) (*Grid, error) {
+	parts, err := splitPuzzle(input, "regions")
+	if err != nil {
+		return nil, err
+	}
+	regions, err := ParseRegionMap(parts[1])
+	if err != nil {
+		return nil, sectionError(err, parts, 1)
+	}
+	size := 0
+	for size*size < len(regions) {
+		size++
+	}
+	shape, err := JigsawShape(size, regions)
+	if err != nil {
+		return nil, &ParseError{Reason: err.Error()}
+	}
+	g := NewGrid(shape)
+	if err := g.Read(parts[0]); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// JigsawString returns a representation of the grid and its regions in the
+// format consumed by ParseJigsaw.  For a grid without regions, the regions
+// are its boxes.
+func (g *Grid) JigsawString() string {
+	regions := g.Shape.Regions
+	if regions == "" {
+		regions = BoxRegions(g.Shape)
+	}
+	return g.String() + "\n" + regions.String()
+}
@@ -0,0 +1,209 @@
+package sudoku
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const jigsawPuzzle = `_ _ 3 _ _ _ 5 _ 2
+_ 9 _ _ _ _ _ _ _
+_ _ _ _ _ 1 _ 3 _
+8 7 _ _ 9 _ _ _ _
+_ 6 _ 5 _ _ _ _ _
+5 _ _ _ _ _ 4 _ _
+_ 1 _ 8 _ _ _ 6 _
+_ _ _ _ _ _ _ _ _
+1 _ _ _ _ _ _ _ _
+
+A A A A B C C C C
+A A A B B B B C C
+A A B B B B C C C
+D D E E E F F F F
+D D D E E E E F F
+D D H H E E I I F
+G D D H H H H I F
+G G G G H I H I F
+G G G G H I I I I
+`
+
+const jigsawSolution = "683179542495286371729451638876392154962514783531728496214835967358647219147963825"
+
+func TestParseRegionMap(t *testing.T) {
+	input := jigsawPuzzle[strings.Index(jigsawPuzzle, "\n\n")+2:]
+	m, err := ParseRegionMap([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseRegionMap: %v", err)
+	}
+	if len(m) != GridSize {
+		t.Fatalf("incorrect length of region map: expected %v, got %v", GridSize, len(m))
+	}
+	cases := []struct {
+		index, expect int
+	}{
+		{0, 0},
+		{4, 1},
+		{8, 2},
+		{40, 4},
+		{47, 7},
+		{80, 8},
+	}
+	for _, c := range cases {
+		if result := m.Region(c.index); result != c.expect {
+			t.Errorf("incorrect result from Region(%v): expected %v, got %v", c.index, c.expect, result)
+		}
+	}
+	if result := m.String(); result != input {
+		t.Errorf("incorrect result from String: expected %q, got %q", input, result)
+	}
+
+	line, err := ParseRegionMap([]byte(m.Line()))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseRegionMap for line: %v", err)
+	}
+	if line != m {
+		t.Errorf("incorrect region map from line: expected %q, got %q", m.Line(), line.Line())
+	}
+
+	_, err = ParseRegionMap([]byte("A A\nA ?\n"))
+	e, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+	if e.Line != 2 || e.Column != 3 || e.Byte != '?' {
+		t.Errorf("incorrect error position: expected line 2, column 3 for '?', got line %v, column %v for %q", e.Line, e.Column, e.Byte)
+	}
+}
+
+func TestShapeValidateRegions(t *testing.T) {
+	invalid := []string{
+		"AABB",
+		"AAAABBBBCCCCDDDDD",
+		"AAAABBBBCCCCDDDE",
+		"AABBABBACCDDCCDD",
+		"AAABBBBACCDDCCDD",
+	}
+	for _, line := range invalid {
+		m, err := ParseRegionMap([]byte(line))
+		if err != nil {
+			t.Fatalf("unexpected error from ParseRegionMap for %q: %v", line, err)
+		}
+		s := Shape{BoxWidth: 4, BoxHeight: 1, Glyphs: "1234", Regions: m}
+		if err := s.Validate(); err == nil {
+			t.Errorf("no error from Validate for invalid regions %q", line)
+		}
+	}
+	m, _ := ParseRegionMap([]byte("AAABACCBDCCBDDDB"))
+	if _, err := JigsawShape(4, m); err != nil {
+		t.Errorf("unexpected error from JigsawShape: %v", err)
+	}
+}
+
+func TestBoxRegions(t *testing.T) {
+	shape, _ := NewShape(3, 2)
+	expect := "AAABBBAAABBBCCCDDDCCCDDDEEEFFFEEEFFF"
+	if result := BoxRegions(shape).Line(); result != expect {
+		t.Errorf("incorrect result from BoxRegions: expected %q, got %q", expect, result)
+	}
+}
+
+func TestParseJigsaw(t *testing.T) {
+	g, err := ParseJigsaw([]byte(jigsawPuzzle))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseJigsaw: %v", err)
+	}
+	if g.Shape.String() != "9×9 jigsaw" {
+		t.Errorf("incorrect shape: expected 9×9 jigsaw, got %v", g.Shape)
+	}
+	if result := g.JigsawString(); result != jigsawPuzzle {
+		t.Errorf("incorrect result from JigsawString: expected %q, got %q", jigsawPuzzle, result)
+	}
+
+	line := g.Line() + "\n\n" + g.Shape.Regions.Line() + "\n"
+	other, err := ParseJigsaw([]byte(line))
+	if err != nil {
+		t.Fatalf("unexpected error from ParseJigsaw for line format: %v", err)
+	}
+	if !other.Equal(g) {
+		t.Errorf("incorrect result from ParseJigsaw for line format: expected\n%v\ngot\n%v", g.JigsawString(), other.JigsawString())
+	}
+
+	invalid := []string{
+		jigsawPuzzle[:strings.Index(jigsawPuzzle, "\n\n")],
+		strings.Replace(jigsawPuzzle, "G G G G H I I I I", "G G G G H I I I !", 1),
+		strings.Replace(jigsawPuzzle, "G G G G H I I I I", "G G G G H I I I H", 1),
+		strings.Replace(jigsawPuzzle, "1 _ _ _ _ _ _ _ _", "1 _ _ _ _ _ _ _ x", 1),
+	}
+	for _, input := range invalid {
+		if _, err := ParseJigsaw([]byte(input)); err == nil {
+			t.Errorf("no error from ParseJigsaw for invalid input:\n%v", input)
+		}
+	}
+	_, err = ParseJigsaw([]byte(invalid[1]))
+	if e, ok := err.(*ParseError); !ok || e.Line != 19 || e.Column != 17 {
+		t.Errorf("incorrect error for bad region letter: expected line 19, column 17, got %v", err)
+	}
+}
+
+func TestJigsawSolve(t *testing.T) {
+	g, _ := ParseJigsaw([]byte(jigsawPuzzle))
+	if n := g.NumSolutions(); n != 1 {
+		t.Errorf("incorrect result from NumSolutions: expected 1, got %v", n)
+	}
+	if remain := g.Solve(); remain != 0 {
+		t.Fatalf("incorrect result from Solve: expected 0, got %v", remain)
+	}
+	if result := g.Line(); result != jigsawSolution {
+		t.Errorf("incorrect solution: expected %v, got %v", jigsawSolution, result)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("unexpected error from Validate: %v", err)
+	}
+	// The classic boxes are not respected by the jigsaw solution.
+	classic := &Grid{Shape: Classic, Cells: g.Cells}
+	if err := classic.Validate(); err == nil {
+		t.Errorf("no error from Validate for jigsaw solution with classic boxes")
+	}
+}
+
+func TestJigsawConflicts(t *testing.T) {
+	g, _ := ParseJigsaw([]byte(jigsawPuzzle))
+	// R1C1 and R3C2 share region A, but not a row or column.
+	g.Set(0, 0, '4')
+	g.Set(2, 1, '4')
+	err, ok := g.Validate().(*ConflictError)
+	if !ok {
+		t.Fatalf("expected *ConflictError from Validate, got %v", g.Validate())
+	}
+	if err.Unit != RegionUnit || err.Index != 0 || err.Glyph != '4' {
+		t.Errorf("incorrect conflict: expected '4' in region 1, got %q in %v %v", err.Glyph, err.Unit, err.Index+1)
+	}
+}
+
+func TestRandomRegions(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, size := range []int{2, 4, 5, 6, 7, 9, 12} {
+		m := RandomRegions(size, random)
+		if _, err := JigsawShape(size, m); err != nil {
+			t.Errorf("invalid result from RandomRegions(%v): %v\n%v", size, err, m)
+		}
+	}
+}
+
+func TestGenerateJigsaw(t *testing.T) {
+	for _, size := range []int{5, 9} {
+		g, err := GenerateJigsaw(size)
+		if err != nil {
+			t.Fatalf("unexpected error from GenerateJigsaw(%v): %v", size, err)
+		}
+		if g.Shape.Regions == "" || g.Size() != size {
+			t.Errorf("incorrect shape from GenerateJigsaw(%v): %v", size, g.Shape)
+		}
+		if n := g.NumUnknowns(); n != 0 {
+			t.Errorf("incorrect number of unknowns from GenerateJigsaw(%v): expected 0, got %v", size, n)
+		}
+		if err := g.Validate(); err != nil {
+			t.Errorf("invalid result from GenerateJigsaw(%v): %v", size, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package sudoku
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// Relation is the constraint that the glyphs of certain pairs of cells are
+// related in a given way: for example, that cells a knight's move apart do
+// not hold the same glyph, or that adjacent cells do not hold consecutive
+// values.
+type Relation struct {
+	// Name describes the relation in errors, e.g. "anti-knight".
+	Name string
+	// Pairs lists the indexes of the pairs of cells which are related.
+	Pairs [][2]int
+	// Allowed holds a bit mask for each glyph 'n' of the shape, of the
+	// glyphs allowed in the second cell of a pair when the first cell holds
+	// glyph 'n'.
+	Allowed []uint64
+	// cells lists the cells of the pairs in grid order, worked out once by
+	// NewRelation since the searcher asks for them on every pass.
+	cells []int
+}
+
+// NewRelation returns a Relation between the given pairs of cells in a grid
+// of the given shape, allowing values 'a' in the first cell and 'b' in the
+// second cell of a pair only when allowed(a, b) is true.  Glyph 'n' has the
+// value n+1.
+func NewRelation(name string, shape Shape, pairs [][2]int, allowed func(a, b int) bool) Relation {
+	size := shape.Size()
+	r := Relation{Name: name, Pairs: pairs, Allowed: make([]uint64, size), cells: pairCells(pairs)}
+	for a := 0; a < size; a++ {
+		for b := 0; b < size; b++ {
+			if allowed(a+1, b+1) {
+				r.Allowed[a] |= 1 << uint(b)
+			}
+		}
+	}
+	return r
+}
+
+// Cells returns every cell named in the relation's pairs, in grid order.
+func (r Relation) Cells() []int {
+	if r.cells != nil {
+		return r.cells
+	}
+	return pairCells(r.Pairs)
+}
+
+// pairCells returns the distinct cells of a list of pairs, in grid order.
+func pairCells(pairs [][2]int) []int {
+	seen := make(map[int]bool)
+	var cells []int
+	for _, p := range pairs {
+		for _, i := range p {
+			if !seen[i] {
+				seen[i] = true
+				cells = append(cells, i)
+			}
+		}
+	}
+	sort.Ints(cells)
+	return cells
+}
+
+// Check returns a *ConstraintError for the first pair of known cells whose
+// glyphs are not related.
+func (r Relation) Check(g *Grid) error {
+	for _, p := range r.Pairs {
+		a, b := g.Shape.glyphIndex(g.Cells[p[0]]), g.Shape.glyphIndex(g.Cells[p[1]])
+		if a < 0 || b < 0 || r.Allowed[a]&(1<<uint(b)) != 0 {
+			continue
+		}
+		return &ConstraintError{
+			Constraint: r,
+			Cells:      []CellRef{g.cellRef(p[0]), g.cellRef(p[1])},
+			Reason:     fmt.Sprintf("%v rule broken by %q and %q", r.Name, g.Cells[p[0]], g.Cells[p[1]]),
+		}
+	}
+	return nil
+}
+
+// Propagate removes the candidates of each cell which have no related
+// candidate left in the other cell of a pair.
+func (r Relation) Propagate(cand []uint64) bool {
+	for _, p := range r.Pairs {
+		var first, second uint64
+		for c := cand[p[0]]; c != 0; c &= c - 1 {
+			n := bits.TrailingZeros64(c)
+			if allowed := r.Allowed[n] & cand[p[1]]; allowed != 0 {
+				first |= 1 << uint(n)
+				second |= allowed
+			}
+		}
+		cand[p[0]], cand[p[1]] = first, second
+		if first == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// distinct returns whether the relation is simply that the glyphs of each
+// pair differ, so that the pairs can be treated as peers.
+func (r Relation) distinct() bool {
+	full := uint64(1)<<uint(len(r.Allowed)) - 1
+	for n, allowed := range r.Allowed {
+		if allowed != full&^(1<<uint(n)) {
+			return false
+		}
+	}
+	return true
+}
+
+// movePairs returns each pair of cells in a grid with 'size' rows and columns
+// which are one of the given moves apart, with the first cell of each pair
+// before the second in grid order.
+func movePairs(size int, moves [][2]int) (pairs [][2]int) {
+	for i := 0; i < size*size; i++ {
+		row, col := i/size, i%size
+		for _, m := range moves {
+			r, c := row+m[0], col+m[1]
+			if r < 0 || r >= size || c < 0 || c >= size {
+				continue
+			}
+			if j := r*size + c; j > i {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	}
+	return
+}
+
+var (
+	knightMoves = [][2]int{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+	kingMoves   = [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	rookSteps   = [][2]int{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+)
+
+// AntiKnight returns the constraint that cells a chess knight's move apart do
+// not hold the same glyph.
+func AntiKnight(shape Shape) Relation {
+	return NewRelation("anti-knight", shape, movePairs(shape.Size(), knightMoves), func(a, b int) bool {
+		return a != b
+	})
+}
+
+// AntiKing returns the constraint that cells a chess king's move apart,
+// including diagonally, do not hold the same glyph.
+func AntiKing(shape Shape) Relation {
+	return NewRelation("anti-king", shape, movePairs(shape.Size(), kingMoves), func(a, b int) bool {
+		return a != b
+	})
+}
+
+// NonConsecutive returns the constraint that orthogonally adjacent cells do
+// not hold values which differ by one.
+func NonConsecutive(shape Shape) Relation {
+	return NewRelation("non-consecutive", shape, movePairs(shape.Size(), rookSteps), func(a, b int) bool {
+		return a-b != 1 && b-a != 1
+	})
+}
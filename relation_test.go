@@ -0,0 +1,148 @@
+package sudoku
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRelationPairs(t *testing.T) {
+	cases := []struct {
+		name     string
+		relation Relation
+		count    int
+		distinct bool
+	}{
+		{"anti-knight", AntiKnight(Classic), 224, true},
+		{"anti-king", AntiKing(Classic), 272, true},
+		{"non-consecutive", NonConsecutive(Classic), 144, false},
+	}
+	for _, c := range cases {
+		if c.relation.Name != c.name {
+			t.Errorf("incorrect name: expected %q, got %q", c.name, c.relation.Name)
+		}
+		if n := len(c.relation.Pairs); n != c.count {
+			t.Errorf("incorrect number of pairs for %v: expected %v, got %v", c.name, c.count, n)
+		}
+		if d := c.relation.distinct(); d != c.distinct {
+			t.Errorf("incorrect result from distinct for %v: expected %v, got %v", c.name, c.distinct, d)
+		}
+		for _, p := range c.relation.Pairs {
+			if p[0] >= p[1] {
+				t.Errorf("incorrect pair for %v: %v is not in grid order", c.name, p)
+			}
+		}
+	}
+
+	pairs := AntiKnight(Classic).Pairs
+	if expected := [][2]int{{0, 11}, {0, 19}}; !reflect.DeepEqual(pairs[:2], expected) {
+		t.Errorf("incorrect anti-knight pairs: expected %v, got %v", expected, pairs[:2])
+	}
+	if cells := NonConsecutive(Classic).Cells(); len(cells) != 81 || cells[80] != 80 {
+		t.Errorf("incorrect cells for non-consecutive: expected 0 to 80, got %v", cells)
+	}
+}
+
+func TestRelationCheck(t *testing.T) {
+	r := NonConsecutive(Classic)
+	g := NewGrid(Classic)
+	if err := r.Check(g); err != nil {
+		t.Errorf("unexpected error from Check on empty grid: %v", err)
+	}
+	g.Set(0, 0, '4')
+	g.Set(0, 1, '6')
+	g.Set(1, 1, '8')
+	if err := r.Check(g); err != nil {
+		t.Errorf("unexpected error from Check: %v", err)
+	}
+	g.Set(1, 0, '5')
+	err, ok := r.Check(g).(*ConstraintError)
+	if !ok {
+		t.Fatalf("expected *ConstraintError from Check, got %v", r.Check(g))
+	}
+	expected := "invalid puzzle: non-consecutive rule broken by '4' and '5' at R1C1, R2C1"
+	if err.Error() != expected {
+		t.Errorf("incorrect error: expected %q, got %q", expected, err.Error())
+	}
+
+	g.Constraints = []Constraint{AntiKnight(Classic)}
+	g.Clear()
+	g.Set(0, 0, '7')
+	g.Set(2, 1, '7')
+	if g.Validate() == nil {
+		t.Errorf("expected error from Validate for '7' a knight's move apart")
+	}
+}
+
+func TestRelationPropagate(t *testing.T) {
+	r := NonConsecutive(Classic)
+	cand := make([]uint64, 81)
+	for i := range cand {
+		cand[i] = 0x1ff
+	}
+	// A 5 in R1C1 rules out 4 and 6 in R1C2 and R2C1.
+	cand[0] = 1 << 4
+	if !r.Propagate(cand) {
+		t.Fatalf("unexpected failure from Propagate")
+	}
+	if expected := uint64(0x1ff &^ (1<<3 | 1<<5)); cand[1] != expected || cand[9] != expected {
+		t.Errorf("incorrect candidates: expected %09b, got %09b and %09b", expected, cand[1], cand[9])
+	}
+	if cand[2] != 0x1ff {
+		t.Errorf("incorrect candidates for R1C3: expected %09b, got %09b", 0x1ff, cand[2])
+	}
+
+	// A 2 in R1C2 then leaves no candidate for R1C1.
+	cand[1] = 1 << 1
+	cand[0] = 1<<0 | 1<<2
+	if r.Propagate(cand) {
+		t.Errorf("expected failure from Propagate")
+	}
+}
+
+func TestRelationRules(t *testing.T) {
+	rules := []Rule{AntiKnightRule, NonConsecutiveRule}
+	constraints, err := VariantConstraints(Classic, rules)
+	if err != nil {
+		t.Fatalf("unexpected error from VariantConstraints: %v", err)
+	}
+	g, err := GenerateVariant(Classic, constraints)
+	if err != nil || g == nil {
+		t.Fatalf("no result from GenerateVariant: %v", err)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("invalid result from GenerateVariant: %v", err)
+	}
+	puzzle := g.ApplyMask(g.MinimalMask())
+	if n := puzzle.NumSolutions(); n != 1 {
+		t.Errorf("incorrect result from NumSolutions: expected 1, got %v", n)
+	}
+	plain := puzzle.Copy()
+	plain.Constraints = nil
+	if n := plain.NumSolutions(); n < 2 {
+		t.Errorf("expected several solutions without the rules, got %v", n)
+	}
+
+	// The relations apply to jigsaw grids as well.
+	shape, _ := JigsawShape(6, RandomRegions(6, newRand()))
+	if _, err := VariantConstraints(shape, []Rule{AntiKingRule}); err != nil {
+		t.Errorf("unexpected error from VariantConstraints for jigsaw: %v", err)
+	}
+}
+
+func TestNonConsecutiveGenerateTime(t *testing.T) {
+	// The clues of a non-consecutive puzzle are few, so that each check of
+	// uniqueness searches widely.
+	const limit = 30 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	defer cancel()
+	constraints, _ := VariantConstraints(Classic, []Rule{NonConsecutiveRule})
+	g, err := GenerateVariantContext(ctx, Classic, constraints)
+	if err == nil {
+		_, err = g.MinimalMaskContext(ctx)
+	}
+	if err != nil {
+		t.Errorf("non-consecutive puzzle not generated within %v: %v", limit, err)
+	}
+}
@@ -0,0 +1,395 @@
+package sudoku
+
+import (
+	"image/color"
+	"math"
+)
+
+// CandidateRef refers to one candidate glyph within a cell.
+type CandidateRef struct {
+	Cell  CellRef
+	Glyph byte
+}
+
+// Highlight colours the background of a cell.
+type Highlight struct {
+	Cell   CellRef
+	Colour color.Color
+}
+
+// CandidateHighlight colours the background of a single candidate.
+type CandidateHighlight struct {
+	Candidate CandidateRef
+	Colour    color.Color
+}
+
+// Link joins two candidates, as in the chains and loops found by solving
+// techniques.  Strong links are drawn with solid lines, and weak links with
+// dashed lines.
+type Link struct {
+	From, To CandidateRef
+	Strong   bool
+}
+
+// RenderOptions configures the graphical rendering of a puzzle.  The zero
+// value is ready to use, and draws the puzzle alone at the default size.
+type RenderOptions struct {
+	// CellSize is the width and height of each cell, in pixels.  Defaults to
+	// 40.
+	CellSize int
+	// Margin is the space around the grid, in pixels.  Defaults to half of
+	// CellSize.
+	Margin int
+	// Givens marks the cells which hold clues.  Other known cells are drawn
+	// as entered digits.  If nil, every known cell is drawn as a given.
+	Givens *Mask
+	// Candidates, if not nil, are drawn as pencil marks in unknown cells.
+	Candidates *CandidateGrid
+	// Highlights colour whole cells.
+	Highlights []Highlight
+	// CandidateHighlights colour individual pencil marks.
+	CandidateHighlights []CandidateHighlight
+	// Links are drawn as lines between pencil marks.
+	Links []Link
+	// Lines are the line constraints of the puzzle, drawn through the centres
+	// of their cells in a colour for each type.
+	Lines []Line
+	// Edges are the edge clues of the puzzle, drawn on the borders between
+	// their cells in the grid colour.
+	Edges []Edge
+
+	// Colours for each element of the drawing.  Nil values take the
+	// defaults: white background, black grid and givens, blue entered
+	// digits, grey candidates and red links.
+	Background, Grid, Given, Entered, Candidate, LinkColour color.Color
+}
+
+// Default colours for rendering.
+var (
+	defaultBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	defaultGrid       = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	defaultGiven      = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	defaultEntered    = color.RGBA{0x1f, 0x4f, 0xbf, 0xff}
+	defaultCandidate  = color.RGBA{0x55, 0x55, 0x55, 0xff}
+	defaultLink       = color.RGBA{0xcc, 0x22, 0x22, 0xff}
+)
+
+// lineColours are the colours in which each type of Line is drawn.
+var lineColours = map[LineType]color.Color{
+	ThermoLine:     color.RGBA{0xbb, 0xbb, 0xbb, 0xff},
+	ArrowLine:      color.RGBA{0x99, 0x99, 0x99, 0xff},
+	WhisperLine:    color.RGBA{0x44, 0xbb, 0x44, 0xff},
+	RenbanLine:     color.RGBA{0xcc, 0x77, 0xdd, 0xff},
+	PalindromeLine: color.RGBA{0x99, 0x99, 0xcc, 0xff},
+}
+
+// pick returns 'c', or 'def' if 'c' is nil.
+func pick(c, def color.Color) color.Color {
+	if c == nil {
+		return def
+	}
+	return c
+}
+
+// layoutRect is a filled rectangle in a layout.
+type layoutRect struct {
+	x, y, w, h float64
+	fill       color.Color
+}
+
+// layoutLine is a straight line in a layout.
+type layoutLine struct {
+	x1, y1, x2, y2 float64
+	width          float64
+	stroke         color.Color
+	dashed         bool
+}
+
+// layoutCircle is a circle in a layout, centred on (x, y).  Either of its
+// fill and stroke may be nil, to leave it out.
+type layoutCircle struct {
+	x, y, r float64
+	fill    color.Color
+	stroke  color.Color
+	width   float64
+}
+
+// layoutText is a single glyph in a layout, centred on (x, y) and 'size'
+// pixels high.
+type layoutText struct {
+	x, y  float64
+	size  float64
+	glyph byte
+	fill  color.Color
+	bold  bool
+}
+
+// layout is a resolution-independent description of a rendered puzzle,
+// shared by all of the graphical renderers so that they draw the same
+// picture.  Elements are drawn in order: rectangles, then circles, then
+// lines, then marks, then text.
+type layout struct {
+	width, height float64
+	rects         []layoutRect
+	circles       []layoutCircle
+	lines         []layoutLine
+	// markRects and markCircles are drawn over the lines, for the edge clues
+	// which sit on the borders between cells.
+	markRects   []layoutRect
+	markCircles []layoutCircle
+	texts       []layoutText
+}
+
+// cellOrigin returns the position of the top left corner of a cell.
+func cellOrigin(opts *RenderOptions, cell CellRef) (x, y float64) {
+	return float64(opts.Margin + cell.col*opts.CellSize), float64(opts.Margin + cell.row*opts.CellSize)
+}
+
+// candidateCentre returns the position of the centre of a pencil mark.
+// Candidates are arranged within the cell like the keys of a phone keypad.
+func candidateCentre(opts *RenderOptions, ref CandidateRef) (x, y float64) {
+	x, y = cellOrigin(opts, ref.Cell)
+	g := int(ref.Glyph - Glyphs[0])
+	third := float64(opts.CellSize) / SubSize
+	return x + third*(float64(g%SubSize)+0.5), y + third*(float64(g/SubSize)+0.5)
+}
+
+// defaults returns a copy of the options with default values filled in.
+func (opts RenderOptions) defaults() *RenderOptions {
+	if opts.CellSize <= 0 {
+		opts.CellSize = 40
+	}
+	if opts.Margin <= 0 {
+		opts.Margin = opts.CellSize / 2
+	}
+	opts.Background = pick(opts.Background, defaultBackground)
+	opts.Grid = pick(opts.Grid, defaultGrid)
+	opts.Given = pick(opts.Given, defaultGiven)
+	opts.Entered = pick(opts.Entered, defaultEntered)
+	opts.Candidate = pick(opts.Candidate, defaultCandidate)
+	opts.LinkColour = pick(opts.LinkColour, defaultLink)
+	return &opts
+}
+
+// newLayout lays out a puzzle for rendering.
+func newLayout(puz *Puzzle, options *RenderOptions) *layout {
+	if options == nil {
+		options = &RenderOptions{}
+	}
+	opts := options.defaults()
+	cs := float64(opts.CellSize)
+	grid := cs * Size
+	l := &layout{
+		width:  grid + float64(2*opts.Margin),
+		height: grid + float64(2*opts.Margin),
+	}
+	l.rects = append(l.rects, layoutRect{0, 0, l.width, l.height, opts.Background})
+
+	for _, h := range opts.Highlights {
+		x, y := cellOrigin(opts, h.Cell)
+		l.rects = append(l.rects, layoutRect{x, y, cs, cs, h.Colour})
+	}
+	for _, h := range opts.CandidateHighlights {
+		if !Known(h.Candidate.Glyph) {
+			continue
+		}
+		x, y := candidateCentre(opts, h.Candidate)
+		l.rects = append(l.rects, layoutRect{x - cs/6, y - cs/6, cs / 3, cs / 3, h.Colour})
+	}
+
+	// Grid lines, with thick lines around each subgrid.
+	m := float64(opts.Margin)
+	thin, thick := cs/40+0.5, cs/16+1
+	for _, line := range opts.Lines {
+		l.addLine(opts, line, thin)
+	}
+	for i := 0; i <= Size; i++ {
+		if i%SubSize == 0 {
+			continue
+		}
+		p := m + float64(i)*cs
+		l.lines = append(l.lines,
+			layoutLine{p, m, p, m + grid, thin, opts.Grid, false},
+			layoutLine{m, p, m + grid, p, thin, opts.Grid, false})
+	}
+	for i := 0; i <= Size; i += SubSize {
+		p := m + float64(i)*cs
+		l.lines = append(l.lines,
+			layoutLine{p, m - thick/2, p, m + grid + thick/2, thick, opts.Grid, false},
+			layoutLine{m - thick/2, p, m + grid + thick/2, p, thick, opts.Grid, false})
+	}
+	for _, edge := range opts.Edges {
+		l.addEdge(opts, edge, thin)
+	}
+	for _, link := range opts.Links {
+		if !Known(link.From.Glyph) || !Known(link.To.Glyph) {
+			continue
+		}
+		x1, y1 := candidateCentre(opts, link.From)
+		x2, y2 := candidateCentre(opts, link.To)
+		l.lines = append(l.lines, layoutLine{x1, y1, x2, y2, thin * 2, opts.LinkColour, !link.Strong})
+	}
+
+	for i := 0; i < GridSize; i++ {
+		cell := indexToCellRef(i)
+		x, y := cellOrigin(opts, cell)
+		if Known(puz[i]) {
+			given := opts.Givens == nil || opts.Givens[i]
+			fill := opts.Entered
+			if given {
+				fill = opts.Given
+			}
+			l.texts = append(l.texts, layoutText{x + cs/2, y + cs/2, cs * 0.6, puz[i], fill, given})
+			continue
+		}
+		if opts.Candidates == nil {
+			continue
+		}
+		for _, glyph := range opts.Candidates.Get(cell) {
+			cx, cy := candidateCentre(opts, CandidateRef{cell, glyph})
+			l.texts = append(l.texts, layoutText{cx, cy, cs * 0.22, glyph, opts.Candidate, false})
+		}
+	}
+	return l
+}
+
+// cellCentre returns the position of the centre of a cell.
+func cellCentre(opts *RenderOptions, i int) (x, y float64) {
+	x, y = cellOrigin(opts, indexToCellRef(i))
+	half := float64(opts.CellSize) / 2
+	return x + half, y + half
+}
+
+// addLine adds a line constraint to the layout.  Thermometers have a filled
+// bulb, and arrows an empty circle and an arrowhead; the other types are
+// plain lines.
+func (l *layout) addLine(opts *RenderOptions, line Line, thin float64) {
+	cs := float64(opts.CellSize)
+	colour := lineColours[line.Type]
+	width := cs * 0.2
+	path := line.Path
+	switch line.Type {
+	case ThermoLine:
+		width = cs * 0.3
+		x, y := cellCentre(opts, path[0])
+		l.circles = append(l.circles, layoutCircle{x, y, cs * 0.38, colour, nil, 0})
+	case ArrowLine:
+		width = thin * 2
+		r := cs * 0.4
+		x, y := cellCentre(opts, path[0])
+		l.circles = append(l.circles, layoutCircle{x, y, r, nil, colour, width})
+		// Start the shaft at the edge of the circle.
+		nx, ny := cellCentre(opts, path[1])
+		d := math.Hypot(nx-x, ny-y)
+		sx, sy := x+(nx-x)*r/d, y+(ny-y)*r/d
+		l.lines = append(l.lines, layoutLine{sx, sy, nx, ny, width, colour, false})
+		path = path[1:]
+		// The arrowhead points along the last step of the shaft.
+		if len(line.Path) > 2 {
+			x, y = cellCentre(opts, line.Path[len(line.Path)-2])
+		}
+		ex, ey := cellCentre(opts, path[len(path)-1])
+		angle := math.Atan2(ey-y, ex-x)
+		for _, turn := range []float64{-0.8, 0.8} {
+			a := angle + math.Pi + turn
+			head := cs * 0.25
+			l.lines = append(l.lines, layoutLine{ex, ey, ex + head*math.Cos(a), ey + head*math.Sin(a), width, colour, false})
+		}
+	}
+	for k := 1; k < len(path); k++ {
+		x1, y1 := cellCentre(opts, path[k-1])
+		x2, y2 := cellCentre(opts, path[k])
+		l.lines = append(l.lines, layoutLine{x1, y1, x2, y2, width, colour, false})
+		if line.Type != ArrowLine && k > 1 {
+			// Round off the corner, if any, where this step meets the last.
+			l.circles = append(l.circles, layoutCircle{x1, y1, width / 2, colour, nil, 0})
+		}
+	}
+}
+
+// addEdge adds an edge clue to the layout, centred on the border between its
+// cells.  Kropki dots are drawn as circles, X and V as letters, and the
+// inequalities as a chevron pointing at the smaller cell.
+func (l *layout) addEdge(opts *RenderOptions, edge Edge, thin float64) {
+	cs := float64(opts.CellSize)
+	ax, ay := cellCentre(opts, edge.A)
+	bx, by := cellCentre(opts, edge.B)
+	x, y := (ax+bx)/2, (ay+by)/2
+	// The unit vector from the first cell to the second.
+	dx, dy := (bx-ax)/cs, (by-ay)/cs
+	switch edge.Kind {
+	case WhiteDot:
+		l.markCircles = append(l.markCircles, layoutCircle{x, y, cs * 0.12, opts.Background, opts.Grid, thin * 1.5})
+	case BlackDot:
+		l.markCircles = append(l.markCircles, layoutCircle{x, y, cs * 0.12, opts.Grid, nil, 0})
+	case XEdge, VEdge:
+		l.markRects = append(l.markRects, layoutRect{x - cs*0.15, y - cs*0.15, cs * 0.3, cs * 0.3, opts.Background})
+		l.texts = append(l.texts, layoutText{x, y, cs * 0.5, byte(edge.Kind) - 'a' + 'A', opts.Grid, true})
+	case LessThan, GreaterThan:
+		half := cs * 0.12
+		sign := 1.0
+		if edge.Kind == LessThan {
+			sign = -1
+		}
+		tipX, tipY := x+sign*dx*half, y+sign*dy*half
+		for _, side := range []float64{-1, 1} {
+			endX := x - sign*dx*half + side*dy*half*1.5
+			endY := y - sign*dy*half + side*dx*half*1.5
+			l.lines = append(l.lines, layoutLine{tipX, tipY, endX, endY, thin * 2, opts.Grid, false})
+		}
+	}
+}
+
+// newMultiLayout lays out a multi-grid puzzle for rendering.  Only the sizes
+// and colours of the options apply, and every known cell is drawn as a given.
+func newMultiLayout(m *Multi, options *RenderOptions) *layout {
+	if options == nil {
+		options = &RenderOptions{}
+	}
+	opts := options.defaults()
+	cs := float64(opts.CellSize)
+	mg := float64(opts.Margin)
+	rows, cols := m.Dimensions()
+	l := &layout{
+		width:  cs*float64(cols) + 2*mg,
+		height: cs*float64(rows) + 2*mg,
+	}
+	l.rects = append(l.rects, layoutRect{0, 0, l.width, l.height, opts.Background})
+
+	// Grid lines for each grid, with thick lines around each box.  Lines
+	// within a shared box are drawn once for each grid covering it.
+	size := m.Shape.Size()
+	grid := cs * float64(size)
+	thin, thick := cs/40+0.5, cs/16+1
+	var thickLines []layoutLine
+	for _, pos := range m.Layout.Grids {
+		top, left := m.origin(pos)
+		x0, y0 := mg+float64(left)*cs, mg+float64(top)*cs
+		for i := 0; i <= size; i++ {
+			x, y := x0+float64(i)*cs, y0+float64(i)*cs
+			if i%m.Shape.BoxWidth != 0 {
+				l.lines = append(l.lines, layoutLine{x, y0, x, y0 + grid, thin, opts.Grid, false})
+			} else {
+				thickLines = append(thickLines, layoutLine{x, y0 - thick/2, x, y0 + grid + thick/2, thick, opts.Grid, false})
+			}
+			if i%m.Shape.BoxHeight != 0 {
+				l.lines = append(l.lines, layoutLine{x0, y, x0 + grid, y, thin, opts.Grid, false})
+			} else {
+				thickLines = append(thickLines, layoutLine{x0 - thick/2, y, x0 + grid + thick/2, y, thick, opts.Grid, false})
+			}
+		}
+	}
+	l.lines = append(l.lines, thickLines...)
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			i := m.board[r*cols+c]
+			if i < 0 || !m.Shape.Known(m.Cells[i]) {
+				continue
+			}
+			x, y := mg+(float64(c)+0.5)*cs, mg+(float64(r)+0.5)*cs
+			l.texts = append(l.texts, layoutText{x, y, cs * 0.6, m.Cells[i], opts.Given, true})
+		}
+	}
+	return l
+}
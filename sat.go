@@ -0,0 +1,366 @@
+package sudoku
+
+import "context"
+
+// cellSet is a set of grid indexes, used to record which of the puzzle's
+// givens a clause or an assignment depends upon.
+type cellSet [(GridSize + 63) / 64]uint64
+
+// add inserts a grid index into the set.
+func (s *cellSet) add(index int) {
+	s[index/64] |= 1 << uint(index%64)
+}
+
+// union inserts all the members of 'other' into the set.
+func (s *cellSet) union(other cellSet) {
+	for i := range s {
+		s[i] |= other[i]
+	}
+}
+
+// cells returns the members of the set as CellRefs, in grid order.
+func (s *cellSet) cells() (refs []CellRef) {
+	for i := 0; i < GridSize; i++ {
+		if s[i/64]&(1<<uint(i%64)) != 0 {
+			refs = append(refs, indexToCellRef(i))
+		}
+	}
+	return
+}
+
+// satClause is a disjunction of DIMACS literals held by the SAT solver.
+//
+// While the clause is being watched, its first two literals are the watched
+// literals.  'deps' records the givens that the clause was derived from; it is
+// empty for the rules of sudoku, and non-empty for learnt clauses.
+type satClause struct {
+	lits []int
+	deps cellSet
+}
+
+// satSolver is a conflict-driven clause learning (CDCL) solver specialised
+// for the sudoku encoding produced by Clauses.
+//
+// Givens are not stored as clauses.  They are assigned directly at decision
+// level zero, and the solver tracks which givens each level zero assignment
+// and each learnt clause depends upon, so that when the puzzle is found to be
+// unsatisfiable it can report the givens responsible.
+type satSolver struct {
+	value    [NumVariables + 1]int8
+	level    [NumVariables + 1]int
+	reason   [NumVariables + 1]*satClause
+	zeroDeps [NumVariables + 1]cellSet
+	watches  [2 * (NumVariables + 1)][]*satClause
+	trail    []int
+	limits   []int
+	head     int
+}
+
+// watchIndex returns the index into the watch lists for a literal.
+func watchIndex(lit int) int {
+	if lit < 0 {
+		return -lit*2 + 1
+	}
+	return lit * 2
+}
+
+// abs returns the variable of a literal.
+func abs(lit int) int {
+	if lit < 0 {
+		return -lit
+	}
+	return lit
+}
+
+// newSATSolver returns a solver loaded with the rules of sudoku.
+func newSATSolver() *satSolver {
+	s := &satSolver{}
+	for _, lits := range ruleClauses() {
+		s.watch(&satClause{lits: lits})
+	}
+	return s
+}
+
+// watch adds a clause of at least two literals to the watch lists.
+func (s *satSolver) watch(c *satClause) {
+	s.watches[watchIndex(c.lits[0])] = append(s.watches[watchIndex(c.lits[0])], c)
+	s.watches[watchIndex(c.lits[1])] = append(s.watches[watchIndex(c.lits[1])], c)
+}
+
+// litValue returns 1 if the literal is true, -1 if it is false and 0 if it is
+// unassigned.
+func (s *satSolver) litValue(lit int) int8 {
+	if lit < 0 {
+		return -s.value[-lit]
+	}
+	return s.value[lit]
+}
+
+// decisionLevel returns the current decision level.
+func (s *satSolver) decisionLevel() int {
+	return len(s.limits)
+}
+
+// assign makes a literal true at the current decision level.
+//
+// At level zero, the givens that the assignment depends upon are recorded: the
+// dependencies of the reason clause, plus those of each of the other literals
+// in the reason, which must all have been made false at level zero as well.
+func (s *satSolver) assign(lit int, reason *satClause) {
+	v := abs(lit)
+	if lit < 0 {
+		s.value[v] = -1
+	} else {
+		s.value[v] = 1
+	}
+	s.level[v] = s.decisionLevel()
+	s.reason[v] = reason
+	if s.level[v] == 0 && reason != nil {
+		deps := reason.deps
+		for _, other := range reason.lits {
+			if other != lit {
+				deps.union(s.zeroDeps[abs(other)])
+			}
+		}
+		s.zeroDeps[v] = deps
+	}
+	s.trail = append(s.trail, lit)
+}
+
+// given assigns the literal for a puzzle given at level zero.  It returns
+// false, along with the givens involved, if the literal is already false.
+func (s *satSolver) given(index int, lit int) (ok bool, deps cellSet) {
+	switch s.litValue(lit) {
+	case 1:
+		return true, deps
+	case -1:
+		deps = s.zeroDeps[abs(lit)]
+		deps.add(index)
+		return false, deps
+	}
+	s.assign(lit, nil)
+	s.zeroDeps[abs(lit)].add(index)
+	return true, deps
+}
+
+// propagate performs unit propagation on all assignments not yet processed,
+// and returns a conflicting clause, or nil if no conflict arose.
+func (s *satSolver) propagate() *satClause {
+	for s.head < len(s.trail) {
+		falsified := -s.trail[s.head]
+		s.head++
+		wi := watchIndex(falsified)
+		ws := s.watches[wi]
+		kept := ws[:0]
+		for i, c := range ws {
+			// Make sure the falsified literal is in the second position.
+			if c.lits[0] == falsified {
+				c.lits[0], c.lits[1] = c.lits[1], c.lits[0]
+			}
+			if s.litValue(c.lits[0]) == 1 {
+				kept = append(kept, c)
+				continue
+			}
+			// Look for a new literal to watch.
+			moved := false
+			for k := 2; k < len(c.lits); k++ {
+				if s.litValue(c.lits[k]) != -1 {
+					c.lits[1], c.lits[k] = c.lits[k], c.lits[1]
+					s.watches[watchIndex(c.lits[1])] = append(s.watches[watchIndex(c.lits[1])], c)
+					moved = true
+					break
+				}
+			}
+			if moved {
+				continue
+			}
+			kept = append(kept, c)
+			if s.litValue(c.lits[0]) == -1 {
+				kept = append(kept, ws[i+1:]...)
+				s.watches[wi] = kept
+				return c
+			}
+			s.assign(c.lits[0], c)
+		}
+		s.watches[wi] = kept
+	}
+	return nil
+}
+
+// analyse derives a learnt clause from a conflict, using the first unique
+// implication point, and returns it with the level to backtrack to.
+//
+// The learnt clause's dependencies are the union of the dependencies of every
+// clause resolved upon, and those of every level zero literal dropped from it.
+func (s *satSolver) analyse(confl *satClause) (learnt *satClause, back int) {
+	var seen [NumVariables + 1]bool
+	learnt = &satClause{lits: []int{0}}
+	pending := 0
+	lit := 0
+	i := len(s.trail) - 1
+	for {
+		learnt.deps.union(confl.deps)
+		for _, q := range confl.lits {
+			if q == lit {
+				continue
+			}
+			v := abs(q)
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+			switch {
+			case s.level[v] == 0:
+				learnt.deps.union(s.zeroDeps[v])
+			case s.level[v] == s.decisionLevel():
+				pending++
+			default:
+				learnt.lits = append(learnt.lits, q)
+			}
+		}
+		// Walk back along the trail to the next literal involved.
+		for !seen[abs(s.trail[i])] || s.level[abs(s.trail[i])] != s.decisionLevel() {
+			i--
+		}
+		lit = s.trail[i]
+		i--
+		pending--
+		if pending == 0 {
+			break
+		}
+		confl = s.reason[abs(lit)]
+	}
+	learnt.lits[0] = -lit
+	// Put the literal with the highest level in the second position, so that
+	// it is watched after backtracking.
+	for j := 1; j < len(learnt.lits); j++ {
+		if s.level[abs(learnt.lits[j])] > back {
+			back = s.level[abs(learnt.lits[j])]
+			learnt.lits[1], learnt.lits[j] = learnt.lits[j], learnt.lits[1]
+		}
+	}
+	return
+}
+
+// backtrack undoes all assignments above the given decision level.
+func (s *satSolver) backtrack(level int) {
+	if s.decisionLevel() <= level {
+		return
+	}
+	start := s.limits[level]
+	for _, lit := range s.trail[start:] {
+		v := abs(lit)
+		s.value[v] = 0
+		s.reason[v] = nil
+	}
+	s.trail = s.trail[:start]
+	s.limits = s.limits[:level]
+	s.head = start
+}
+
+// decide chooses the next literal to assign.
+//
+// It picks the first possible glyph of the unsolved cell having the fewest
+// glyphs remaining, and returns zero if every cell is solved.
+func (s *satSolver) decide() int {
+	best, bestCount := 0, Size+1
+	for i := 0; i < GridSize; i++ {
+		count, first, solved := 0, 0, false
+		for g := 0; g < Size; g++ {
+			v := i*Size + g + 1
+			switch s.value[v] {
+			case 1:
+				solved = true
+			case 0:
+				if first == 0 {
+					first = v
+				}
+				count++
+			}
+		}
+		if !solved && count > 0 && count < bestCount {
+			best, bestCount = first, count
+		}
+	}
+	return best
+}
+
+// solve runs the search to completion.  It returns true if a solution was
+// found, or false along with the givens responsible if the puzzle has no
+// solution.
+func (s *satSolver) solve(ctx context.Context, puz *Puzzle) (ok bool, deps cellSet, err error) {
+	for i := 0; i < GridSize; i++ {
+		if Known(puz[i]) {
+			if ok, deps = s.given(i, Variable(indexToCellRef(i), puz[i])); !ok {
+				return
+			}
+		}
+	}
+	for n := 0; ; n++ {
+		if n%64 == 0 {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+		}
+		confl := s.propagate()
+		if confl != nil {
+			if s.decisionLevel() == 0 {
+				deps = confl.deps
+				for _, lit := range confl.lits {
+					deps.union(s.zeroDeps[abs(lit)])
+				}
+				return false, deps, nil
+			}
+			learnt, back := s.analyse(confl)
+			s.backtrack(back)
+			if len(learnt.lits) == 1 {
+				s.assign(learnt.lits[0], learnt)
+			} else {
+				s.watch(learnt)
+				s.assign(learnt.lits[0], learnt)
+			}
+			continue
+		}
+		lit := s.decide()
+		if lit == 0 {
+			return true, deps, nil
+		}
+		s.limits = append(s.limits, len(s.trail))
+		s.assign(lit, nil)
+	}
+}
+
+// SolveSAT attempts to solve a sudoku puzzle using a conflict-driven clause
+// learning SAT solver, as an alternative to the guesswork of Solve.
+//
+// If a solution is found, it is written into the puzzle and SolveSAT returns
+// zero.  Otherwise the puzzle is not modified, and SolveSAT returns the number
+// of unknown cells along with a set of givens which together cannot be part of
+// any solution.  The set is sorted in grid order, and is usually much smaller
+// than the full set of givens, although it is not guaranteed to be minimal.
+func (puz *Puzzle) SolveSAT() (remain int, conflict []CellRef) {
+	remain, conflict, _ = puz.SolveSATContext(context.Background())
+	return
+}
+
+// SolveSATContext is like SolveSAT, but abandons the search when the context
+// is cancelled or its deadline expires.
+//
+// In that case it returns ctx.Err(), and the puzzle is not modified.
+func (puz *Puzzle) SolveSATContext(ctx context.Context) (remain int, conflict []CellRef, err error) {
+	s := newSATSolver()
+	ok, deps, err := s.solve(ctx, puz)
+	if err != nil || !ok {
+		if err == nil {
+			conflict = deps.cells()
+		}
+		return puz.NumUnknowns(), conflict, err
+	}
+	for _, lit := range s.trail {
+		if lit > 0 {
+			index, glyph := variableCell(lit)
+			puz[index] = glyph
+		}
+	}
+	return 0, nil, nil
+}
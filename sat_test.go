@@ -0,0 +1,129 @@
+package sudoku
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSolveSAT(t *testing.T) {
+	// “AI Etana”, by Arto Inkala
+	puz := Puzzle{
+		'1', ' ', ' ', ' ', ' ', '7', ' ', '9', ' ',
+		' ', '3', ' ', ' ', '2', ' ', ' ', ' ', '8',
+		' ', ' ', '9', '6', ' ', ' ', '5', ' ', ' ',
+		' ', ' ', '5', '3', ' ', ' ', '9', ' ', ' ',
+		' ', '1', ' ', ' ', '8', ' ', ' ', ' ', '2',
+		'6', ' ', ' ', ' ', ' ', '4', ' ', ' ', ' ',
+		'3', ' ', ' ', ' ', ' ', ' ', ' ', '1', ' ',
+		' ', '4', ' ', ' ', ' ', ' ', ' ', ' ', '7',
+		' ', ' ', '7', ' ', ' ', ' ', '3', ' ', ' '}
+	expect := Puzzle{
+		'1', '6', '2', '8', '5', '7', '4', '9', '3',
+		'5', '3', '4', '1', '2', '9', '6', '7', '8',
+		'7', '8', '9', '6', '4', '3', '5', '2', '1',
+		'4', '7', '5', '3', '1', '2', '9', '8', '6',
+		'9', '1', '3', '5', '8', '6', '7', '4', '2',
+		'6', '2', '8', '7', '9', '4', '1', '3', '5',
+		'3', '5', '6', '4', '7', '8', '2', '1', '9',
+		'2', '4', '1', '9', '3', '5', '8', '6', '7',
+		'8', '9', '7', '2', '6', '1', '3', '5', '4'}
+	remain, conflict := puz.SolveSAT()
+	if remain != 0 || conflict != nil {
+		t.Errorf("incorrect return from SolveSAT: expected 0, nil, got %v, %v", remain, conflict)
+	}
+	if !puz.Equal(expect) {
+		t.Errorf("incorrect puzzle solution: expected:\n%v\n\ngot:\n%v", expect.String(), puz.String())
+	}
+
+	// Blank puzzle, any valid solution will do
+	puz.Clear()
+	remain, _ = puz.SolveSAT()
+	if remain != 0 || puz.Validate() != nil {
+		t.Errorf("invalid solution from SolveSAT for blank puzzle:\n%v", puz.String())
+	}
+}
+
+func TestSolveSATConflict(t *testing.T) {
+	// Duplicate givens in column 4
+	puz := Puzzle{
+		' ', ' ', ' ', '5', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', '2', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', '1', ' ', ' ',
+		' ', ' ', ' ', '5', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '}
+	orig := puz
+	remain, conflict := puz.SolveSAT()
+	if remain != orig.NumUnknowns() {
+		t.Errorf("incorrect return from SolveSAT: expected %v unknowns, got %v", orig.NumUnknowns(), remain)
+	}
+	if !puz.Equal(orig) {
+		t.Errorf("puzzle modified by unsuccessful SolveSAT:\n%v", puz.String())
+	}
+	expect := []CellRef{{0, 3}, {7, 3}}
+	if !equalRefs(conflict, expect) {
+		t.Errorf("incorrect conflict from SolveSAT: expected %v, got %v", expect, conflict)
+	}
+
+	// No duplicates, but nothing can go in R1C9: 1-8 are in row 1 and 9 is
+	// in column 9.
+	puz = Puzzle{
+		'1', '2', '3', '4', '5', '6', '7', '8', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', '3', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', '9',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '}
+	_, conflict = puz.SolveSAT()
+	expect = []CellRef{
+		{0, 0}, {0, 1}, {0, 2}, {0, 3}, {0, 4}, {0, 5}, {0, 6}, {0, 7},
+		{6, 8}}
+	if !equalRefs(conflict, expect) {
+		t.Errorf("incorrect conflict from SolveSAT: expected %v, got %v", expect, conflict)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, conflict, err := puz.SolveSATContext(ctx)
+	if err != context.Canceled || conflict != nil {
+		t.Errorf("incorrect return from cancelled SolveSATContext: expected nil, %v, got %v, %v", context.Canceled, conflict, err)
+	}
+}
+
+// equalRefs returns whether two slices contain the same CellRefs in the same
+// order.
+func equalRefs(a, b []CellRef) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func BenchmarkSolveSAT(b *testing.B) {
+	// “AI Etana”, by Arto Inkala
+	puz := Puzzle{
+		'1', ' ', ' ', ' ', ' ', '7', ' ', '9', ' ',
+		' ', '3', ' ', ' ', '2', ' ', ' ', ' ', '8',
+		' ', ' ', '9', '6', ' ', ' ', '5', ' ', ' ',
+		' ', ' ', '5', '3', ' ', ' ', '9', ' ', ' ',
+		' ', '1', ' ', ' ', '8', ' ', ' ', ' ', '2',
+		'6', ' ', ' ', ' ', ' ', '4', ' ', ' ', ' ',
+		'3', ' ', ' ', ' ', ' ', ' ', ' ', '1', ' ',
+		' ', '4', ' ', ' ', ' ', ' ', ' ', ' ', '7',
+		' ', ' ', '7', ' ', ' ', ' ', '3', ' ', ' '}
+	for i := 0; i < b.N; i++ {
+		test := puz
+		test.SolveSAT()
+	}
+}
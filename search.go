@@ -0,0 +1,382 @@
+package sudoku
+
+import (
+	"context"
+	"math/bits"
+	"math/rand"
+	"sync"
+)
+
+// searcher solves grids of one shape by constraint propagation and
+// backtracking search.
+//
+// Each cell's candidates are held as a bit mask, with bit 'n' set if the
+// shape's glyph 'n' is still possible.  Assigning a glyph to a cell removes it
+// from the cell's peers, and whenever a cell is left with one candidate, or a
+// glyph is left with one place in a complete unit, that follows in turn.
+// Removing a candidate from a cell also removes those it leaves unsupported in
+// the cells related to it by a Relation.  Other constraints then propagate
+// their own eliminations.
+type searcher struct {
+	shape Shape
+	// units lists the cells of each complete unit, which holds every glyph,
+	// and cellUnits the complete units containing each cell.
+	units     [][]int
+	cellUnits [][]int
+	// peers lists the cells which may not hold the same glyph as each cell:
+	// those sharing any unit with it, or related to it by a distinct
+	// Relation.
+	peers [][]int
+	// arcs lists the cells related to each cell by a Relation which is not
+	// distinct.
+	arcs [][]arc
+	// others are the remaining constraints, and otherCells the cells of
+	// each, which are looked up once rather than on every pass.
+	others     []Constraint
+	otherCells [][]int
+	full       uint64
+}
+
+// arc links a cell to another cell related to it.  Glyph 'm' of the other
+// cell is possible only while the first cell has a candidate in support[m].
+type arc struct {
+	cell    int
+	support []uint64
+}
+
+// searchers caches a searcher for each shape that has been used with the
+// default constraints.
+var searchers sync.Map
+
+// newSearcher returns a searcher for grids of the given shape and
+// constraints, or the default constraints if 'constraints' is nil.  Searchers
+// are not modified by searching, so those for the default constraints are
+// shared between callers.
+func newSearcher(shape Shape, constraints []Constraint) *searcher {
+	if constraints == nil {
+		if s, ok := searchers.Load(shape); ok {
+			return s.(*searcher)
+		}
+	}
+	cs := constraints
+	if cs == nil {
+		cs = DefaultConstraints(shape)
+	}
+	// The constraints of a Multi refer to more cells than one grid holds.
+	n := shape.NumCells()
+	for _, c := range cs {
+		for _, i := range c.Cells() {
+			if i >= n {
+				n = i + 1
+			}
+		}
+	}
+	s := &searcher{
+		shape:     shape,
+		cellUnits: make([][]int, n),
+		peers:     make([][]int, n),
+		arcs:      make([][]arc, n),
+		full:      1<<uint(shape.Size()) - 1,
+	}
+	peers := make([]map[int]bool, n)
+	for i := range peers {
+		peers[i] = map[int]bool{i: true}
+	}
+	addPeers := func(i, j int) {
+		if !peers[i][j] {
+			peers[i][j] = true
+			s.peers[i] = append(s.peers[i], j)
+		}
+	}
+	for _, c := range cs {
+		if r, ok := c.(Relation); ok {
+			if r.distinct() {
+				for _, p := range r.Pairs {
+					addPeers(p[0], p[1])
+					addPeers(p[1], p[0])
+				}
+				continue
+			}
+			// The glyphs of the first cell of a pair which allow each glyph
+			// of the second.
+			reverse := make([]uint64, len(r.Allowed))
+			for a, allowed := range r.Allowed {
+				for b := range reverse {
+					if allowed&(1<<uint(b)) != 0 {
+						reverse[b] |= 1 << uint(a)
+					}
+				}
+			}
+			for _, p := range r.Pairs {
+				s.arcs[p[0]] = append(s.arcs[p[0]], arc{p[1], reverse})
+				s.arcs[p[1]] = append(s.arcs[p[1]], arc{p[0], r.Allowed})
+			}
+			continue
+		}
+		u, ok := c.(Unit)
+		if !ok {
+			s.others = append(s.others, c)
+			s.otherCells = append(s.otherCells, c.Cells())
+			continue
+		}
+		for _, i := range u.Members {
+			for _, j := range u.Members {
+				addPeers(i, j)
+			}
+		}
+		if len(u.Members) == shape.Size() {
+			for _, i := range u.Members {
+				s.cellUnits[i] = append(s.cellUnits[i], len(s.units))
+			}
+			s.units = append(s.units, u.Members)
+		}
+	}
+	if constraints == nil {
+		searchers.Store(shape, s)
+	}
+	return s
+}
+
+// start returns the candidates for a grid after assigning its known cells, or
+// nil if they contradict one another.
+func (s *searcher) start(g *Grid) []uint64 {
+	cand := make([]uint64, len(g.Cells))
+	for i := range cand {
+		cand[i] = s.full
+	}
+	for i := range s.arcs {
+		if !s.revise(cand, i) {
+			return nil
+		}
+	}
+	for i, glyph := range g.Cells {
+		if n := s.shape.glyphIndex(glyph); n >= 0 {
+			if !s.assign(cand, i, 1<<uint(n)) {
+				return nil
+			}
+		}
+	}
+	if !s.propagate(cand) {
+		return nil
+	}
+	return cand
+}
+
+// propagate applies the constraints other than units until they make no
+// further eliminations, and returns false if that leads to a contradiction.
+// Each elimination is made through eliminate, so that it carries over to the
+// units in turn.
+func (s *searcher) propagate(cand []uint64) bool {
+	for changed := true; changed; {
+		changed = false
+		for j, c := range s.others {
+			cells := s.otherCells[j]
+			before := make([]uint64, len(cells))
+			for k, i := range cells {
+				before[k] = cand[i]
+			}
+			ok := c.Propagate(cand)
+			after := make([]uint64, len(cells))
+			for k, i := range cells {
+				after[k], cand[i] = cand[i], before[k]
+			}
+			if !ok {
+				return false
+			}
+			for k, i := range cells {
+				for removed := before[k] &^ after[k]; removed != 0; {
+					b := removed & -removed
+					removed &^= b
+					changed = true
+					if !s.eliminate(cand, i, b) {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// assign reduces a cell's candidates to the single glyph in 'bit', and
+// returns false if that leads to a contradiction.
+func (s *searcher) assign(cand []uint64, cell int, bit uint64) bool {
+	others := cand[cell] &^ bit
+	for others != 0 {
+		b := others & -others
+		others &^= b
+		if !s.eliminate(cand, cell, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// eliminate removes the glyph in 'bit' from a cell's candidates, and returns
+// false if that leads to a contradiction.
+func (s *searcher) eliminate(cand []uint64, cell int, bit uint64) bool {
+	if cand[cell]&bit == 0 {
+		return true
+	}
+	cand[cell] &^= bit
+	switch bits.OnesCount64(cand[cell]) {
+	case 0:
+		return false
+	case 1:
+		// The cell is solved, so its glyph is ruled out of its peers.
+		for _, p := range s.peers[cell] {
+			if !s.eliminate(cand, p, cand[cell]) {
+				return false
+			}
+		}
+	}
+	if !s.revise(cand, cell) {
+		return false
+	}
+	// If the glyph has only one place left in a unit, it must go there.
+	for _, u := range s.cellUnits[cell] {
+		place, count := -1, 0
+		for _, j := range s.units[u] {
+			if cand[j]&bit != 0 {
+				place = j
+				count++
+			}
+		}
+		if count == 0 {
+			return false
+		}
+		if count == 1 && cand[place] != bit {
+			if !s.assign(cand, place, bit) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// revise removes the candidates of the cells related to a cell which none of
+// its own candidates allow, and returns false if that leads to a
+// contradiction.
+func (s *searcher) revise(cand []uint64, cell int) bool {
+	for _, a := range s.arcs[cell] {
+		for c := cand[a.cell]; c != 0; c &= c - 1 {
+			if b := c & -c; a.support[bits.TrailingZeros64(b)]&cand[cell] == 0 {
+				if !s.eliminate(cand, a.cell, b) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// search visits the solutions reachable from the candidates, until 'visit'
+// returns true to stop the search.  Glyphs are tried in order, or in a random
+// order if 'random' is not nil.  It returns whether the search was stopped,
+// or ctx.Err() if the context is cancelled.
+func (s *searcher) search(ctx context.Context, cand []uint64, random *rand.Rand, visit func([]uint64) bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	// Branch on the unsolved cell with the fewest candidates.
+	best, bestCount := -1, 0
+	for i, c := range cand {
+		if n := bits.OnesCount64(c); n > 1 && (best < 0 || n < bestCount) {
+			best, bestCount = i, n
+		}
+	}
+	if best < 0 {
+		return visit(cand), nil
+	}
+	options := make([]uint64, 0, bestCount)
+	for c := cand[best]; c != 0; c &= c - 1 {
+		options = append(options, c&-c)
+	}
+	if random != nil {
+		random.Shuffle(len(options), func(i, j int) {
+			options[i], options[j] = options[j], options[i]
+		})
+	}
+	for _, bit := range options {
+		next := append([]uint64(nil), cand...)
+		if !s.assign(next, best, bit) || !s.propagate(next) {
+			continue
+		}
+		stop, err := s.search(ctx, next, random, visit)
+		if stop || err != nil {
+			return stop, err
+		}
+	}
+	return false, nil
+}
+
+// fill writes solved candidates into the grid.
+func (s *searcher) fill(g *Grid, cand []uint64) {
+	for i, c := range cand {
+		g.Cells[i] = s.shape.Glyphs[bits.TrailingZeros64(c)]
+	}
+}
+
+// Solve attempts to solve the grid, and returns the number of unknown cells
+// remaining.
+//
+// If the grid has a solution, it is written into the grid and Solve returns
+// zero; if it has several, the first one found is used.  Otherwise the grid
+// is not modified.
+func (g *Grid) Solve() (remain int) {
+	remain, _ = g.SolveContext(context.Background())
+	return
+}
+
+// SolveContext is like Solve, but abandons the search when the context is
+// cancelled or its deadline expires.
+//
+// In that case it returns ctx.Err(), and the grid is not modified.
+func (g *Grid) SolveContext(ctx context.Context) (remain int, err error) {
+	s := newSearcher(g.Shape, g.Constraints)
+	cand := s.start(g)
+	if cand == nil {
+		return g.NumUnknowns(), nil
+	}
+	var solution []uint64
+	_, err = s.search(ctx, cand, nil, func(c []uint64) bool {
+		solution = c
+		return true
+	})
+	if solution == nil {
+		return g.NumUnknowns(), err
+	}
+	s.fill(g, solution)
+	return 0, nil
+}
+
+// NumSolutions returns the number of solutions to the grid, or two if it has
+// more than one.  The grid is not modified.
+func (g *Grid) NumSolutions() int {
+	n, _ := g.NumSolutionsContext(context.Background())
+	return n
+}
+
+// NumSolutionsContext is like NumSolutions, but abandons the count when the
+// context is cancelled or its deadline expires.
+//
+// In that case it returns zero and ctx.Err().
+func (g *Grid) NumSolutionsContext(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s := newSearcher(g.Shape, g.Constraints)
+	cand := s.start(g)
+	if cand == nil {
+		return 0, nil
+	}
+	count := 0
+	_, err := s.search(ctx, cand, nil, func([]uint64) bool {
+		count++
+		return count > 1
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
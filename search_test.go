@@ -0,0 +1,79 @@
+package sudoku
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGridSolve(t *testing.T) {
+	cases := []struct {
+		size          int
+		input, expect string
+	}{
+		{4, ".3.4........41.3", "2314143232414123"},
+		{6, "6.51.......3.....2.6..5.41..........", "635124142563351642264351416235523416"},
+		{9, "..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4..",
+			"718936254294578631653412987145623798829741365367859142436287519571394826982165473"},
+	}
+	for _, c := range cases {
+		shape, _ := ShapeForSize(c.size)
+		g := NewGrid(shape)
+		if err := g.ReadLine([]byte(c.input)); err != nil {
+			t.Fatalf("ReadLine failed: %v", err)
+		}
+		remain := g.Solve()
+		if remain != 0 || g.Validate() != nil {
+			t.Errorf("incorrect result from Solve for %v: %v remaining, got\n%v", shape, remain, g.String())
+		}
+		if c.expect != "" && g.Line() != c.expect {
+			t.Errorf("incorrect result from Solve for %v: expected %q, got %q", shape, c.expect, g.Line())
+		}
+	}
+
+	// No solution: the grid is left unchanged.
+	shape, _ := ShapeForSize(4)
+	g := NewGrid(shape)
+	g.ReadLine([]byte("12.....3..3....."))
+	before := g.Line()
+	if remain := g.Solve(); remain != 12 || g.Line() != before {
+		t.Errorf("incorrect result from Solve for unsolvable grid: expected 12 remaining and %q, got %v and %q", before, remain, g.Line())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.Clear()
+	if remain, err := g.SolveContext(ctx); err != context.Canceled || remain != 16 {
+		t.Errorf("incorrect result from cancelled SolveContext: expected 16, %v, got %v, %v", context.Canceled, remain, err)
+	}
+}
+
+func TestGridNumSolutions(t *testing.T) {
+	shape, _ := ShapeForSize(4)
+	cases := []struct {
+		input  string
+		expect int
+	}{
+		{".3.4........41.3", 1},
+		{"12.....3..3.....", 0},
+		{"................", 2},
+		{"11..............", 0},
+	}
+	for _, c := range cases {
+		g := NewGrid(shape)
+		g.ReadLine([]byte(c.input))
+		if n := g.NumSolutions(); n != c.expect {
+			t.Errorf("incorrect result from NumSolutions for %q: expected %v, got %v", c.input, c.expect, n)
+		}
+		if g.Line() != c.input {
+			t.Errorf("grid modified by NumSolutions: expected %q, got %q", c.input, g.Line())
+		}
+	}
+}
+
+func BenchmarkGridSolve(b *testing.B) {
+	var puz Puzzle
+	puz.ReadLine([]byte("..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4.."))
+	for i := 0; i < b.N; i++ {
+		puz.Grid().Solve()
+	}
+}
@@ -0,0 +1,184 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shape describes the dimensions of a sudoku grid: the size of the boxes into
+// which it is divided, and the glyphs that fill its cells.
+//
+// A grid has as many rows and columns as each box has cells, so for example a
+// shape with boxes 3 columns wide and 2 rows high describes a 6×6 grid made of
+// six boxes.  In a jigsaw grid the boxes are replaced by irregular regions,
+// given by Regions; the size of the grid is still BoxWidth×BoxHeight.
+type Shape struct {
+	// BoxWidth and BoxHeight are the number of columns and rows in each box.
+	BoxWidth, BoxHeight int
+	// Glyphs lists the glyphs which may fill a cell, in order.  There is one
+	// glyph for each row of the grid.
+	Glyphs string
+	// Regions, if not empty, replaces the boxes with irregular regions.
+	Regions RegionMap
+}
+
+// Classic is the shape of the standard 9×9 puzzle, as represented by Puzzle.
+var Classic = Shape{SubSize, SubSize, string(Glyphs[:]), ""}
+
+// MaxSize is the largest number of rows and columns supported in a grid.
+const MaxSize = 36
+
+// DefaultGlyphs returns the conventional glyphs for a grid with 'size' rows
+// and columns: the digits from '1' for grids up to 9×9, the hexadecimal
+// digits '0' to 'F' for 16×16, the letters 'A' to 'Y' for 25×25, and
+// otherwise the digits from '1' followed by the letters from 'A'.
+func DefaultGlyphs(size int) string {
+	switch size {
+	case 16:
+		return "0123456789ABCDEF"
+	case 25:
+		return "ABCDEFGHIJKLMNOPQRSTUVWXY"
+	}
+	const all = "123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ0"
+	if size > len(all) {
+		size = len(all)
+	}
+	return all[:size]
+}
+
+// NewShape returns the shape for boxes of the given width and height, using
+// DefaultGlyphs.
+func NewShape(boxWidth, boxHeight int) (Shape, error) {
+	s := Shape{BoxWidth: boxWidth, BoxHeight: boxHeight}
+	if boxWidth > 0 && boxHeight > 0 {
+		s.Glyphs = DefaultGlyphs(boxWidth * boxHeight)
+	}
+	return s, s.Validate()
+}
+
+// ShapeForSize returns the conventional shape for a grid with 'size' rows and
+// columns.  The boxes are square where possible, and otherwise as close to
+// square as they can be, with more columns than rows; so 6×6 grids have boxes
+// three columns wide and two rows high.  Sizes which are prime have no such
+// shape.
+func ShapeForSize(size int) (Shape, error) {
+	if size == Size {
+		return Classic, nil
+	}
+	height := 1
+	for h := 2; h*h <= size; h++ {
+		if size%h == 0 {
+			height = h
+		}
+	}
+	if height == 1 {
+		return Shape{}, fmt.Errorf("no box shape for a grid of size %v", size)
+	}
+	return NewShape(size/height, height)
+}
+
+// Validate checks that the shape can be used for a grid.
+func (s Shape) Validate() error {
+	size := s.Size()
+	switch {
+	case s.BoxWidth < 1 || s.BoxHeight < 1:
+		return fmt.Errorf("invalid box dimensions %v×%v", s.BoxWidth, s.BoxHeight)
+	case size < 2 || size > MaxSize:
+		return fmt.Errorf("invalid grid size %v: must be between 2 and %v", size, MaxSize)
+	case len(s.Glyphs) != size:
+		return fmt.Errorf("expected %v glyphs, got %v", size, len(s.Glyphs))
+	}
+	for i := 0; i < size; i++ {
+		g := s.Glyphs[i]
+		if g <= ' ' || g > '~' || g == '.' || g == '_' || g == '#' {
+			return fmt.Errorf("invalid glyph %q", g)
+		}
+		if strings.IndexByte(s.Glyphs[:i], g) >= 0 {
+			return fmt.Errorf("duplicate glyph %q", g)
+		}
+	}
+	if s.Regions != "" {
+		return s.validateRegions()
+	}
+	return nil
+}
+
+// String returns a description of the shape, e.g. "6×6 (3×2 boxes)" or
+// "9×9 jigsaw".
+func (s Shape) String() string {
+	if s.Regions != "" {
+		return fmt.Sprintf("%v×%v jigsaw", s.Size(), s.Size())
+	}
+	return fmt.Sprintf("%v×%v (%v×%v boxes)", s.Size(), s.Size(), s.BoxWidth, s.BoxHeight)
+}
+
+// Size returns the number of rows and columns in the grid.
+func (s Shape) Size() int {
+	return s.BoxWidth * s.BoxHeight
+}
+
+// NumCells returns the total number of cells in the grid.
+func (s Shape) NumCells() int {
+	return s.Size() * s.Size()
+}
+
+// Known returns whether the given glyph indicates a known value in this
+// shape.
+func (s Shape) Known(glyph byte) bool {
+	return s.glyphIndex(glyph) >= 0
+}
+
+// glyphIndex returns the position of a glyph within the shape's glyphs, or
+// -1 if it is not one of them.
+func (s Shape) glyphIndex(glyph byte) int {
+	if glyph == Unknown || glyph == Null {
+		return -1
+	}
+	return strings.IndexByte(s.Glyphs, glyph)
+}
+
+// blank returns whether a byte of input indicates an unknown cell in the line
+// format: a full stop, or a zero if zero is not one of the shape's glyphs.
+func (s Shape) blank(b byte) bool {
+	return b == '.' || (b == '0' && !s.Known('0'))
+}
+
+// describe returns a description of the glyphs for use in error messages.
+// If 'zero' is true, the description includes zero, for blanks.
+func (s Shape) describe(zero bool) string {
+	if strings.HasPrefix("123456789", s.Glyphs) {
+		first := "1"
+		if zero {
+			first = "0"
+		}
+		return fmt.Sprintf("digit %v-%c", first, s.Glyphs[len(s.Glyphs)-1])
+	}
+	return fmt.Sprintf("one of %q", s.Glyphs)
+}
+
+// Box returns the index of the box containing a cell.  Boxes are indexed in
+// left to right, top to bottom order, beginning with zero.  For a jigsaw
+// shape, it returns the index of the cell's region.
+func (s Shape) Box(row, col int) int {
+	if s.Regions != "" {
+		return s.Regions.Region(row*s.Size() + col)
+	}
+	return (row/s.BoxHeight)*(s.Size()/s.BoxWidth) + col/s.BoxWidth
+}
+
+// units returns the cell indexes of each unit of the grid: every row, then
+// every column, then every box, each in grid order.
+func (s Shape) units() [][]int {
+	size := s.Size()
+	units := make([][]int, 3*size)
+	for r := 0; r < size; r++ {
+		for c := 0; c < size; c++ {
+			i := r*size + c
+			units[r] = append(units[r], i)
+			units[size+c] = append(units[size+c], i)
+			b := 2*size + s.Box(r, c)
+			units[b] = append(units[b], i)
+		}
+	}
+	return units
+}
@@ -0,0 +1,78 @@
+package sudoku
+
+import "testing"
+
+func TestShapeForSize(t *testing.T) {
+	cases := []struct {
+		size          int
+		width, height int
+		glyphs        string
+	}{
+		{4, 2, 2, "1234"},
+		{6, 3, 2, "123456"},
+		{8, 4, 2, "12345678"},
+		{9, 3, 3, "123456789"},
+		{12, 4, 3, "123456789ABC"},
+		{16, 4, 4, "0123456789ABCDEF"},
+		{25, 5, 5, "ABCDEFGHIJKLMNOPQRSTUVWXY"},
+	}
+	for _, c := range cases {
+		shape, err := ShapeForSize(c.size)
+		if err != nil {
+			t.Errorf("unexpected error from ShapeForSize(%v): %v", c.size, err)
+			continue
+		}
+		if shape.BoxWidth != c.width || shape.BoxHeight != c.height || shape.Glyphs != c.glyphs {
+			t.Errorf("incorrect result from ShapeForSize(%v): expected %v×%v %q, got %v×%v %q", c.size, c.width, c.height, c.glyphs, shape.BoxWidth, shape.BoxHeight, shape.Glyphs)
+		}
+		if shape.Size() != c.size || shape.NumCells() != c.size*c.size {
+			t.Errorf("incorrect dimensions for %v: got size %v, %v cells", shape, shape.Size(), shape.NumCells())
+		}
+	}
+	if shape, _ := ShapeForSize(Size); shape != Classic {
+		t.Errorf("incorrect result from ShapeForSize(%v): expected Classic, got %v", Size, shape)
+	}
+	for _, size := range []int{0, 1, 5, 7, 13, 49} {
+		if _, err := ShapeForSize(size); err == nil {
+			t.Errorf("no error from ShapeForSize(%v)", size)
+		}
+	}
+}
+
+func TestShapeValidate(t *testing.T) {
+	invalid := []Shape{
+		{0, 3, "", ""},
+		{3, 3, "12345678", ""},
+		{3, 3, "123456788", ""},
+		{2, 2, "12.4", ""},
+		{2, 2, "12 4", ""},
+		{7, 7, DefaultGlyphs(49), ""},
+	}
+	for _, shape := range invalid {
+		if err := shape.Validate(); err == nil {
+			t.Errorf("no error from Validate for invalid shape %+v", shape)
+		}
+	}
+	if err := (Shape{3, 2, "ABCDEF", ""}).Validate(); err != nil {
+		t.Errorf("unexpected error from Validate for custom glyphs: %v", err)
+	}
+}
+
+func TestShapeBox(t *testing.T) {
+	shape, _ := NewShape(3, 2)
+	cases := []struct {
+		row, col, expect int
+	}{
+		{0, 0, 0},
+		{0, 3, 1},
+		{1, 5, 1},
+		{2, 0, 2},
+		{3, 4, 3},
+		{5, 5, 5},
+	}
+	for _, c := range cases {
+		if result := shape.Box(c.row, c.col); result != c.expect {
+			t.Errorf("incorrect result from Box(%v, %v): expected %v, got %v", c.row, c.col, c.expect, result)
+		}
+	}
+}
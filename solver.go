@@ -1,50 +1,12 @@
 package sudoku
 
+import "context"
+
 // Candidates returns all of the candidate glyphs for a given puzzle cell.
+//
+// It is a wrapper for Grid.Candidates.
 func (puz *Puzzle) Candidates(r, c int) (result []byte) {
-	candidates := make(map[byte]bool)
-	for _, glyph := range Glyphs {
-		candidates[glyph] = true
-	}
-
-	// Eliminate glyphs in same row.
-	for i := 0; i < Size; i++ {
-		if i != c {
-			glyph := puz[coordsToIndex(r, i)]
-			if Known(glyph) {
-				candidates[glyph] = false
-			}
-		}
-	}
-	// Eliminate glyphs in same column.
-	for i := 0; i < Size; i++ {
-		if i != r {
-			glyph := puz[coordsToIndex(i, c)]
-			if Known(glyph) {
-				candidates[glyph] = false
-			}
-		}
-	}
-	// Eliminate glyphs in same subgrid.
-	sr := (r / SubSize) * SubSize
-	sc := (c / SubSize) * SubSize
-	for i := sr; i < sr+SubSize; i++ {
-		for j := sc; j < sc+SubSize; j++ {
-			if i != r || j != c {
-				glyph := puz[coordsToIndex(i, j)]
-				if Known(glyph) {
-					candidates[glyph] = false
-				}
-			}
-		}
-	}
-	// Return all candidates not eliminated.
-	for _, glyph := range Glyphs {
-		if candidates[glyph] {
-			result = append(result, glyph)
-		}
-	}
-	return
+	return puz.Grid().Candidates(r, c)
 }
 
 // solveSolo solves a given cell, if it can be determined by simple candidate
@@ -363,62 +325,28 @@ func (puz *Puzzle) SolveEasy() (remain int) {
 	return
 }
 
-// Guess attempts to solve a puzzle by brute force guesswork.
-//
-// Starting with the given cell, guess tries each glyph in turn and, if it does
-// not violate any constraints, recurses on to the next unknown cell and
-// repeats the process.
+// Solve attempts to solve a sudoku puzzle.
 //
-// The channel receives true if the cell and all subsequent cells have a
-// satisfactory solution, false otherwise.
+// If the puzzle has a solution, it is written into the puzzle and Solve
+// returns zero; if it has several, the first one found is used.  Otherwise
+// the puzzle is not modified, and Solve returns the number of unknown cells.
 //
-// Eventually, either all cells will be solved, or no solution can be
-// discovered.
-func (puz *Puzzle) guess(r, c int, ch chan bool) {
-	subgrid := CellSubGrid(r, c)
-	index := coordsToIndex(r, c)
-	for _, glyph := range Glyphs {
-		if (puz.glyphInRow(glyph, r, c) ||
-				puz.glyphInColumn(glyph, c, r) ||
-				puz.glyphInSubGrid(glyph, subgrid, r, c)) {
-			continue
-		}
-		puz[index] = glyph
-		if puz.Validate() == nil {
-			nr, nc, found := puz.FindUnknown(r, c)
-			if found {
-				// So far so good, recurse to the next cell.
-				nch := make(chan bool)
-				go puz.guess(nr, nc, nch)
-				if <-nch {
-					ch <- true
-					return
-				}
-			} else {
-				ch <- true
-				return
-			}
-		}
-	}
-	// No solution found
-	puz[index] = Unknown
-	ch <- false
+// It is a wrapper for Grid.Solve.
+func (puz *Puzzle) Solve() (remain int) {
+	remain, _ = puz.SolveContext(context.Background())
+	return
 }
 
-// Solve attempts to solve a sudoku puzzle.
-//
-// It uses a combination of logical elimination and outright guesswork,
-// continuing until either all cells have been solved, or no further progress
-// can be made.
+// SolveContext is like Solve, but abandons the search when the context is
+// cancelled or its deadline expires.
 //
-// Returns the number of cells that remain unsolved.
-func (puz *Puzzle) Solve() (remain int) {
-	puz.SolveEasy()
-	ch := make(chan bool)
-	r, c, found := puz.NextUnknown(0, 0)
-	if found {
-		go puz.guess(r, c, ch)
-		<-ch
+// In that case it returns ctx.Err(), and the puzzle is not modified.
+func (puz *Puzzle) SolveContext(ctx context.Context) (remain int, err error) {
+	g := puz.Grid()
+	if remain, err = g.SolveContext(ctx); remain == 0 {
+		copy(puz[:], g.Cells)
+	} else {
+		remain = puz.NumUnknowns()
 	}
-	return puz.NumUnknowns()
+	return
 }
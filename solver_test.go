@@ -2,7 +2,9 @@ package sudoku
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 )
 
 func TestCandidates(t *testing.T) {
@@ -271,6 +273,49 @@ func TestSolve(t *testing.T) {
 	}
 }
 
+func TestSolveContext(t *testing.T) {
+	// “Extreme” difficulty
+	orig := Puzzle{
+		' ', ' ', '8', ' ', ' ', '6', '2', '5', ' ',
+		' ', ' ', ' ', ' ', '7', ' ', ' ', '3', ' ',
+		' ', ' ', ' ', ' ', '1', '2', '9', '8', ' ',
+		' ', ' ', '5', ' ', ' ', '3', ' ', ' ', ' ',
+		' ', '2', ' ', '7', ' ', '1', ' ', '6', ' ',
+		' ', ' ', ' ', '8', ' ', ' ', '1', ' ', ' ',
+		' ', '3', '6', '2', '8', ' ', ' ', ' ', ' ',
+		' ', '7', ' ', ' ', '9', ' ', ' ', ' ', ' ',
+		' ', '8', '2', '1', ' ', ' ', '4', ' ', ' '}
+	puz := orig
+	remain, err := puz.SolveContext(context.Background())
+	if err != nil || remain != 0 {
+		t.Errorf("incorrect return from SolveContext: expected 0, nil, got %v, %v", remain, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	puz = orig
+	remain, err = puz.SolveContext(ctx)
+	if err != context.Canceled {
+		t.Errorf("incorrect error from SolveContext: expected %v, got %v", context.Canceled, err)
+	}
+	if remain != orig.NumUnknowns() || !puz.Equal(orig) {
+		t.Errorf("puzzle modified by cancelled SolveContext: expected:\n%v\n\ngot:\n%v", orig.String(), puz.String())
+	}
+
+	// Cancelled part way through a long search.
+	g := pigeonholeGrid()
+	before := g.String()
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	remain, err = g.SolveContext(ctx)
+	if err != ctx.Err() || err != context.DeadlineExceeded {
+		t.Errorf("incorrect error from SolveContext: expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if remain != g.NumUnknowns() || g.String() != before {
+		t.Errorf("grid modified by cancelled SolveContext: expected:\n%v\n\ngot:\n%v", before, g.String())
+	}
+}
+
 func BenchmarkSolveEasy(b *testing.B) {
 	puz := Puzzle{
 		'2', ' ', ' ', '6', '3', ' ', ' ', '1', ' ',
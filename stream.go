@@ -0,0 +1,255 @@
+package sudoku
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// GridReader reads a sequence of grids of one shape from an input stream.
+//
+// The input may contain any mixture of grids in the line format, one per
+// line, and in the grid format, separated from one another by blank lines.
+// Blank lines, and comment lines beginning with '#', are ignored between
+// grids.
+//
+// A header comment of the form "# rules: x, windoku" selects variant rules
+// for the grids which follow it, until the next such header; see ParseRules.
+// The grids are then returned with the rules' VariantConstraints.
+//
+// Successive calls to Scan step through the grids in the input, in the manner
+// of bufio.Scanner.
+type GridReader struct {
+	shape   Shape
+	scanner *bufio.Scanner
+	line    int
+	start   int
+	grid    *Grid
+	err     error
+	// rules are the rules from the latest header, and constraints their
+	// VariantConstraints.
+	rules       []Rule
+	constraints []Constraint
+}
+
+// NewGridReader returns a new GridReader reading grids of the given shape
+// from 'r'.
+func NewGridReader(r io.Reader, shape Shape) *GridReader {
+	return &GridReader{shape: shape, scanner: bufio.NewScanner(r)}
+}
+
+// next returns the next line of input, without its line ending, or false if
+// the input is exhausted.
+func (gr *GridReader) next() ([]byte, bool) {
+	if !gr.scanner.Scan() {
+		return nil, false
+	}
+	gr.line++
+	return bytes.TrimRight(gr.scanner.Bytes(), "\r"), true
+}
+
+// rulesHeader returns the text following "rules:" in a header comment, or
+// false if the line is not a rules header.
+func rulesHeader(line []byte) (string, bool) {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 || trimmed[0] != '#' {
+		return "", false
+	}
+	trimmed = bytes.TrimSpace(trimmed[1:])
+	if !bytes.HasPrefix(trimmed, []byte("rules:")) {
+		return "", false
+	}
+	return string(trimmed[len("rules:"):]), true
+}
+
+// skip returns whether a line should be ignored between grids.
+func skip(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) == 0 || trimmed[0] == '#'
+}
+
+// Scan advances the reader to the next grid, which will then be available
+// through the Grid method.  It returns false when the input is exhausted or
+// an error occurs, after which Err returns the error, if any.
+//
+// Errors in the input are reported as a *ParseError, with line numbers
+// counted from the start of the stream.
+func (gr *GridReader) Scan() bool {
+	if gr.err != nil {
+		return false
+	}
+	var line []byte
+	for {
+		var ok bool
+		line, ok = gr.next()
+		if !ok {
+			gr.err = gr.scanner.Err()
+			return false
+		}
+		if text, ok := rulesHeader(line); ok {
+			if !gr.setRules(text) {
+				return false
+			}
+			continue
+		}
+		if !skip(line) {
+			break
+		}
+	}
+	gr.start = gr.line
+
+	var err error
+	grid := NewGrid(gr.shape)
+	size := gr.shape.Size()
+	if bytes.IndexByte(bytes.TrimSpace(line), ' ') < 0 {
+		err = grid.ReadLine(line)
+	} else {
+		// Collect lines up to the end of the grid.
+		text := append([]byte(nil), line...)
+		n := 1
+		for ; n < size; n++ {
+			line, ok := gr.next()
+			if !ok || skip(line) {
+				break
+			}
+			text = append(text, '\n')
+			text = append(text, line...)
+		}
+		if n < size {
+			err = &ParseError{Reason: fmt.Sprintf("expected %v lines, got %v", size, n)}
+		} else {
+			err = grid.Read(text)
+		}
+	}
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			perr.Line += gr.start
+			if perr.Line > gr.start {
+				perr.Line--
+			}
+		}
+		gr.err = err
+		return false
+	}
+	grid.Constraints = gr.constraints
+	gr.grid = grid
+	return true
+}
+
+// setRules applies the rules named in a header, and returns false if they
+// are not valid for the reader's shape.
+func (gr *GridReader) setRules(text string) bool {
+	rules, err := ParseRules(text)
+	if err == nil {
+		gr.constraints, err = VariantConstraints(gr.shape, rules)
+	}
+	if err != nil {
+		gr.err = &ParseError{Line: gr.line, Reason: err.Error()}
+		return false
+	}
+	gr.rules = rules
+	return true
+}
+
+// Rules returns the rules which apply to the grid found by the most recent
+// call to Scan.
+func (gr *GridReader) Rules() []Rule {
+	return gr.rules
+}
+
+// Grid returns the grid found by the most recent call to Scan.
+func (gr *GridReader) Grid() *Grid {
+	return gr.grid
+}
+
+// Line returns the line number on which the most recent grid began.
+func (gr *GridReader) Line() int {
+	return gr.start
+}
+
+// Err returns the first error encountered by the reader, other than io.EOF.
+func (gr *GridReader) Err() error {
+	return gr.err
+}
+
+// PuzzleReader reads a sequence of puzzles from an input stream.  It is a
+// GridReader for grids of the Classic shape.
+type PuzzleReader struct {
+	*GridReader
+}
+
+// NewPuzzleReader returns a new PuzzleReader reading from 'r'.
+func NewPuzzleReader(r io.Reader) *PuzzleReader {
+	return &PuzzleReader{NewGridReader(r, Classic)}
+}
+
+// Puzzle returns the puzzle found by the most recent call to Scan.
+func (pr *PuzzleReader) Puzzle() (puz Puzzle) {
+	if pr.grid != nil {
+		puz, _ = pr.grid.Puzzle()
+	}
+	return
+}
+
+// PuzzleWriter writes a sequence of puzzles or grids to an output stream, in
+// a form that can be read by PuzzleReader or GridReader.
+//
+// Puzzles are written in the grid format, separated by blank lines, unless
+// LineFormat is set, in which case they are written one per line.  Output is
+// buffered, so Flush must be called once all puzzles have been written.
+type PuzzleWriter struct {
+	LineFormat bool
+	w          *bufio.Writer
+	count      int
+}
+
+// NewPuzzleWriter returns a new PuzzleWriter writing to 'w'.
+func NewPuzzleWriter(w io.Writer) *PuzzleWriter {
+	return &PuzzleWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes one puzzle to the stream.
+func (pw *PuzzleWriter) Write(puz *Puzzle) error {
+	return pw.WriteGrid(puz.Grid())
+}
+
+// WriteGrid writes one grid to the stream.
+func (pw *PuzzleWriter) WriteGrid(g *Grid) error {
+	if pw.LineFormat {
+		pw.w.WriteString(g.Line())
+		_, err := pw.w.WriteString("\n")
+		return err
+	}
+	if pw.count > 0 {
+		pw.w.WriteString("\n")
+	}
+	pw.count++
+	_, err := pw.w.WriteString(g.String())
+	return err
+}
+
+// WriteRules writes a header selecting the rules for the grids which follow.
+func (pw *PuzzleWriter) WriteRules(rules []Rule) error {
+	if pw.count > 0 && !pw.LineFormat {
+		// Separate the header from the previous grid; the next grid
+		// follows the header directly.
+		pw.w.WriteString("\n")
+		pw.count = 0
+	}
+	return pw.Comment("rules: " + FormatRules(rules))
+}
+
+// Comment writes a comment line to the stream.  The text should not contain
+// any newlines.
+func (pw *PuzzleWriter) Comment(text string) error {
+	_, err := pw.w.WriteString("# " + text + "\n")
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (pw *PuzzleWriter) Flush() error {
+	return pw.w.Flush()
+}
@@ -0,0 +1,199 @@
+package sudoku
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPuzzleReader(t *testing.T) {
+	input := "# A collection of puzzles\n" +
+		"\n" +
+		"1.3..6.8..5..8.12.7.91.3.56.3..67.9.5.78...3.8.1.3.5.7.4..78.1.6.8..2.4..12.45.78\n" +
+		"..8..625.....7..3.....1298...5..3....2.7.1.6....8..1...3628.....7..9.....821..4..\r\n" +
+		"\n" +
+		"# Grid format\n" +
+		"_ _ 3 _ 5 _ 2 _ _\n" +
+		"2 _ _ 7 _ 6 _ _ 9\n" +
+		"7 _ _ _ _ _ _ _ 4\n" +
+		"_ 2 _ 8 _ 1 _ 6 _\n" +
+		"_ _ 9 6 _ 2 4 _ _\n" +
+		"_ 4 _ 3 _ 5 _ 2 _\n" +
+		"4 _ _ _ _ _ _ _ 8\n" +
+		"3 _ _ 4 _ 8 _ _ 2\n" +
+		"_ _ 5 _ 1 _ 3 _ _\n" +
+		"\n" +
+		"\n"
+	expect := []struct {
+		line  int
+		first string
+	}{
+		{3, "1.3..6.8."},
+		{4, "..8..625."},
+		{7, "..3.5.2.."},
+	}
+	pr := NewPuzzleReader(strings.NewReader(input))
+	n := 0
+	for pr.Scan() {
+		if n >= len(expect) {
+			t.Fatalf("too many puzzles from PuzzleReader")
+		}
+		puz := pr.Puzzle()
+		if pr.Line() != expect[n].line {
+			t.Errorf("incorrect line for puzzle %v: expected %v, got %v", n+1, expect[n].line, pr.Line())
+		}
+		if !strings.HasPrefix(puz.Line(), expect[n].first) {
+			t.Errorf("incorrect puzzle %v: expected row 1 %q, got:\n%v", n+1, expect[n].first, puz.String())
+		}
+		n++
+	}
+	if pr.Err() != nil {
+		t.Errorf("unexpected error from PuzzleReader: %v", pr.Err())
+	}
+	if n != len(expect) {
+		t.Errorf("incorrect number of puzzles from PuzzleReader: expected %v, got %v", len(expect), n)
+	}
+
+	// Errors report line numbers within the stream.
+	inputs := []struct {
+		input string
+		line  int
+	}{
+		{"# comment\n\n1.3..6.8..5..8.12.7\n", 3},
+		{"\n_ _ 3 _ 5 _ 2 _ _\n2 _ _ 7 _ 6 _ _ 9\n7 _ _ _ x _ _ _ 4\n", 2},
+		{"\n\n_ _ 3 _ 5 _ 2 _ _\n2 _ _ 7 _ 6 _ _ 9\n7 _ _ _ x _ _ _ 4\n" +
+			"_ 2 _ 8 _ 1 _ 6 _\n_ _ 9 6 _ 2 4 _ _\n_ 4 _ 3 _ 5 _ 2 _\n" +
+			"4 _ _ _ _ _ _ _ 8\n3 _ _ 4 _ 8 _ _ 2\n_ _ 5 _ 1 _ 3 _ _\n", 5},
+	}
+	for _, c := range inputs {
+		pr := NewPuzzleReader(strings.NewReader(c.input))
+		if pr.Scan() {
+			t.Errorf("Scan succeeded on malformed input %q", c.input)
+		}
+		var perr *ParseError
+		if !errors.As(pr.Err(), &perr) {
+			t.Errorf("incorrect error from PuzzleReader for %q: expected *ParseError, got %v", c.input, pr.Err())
+		} else if perr.Line != c.line {
+			t.Errorf("incorrect line in error from PuzzleReader for %q: expected %v, got %v", c.input, c.line, perr.Line)
+		}
+		if pr.Scan() {
+			t.Errorf("Scan succeeded after error")
+		}
+	}
+}
+
+func TestPuzzleWriter(t *testing.T) {
+	a := Puzzle{
+		'1', ' ', '3', ' ', ' ', '6', ' ', '8', ' ',
+		' ', '5', ' ', ' ', '8', ' ', '1', '2', ' ',
+		'7', ' ', '9', '1', ' ', '3', ' ', '5', '6',
+		' ', '3', ' ', ' ', '6', '7', ' ', '9', ' ',
+		'5', ' ', '7', '8', ' ', ' ', ' ', '3', ' ',
+		'8', ' ', '1', ' ', '3', ' ', '5', ' ', '7',
+		' ', '4', ' ', ' ', '7', '8', ' ', '1', ' ',
+		'6', ' ', '8', ' ', ' ', '2', ' ', '4', ' ',
+		' ', '1', '2', ' ', '4', '5', ' ', '7', '8'}
+	b := a
+	b.SetCell(CellRef{0, 1}, '2')
+
+	for _, line := range []bool{false, true} {
+		var buf bytes.Buffer
+		pw := NewPuzzleWriter(&buf)
+		pw.LineFormat = line
+		pw.Comment("two puzzles")
+		pw.Write(&a)
+		pw.Write(&b)
+		if err := pw.Flush(); err != nil {
+			t.Errorf("unexpected error from PuzzleWriter: %v", err)
+		}
+		if !strings.HasPrefix(buf.String(), "# two puzzles\n") {
+			t.Errorf("missing comment in PuzzleWriter output:\n%v", buf.String())
+		}
+
+		pr := NewPuzzleReader(&buf)
+		for i, expect := range []Puzzle{a, b} {
+			if !pr.Scan() {
+				t.Fatalf("failed to read back puzzle %v from PuzzleWriter output: %v", i+1, pr.Err())
+			}
+			result := pr.Puzzle()
+			if !result.Equal(expect) {
+				t.Errorf("incorrect puzzle %v read back: expected:\n%v\n\ngot:\n%v", i+1, expect.String(), result.String())
+			}
+		}
+		if pr.Scan() {
+			t.Errorf("too many puzzles read back from PuzzleWriter output")
+		}
+	}
+}
+
+func TestGridReader(t *testing.T) {
+	shape, _ := ShapeForSize(4)
+	input := "# 4×4 grids\n" +
+		".3.4........41.3\n" +
+		"\n" +
+		"1 2 _ _\n" +
+		"_ _ 1 2\n" +
+		"_ _ _ _\n" +
+		"_ _ _ _\n" +
+		"123\n"
+	gr := NewGridReader(strings.NewReader(input), shape)
+	var lines []string
+	for gr.Scan() {
+		lines = append(lines, gr.Grid().Line())
+	}
+	expect := []string{".3.4........41.3", "12....12........"}
+	if len(lines) != len(expect) || lines[0] != expect[0] || lines[1] != expect[1] {
+		t.Errorf("incorrect grids from GridReader: expected %q, got %q", expect, lines)
+	}
+	var perr *ParseError
+	if err := gr.Err(); !errors.As(err, &perr) || perr.Line != 8 {
+		t.Errorf("incorrect error from GridReader: expected ParseError on line 8, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	pw := NewPuzzleWriter(&buf)
+	pw.LineFormat = true
+	g := NewGrid(shape)
+	g.ReadLine([]byte(expect[0]))
+	pw.WriteGrid(g)
+	pw.Flush()
+	if buf.String() != expect[0]+"\n" {
+		t.Errorf("incorrect result from WriteGrid: expected %q, got %q", expect[0]+"\n", buf.String())
+	}
+}
+
+func TestGridReaderRules(t *testing.T) {
+	input := "# rules: x\n" +
+		diagonalPuzzle + "\n" +
+		"# rules: none\n" +
+		diagonalPuzzle + "\n" +
+		"# rules: hyper\n" +
+		diagonalPuzzle + "\n"
+	pr := NewPuzzleReader(strings.NewReader(input))
+	var counts []int
+	for pr.Scan() {
+		counts = append(counts, pr.Grid().NumSolutions())
+	}
+	if expect := []int{1, 2}; !reflect.DeepEqual(counts, expect) {
+		t.Errorf("incorrect numbers of solutions from PuzzleReader with rules: expected %v, got %v", expect, counts)
+	}
+	var perr *ParseError
+	if err := pr.Err(); !errors.As(err, &perr) || perr.Line != 5 {
+		t.Errorf("incorrect error for unknown rule: expected ParseError on line 5, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	pw := NewPuzzleWriter(&buf)
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(diagonalSolution))
+	pw.WriteGrid(g)
+	pw.WriteRules([]Rule{DiagonalRule, WindokuRule})
+	pw.WriteGrid(g)
+	pw.Flush()
+	expect := g.String() + "\n# rules: x, windoku\n" + g.String()
+	if buf.String() != expect {
+		t.Errorf("incorrect output from WriteRules: expected %q, got %q", expect, buf.String())
+	}
+}
@@ -0,0 +1,86 @@
+package sudoku
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// svgColour returns the SVG colour and opacity for a colour.
+func svgColour(c color.Color) (string, float64) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B), float64(n.A) / 0xff
+}
+
+// svgPaint returns the SVG attributes for filling or stroking with a colour.
+// The opacity attribute is omitted for opaque colours.
+func svgPaint(attr string, c color.Color) string {
+	hex, opacity := svgColour(c)
+	if opacity >= 1 {
+		return fmt.Sprintf(`%v="%v"`, attr, hex)
+	}
+	return fmt.Sprintf(`%v="%v" %v-opacity="%.3g"`, attr, hex, attr, opacity)
+}
+
+// RenderSVG draws the puzzle as an SVG image, and writes it to 'w'.
+//
+// See RenderOptions for the configuration of the drawing.  A nil 'opts' is
+// equivalent to the zero RenderOptions.
+func (puz *Puzzle) RenderSVG(w io.Writer, opts *RenderOptions) error {
+	l := newLayout(puz, opts)
+	return l.writeSVG(w)
+}
+
+// writeSVG writes the layout as an SVG document.
+func (l *layout) writeSVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n",
+		l.width, l.height, l.width, l.height)
+	writeShapes(bw, l.rects, l.circles)
+	for _, ln := range l.lines {
+		dash := ""
+		if ln.dashed {
+			dash = fmt.Sprintf(` stroke-dasharray="%g %g"`, ln.width*3, ln.width*2)
+		}
+		fmt.Fprintf(bw, `<line x1="%g" y1="%g" x2="%g" y2="%g" %v stroke-width="%g"%v/>`+"\n",
+			ln.x1, ln.y1, ln.x2, ln.y2, svgPaint("stroke", ln.stroke), ln.width, dash)
+	}
+	writeShapes(bw, l.markRects, l.markCircles)
+	for _, t := range l.texts {
+		weight := ""
+		if t.bold {
+			weight = ` font-weight="bold"`
+		}
+		fmt.Fprintf(bw, `<text x="%g" y="%g" font-family="sans-serif" font-size="%g"%v text-anchor="middle" dominant-baseline="central" %v>%c</text>`+"\n",
+			t.x, t.y, t.size, weight, svgPaint("fill", t.fill), t.glyph)
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// writeShapes writes rectangles and then circles as SVG elements.
+func writeShapes(bw *bufio.Writer, rects []layoutRect, circles []layoutCircle) {
+	for _, r := range rects {
+		fmt.Fprintf(bw, `<rect x="%g" y="%g" width="%g" height="%g" %v/>`+"\n", r.x, r.y, r.w, r.h, svgPaint("fill", r.fill))
+	}
+	for _, c := range circles {
+		fill, stroke := `fill="none"`, ""
+		if c.fill != nil {
+			fill = svgPaint("fill", c.fill)
+		}
+		if c.stroke != nil {
+			stroke = fmt.Sprintf(` %v stroke-width="%g"`, svgPaint("stroke", c.stroke), c.width)
+		}
+		fmt.Fprintf(bw, `<circle cx="%g" cy="%g" r="%g" %v%v/>`+"\n", c.x, c.y, c.r, fill, stroke)
+	}
+}
+
+// RenderSVG draws the board of a multi-grid puzzle as an SVG image, and
+// writes it to 'w'.
+//
+// Only the sizes and colours of the RenderOptions apply, and every known cell
+// is drawn as a given.  A nil 'opts' is equivalent to the zero RenderOptions.
+func (m *Multi) RenderSVG(w io.Writer, opts *RenderOptions) error {
+	return newMultiLayout(m, opts).writeSVG(w)
+}
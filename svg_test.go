@@ -0,0 +1,75 @@
+package sudoku
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestRenderSVG(t *testing.T) {
+	puz := Puzzle{
+		'1', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', '5', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', '9',
+	}
+	var givens Mask
+	givens[0] = true
+	var cg CandidateGrid
+	cg.Set(CellRef{0, 1}, []byte("23"))
+
+	var buf bytes.Buffer
+	err := puz.RenderSVG(&buf, &RenderOptions{
+		CellSize:   30,
+		Givens:     &givens,
+		Candidates: &cg,
+		Highlights: []Highlight{{CellRef{2, 2}, color.RGBA{0xff, 0xee, 0x88, 0xff}}},
+		Links: []Link{
+			{CandidateRef{CellRef{0, 1}, '2'}, CandidateRef{CellRef{0, 1}, '3'}, false},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from RenderSVG: %v", err)
+	}
+	out := buf.String()
+
+	expect := []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 300 300">`,
+		`<rect x="0" y="0" width="300" height="300" fill="#ffffff"/>`,
+		`<rect x="75" y="75" width="30" height="30" fill="#ffee88"/>`,
+		`font-weight="bold" text-anchor="middle" dominant-baseline="central" fill="#000000">1</text>`,
+		`fill="#1f4fbf">5</text>`,
+		`fill="#1f4fbf">9</text>`,
+		`fill="#555555">2</text>`,
+		`fill="#555555">3</text>`,
+		`stroke="#cc2222" stroke-width="`,
+		`stroke-dasharray=`,
+		"</svg>\n",
+	}
+	for _, e := range expect {
+		if !strings.Contains(out, e) {
+			t.Errorf("incorrect result from RenderSVG: expected output to contain %q, got:\n%v", e, out)
+		}
+	}
+	if n := strings.Count(out, "<line"); n != 2*(Size+1)+1 {
+		t.Errorf("incorrect number of lines from RenderSVG: expected %v, got %v", 2*(Size+1)+1, n)
+	}
+	if n := strings.Count(out, "<text"); n != 5 {
+		t.Errorf("incorrect number of glyphs from RenderSVG: expected 5, got %v", n)
+	}
+
+	// With no options, every known cell is a given.
+	buf.Reset()
+	if err := puz.RenderSVG(&buf, nil); err != nil {
+		t.Fatalf("unexpected error from RenderSVG: %v", err)
+	}
+	if n := strings.Count(buf.String(), `font-weight="bold"`); n != 3 {
+		t.Errorf("incorrect number of givens from RenderSVG: expected 3, got %v", n)
+	}
+}
@@ -0,0 +1,164 @@
+package sudoku
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rule names a variant which adds extra constraints to a grid.
+type Rule string
+
+const (
+	// DiagonalRule (Sudoku-X) requires both main diagonals to hold every
+	// glyph.
+	DiagonalRule Rule = "x"
+	// WindokuRule (Hyper Sudoku) adds the windows: boxes inset one cell from
+	// the edge of the grid and one cell apart, of which a 9×9 grid has four.
+	WindokuRule Rule = "windoku"
+	// CentreDotRule requires the centre cells of the boxes to hold every
+	// glyph.  It needs boxes with an odd number of rows and columns.
+	CentreDotRule Rule = "centre-dot"
+	// DisjointRule (Disjoint Groups) requires the cells in the same position
+	// within every box to hold every glyph.
+	DisjointRule Rule = "disjoint"
+	// AntiKnightRule forbids the same glyph in cells a chess knight's move
+	// apart; see AntiKnight.
+	AntiKnightRule Rule = "anti-knight"
+	// AntiKingRule forbids the same glyph in cells a chess king's move apart;
+	// see AntiKing.
+	AntiKingRule Rule = "anti-king"
+	// NonConsecutiveRule forbids consecutive values in orthogonally adjacent
+	// cells; see NonConsecutive.
+	NonConsecutiveRule Rule = "non-consecutive"
+)
+
+// Rules lists the supported rules.
+var Rules = []Rule{DiagonalRule, WindokuRule, CentreDotRule, DisjointRule, AntiKnightRule, AntiKingRule, NonConsecutiveRule}
+
+// Constraints returns the extra constraints which the rule adds to grids of
+// the given shape.  It returns an error if the rule is unknown, or cannot be
+// applied to the shape.
+func (r Rule) Constraints(shape Shape) ([]Constraint, error) {
+	size := shape.Size()
+	w, h := shape.BoxWidth, shape.BoxHeight
+	switch r {
+	case AntiKnightRule:
+		return []Constraint{AntiKnight(shape)}, nil
+	case AntiKingRule:
+		return []Constraint{AntiKing(shape)}, nil
+	case NonConsecutiveRule:
+		return []Constraint{NonConsecutive(shape)}, nil
+	}
+	if r != DiagonalRule && shape.Regions != "" {
+		return nil, fmt.Errorf("rule %q needs a grid with rectangular boxes", r)
+	}
+	var result []Constraint
+	switch r {
+	case DiagonalRule:
+		main := Unit{Type: DiagonalUnit, Index: 0}
+		anti := Unit{Type: DiagonalUnit, Index: 1}
+		for i := 0; i < size; i++ {
+			main.Members = append(main.Members, i*size+i)
+			anti.Members = append(anti.Members, i*size+size-1-i)
+		}
+		result = append(result, main, anti)
+	case WindokuRule:
+		for top := 1; top+h < size; top += h + 1 {
+			for left := 1; left+w < size; left += w + 1 {
+				u := Unit{Type: WindowUnit, Index: len(result)}
+				for row := top; row < top+h; row++ {
+					for col := left; col < left+w; col++ {
+						u.Members = append(u.Members, row*size+col)
+					}
+				}
+				result = append(result, u)
+			}
+		}
+		if len(result) == 0 {
+			return nil, fmt.Errorf("no windows fit in a %v grid", shape)
+		}
+	case CentreDotRule:
+		if w%2 == 0 || h%2 == 0 {
+			return nil, fmt.Errorf("boxes of a %v grid have no centre cell", shape)
+		}
+		result = append(result, disjointGroup(shape, h/2, w/2))
+	case DisjointRule:
+		for row := 0; row < h; row++ {
+			for col := 0; col < w; col++ {
+				result = append(result, disjointGroup(shape, row, col))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown rule %q", r)
+	}
+	return result, nil
+}
+
+// disjointGroup returns the unit of cells in the given row and column within
+// every box.
+func disjointGroup(shape Shape, row, col int) Unit {
+	size := shape.Size()
+	w, h := shape.BoxWidth, shape.BoxHeight
+	u := Unit{Type: DisjointUnit, Index: row*w + col}
+	for r := row; r < size; r += h {
+		for c := col; c < size; c += w {
+			u.Members = append(u.Members, r*size+c)
+		}
+	}
+	sort.Ints(u.Members)
+	return u
+}
+
+// VariantConstraints returns the DefaultConstraints for a shape followed by
+// the constraints of each of the given rules.  With no rules, it returns nil,
+// which as Grid.Constraints stands for the default constraints.
+func VariantConstraints(shape Shape, rules []Rule) ([]Constraint, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	result := DefaultConstraints(shape)
+	for _, r := range rules {
+		cs, err := r.Constraints(shape)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, cs...)
+	}
+	return result, nil
+}
+
+// ParseRules reads a list of rule names separated by commas or spaces, as
+// written by FormatRules.  The name "none" stands for an empty list.
+func ParseRules(text string) (rules []Rule, err error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 1 && fields[0] == "none" {
+		return nil, nil
+	}
+	for _, f := range fields {
+		r := Rule(strings.ToLower(f))
+		known := false
+		for _, k := range Rules {
+			known = known || r == k
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown rule %q", f)
+		}
+		rules = append(rules, r)
+	}
+	return
+}
+
+// FormatRules returns a list of rules in the form read by ParseRules.
+func FormatRules(rules []Rule) string {
+	if len(rules) == 0 {
+		return "none"
+	}
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = string(r)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,140 @@
+package sudoku
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	diagonalPuzzle   = ".....8..9.3..71...8..4..2..5......48...9.......9..4.73..3................9......7"
+	diagonalSolution = "164328759932571864875496231516237948347985126289164573723849615658713492491652387"
+)
+
+func TestRuleConstraints(t *testing.T) {
+	cases := []struct {
+		rule  Rule
+		count int
+		index int
+		unit  Unit
+	}{
+		{DiagonalRule, 2, 0, Unit{DiagonalUnit, 0, []int{0, 10, 20, 30, 40, 50, 60, 70, 80}}},
+		{DiagonalRule, 2, 1, Unit{DiagonalUnit, 1, []int{8, 16, 24, 32, 40, 48, 56, 64, 72}}},
+		{WindokuRule, 4, 0, Unit{WindowUnit, 0, []int{10, 11, 12, 19, 20, 21, 28, 29, 30}}},
+		{WindokuRule, 4, 3, Unit{WindowUnit, 3, []int{50, 51, 52, 59, 60, 61, 68, 69, 70}}},
+		{CentreDotRule, 1, 0, Unit{DisjointUnit, 4, []int{10, 13, 16, 37, 40, 43, 64, 67, 70}}},
+		{DisjointRule, 9, 2, Unit{DisjointUnit, 2, []int{2, 5, 8, 29, 32, 35, 56, 59, 62}}},
+	}
+	for _, c := range cases {
+		units, err := c.rule.Constraints(Classic)
+		if err != nil {
+			t.Fatalf("unexpected error from Constraints for %v: %v", c.rule, err)
+		}
+		if len(units) != c.count {
+			t.Errorf("incorrect number of constraints for %v: expected %v, got %v", c.rule, c.count, len(units))
+			continue
+		}
+		if !reflect.DeepEqual(units[c.index], c.unit) {
+			t.Errorf("incorrect unit %v for %v: expected %v, got %v", c.index, c.rule, c.unit, units[c.index])
+		}
+	}
+
+	shape, _ := NewShape(2, 2)
+	if units, err := WindokuRule.Constraints(shape); err != nil || len(units) != 1 {
+		t.Errorf("incorrect result from Constraints for windoku on 4×4: expected 1 window, got %v, %v", units, err)
+	}
+	jigsaw, _ := ParseJigsaw([]byte(jigsawPuzzle))
+	invalid := []struct {
+		rule  Rule
+		shape Shape
+	}{
+		{Rule("y"), Classic},
+		{CentreDotRule, shape},
+		{WindokuRule, jigsaw.Shape},
+		{DisjointRule, jigsaw.Shape},
+	}
+	for _, c := range invalid {
+		if _, err := c.rule.Constraints(c.shape); err == nil {
+			t.Errorf("no error from Constraints for %v on %v", c.rule, c.shape)
+		}
+	}
+	if _, err := DiagonalRule.Constraints(jigsaw.Shape); err != nil {
+		t.Errorf("unexpected error from Constraints for x on jigsaw: %v", err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	cases := []struct {
+		text   string
+		expect []Rule
+	}{
+		{"", nil},
+		{"none", nil},
+		{"x", []Rule{DiagonalRule}},
+		{"X, Windoku", []Rule{DiagonalRule, WindokuRule}},
+		{"centre-dot disjoint", []Rule{CentreDotRule, DisjointRule}},
+	}
+	for _, c := range cases {
+		result, err := ParseRules(c.text)
+		if err != nil {
+			t.Errorf("unexpected error from ParseRules(%q): %v", c.text, err)
+		}
+		if !reflect.DeepEqual(result, c.expect) {
+			t.Errorf("incorrect result from ParseRules(%q): expected %v, got %v", c.text, c.expect, result)
+		}
+	}
+	if _, err := ParseRules("x, hyper"); err == nil {
+		t.Errorf("no error from ParseRules for unknown rule")
+	}
+	if result := FormatRules([]Rule{DiagonalRule, WindokuRule}); result != "x, windoku" {
+		t.Errorf("incorrect result from FormatRules: expected \"x, windoku\", got %q", result)
+	}
+	if result := FormatRules(nil); result != "none" {
+		t.Errorf("incorrect result from FormatRules: expected \"none\", got %q", result)
+	}
+}
+
+func TestDiagonalSolve(t *testing.T) {
+	g := NewGrid(Classic)
+	g.ReadLine([]byte(diagonalPuzzle))
+	if n := g.NumSolutions(); n != 2 {
+		t.Errorf("incorrect result from NumSolutions without rules: expected 2, got %v", n)
+	}
+	g.Constraints, _ = VariantConstraints(Classic, []Rule{DiagonalRule})
+	if n := g.NumSolutions(); n != 1 {
+		t.Errorf("incorrect result from NumSolutions: expected 1, got %v", n)
+	}
+	g.Solve()
+	if result := g.Line(); result != diagonalSolution {
+		t.Errorf("incorrect solution: expected %v, got %v", diagonalSolution, result)
+	}
+
+	g.ReadLine([]byte(diagonalPuzzle))
+	g.Set(7, 7, '3')
+	err, ok := g.Validate().(*ConflictError)
+	if !ok {
+		t.Fatalf("expected *ConflictError from Validate, got %v", g.Validate())
+	}
+	if err.Unit != DiagonalUnit || err.Index != 0 || err.Glyph != '3' {
+		t.Errorf("incorrect conflict: expected '3' in diagonal 1, got %v", err)
+	}
+}
+
+func TestGenerateRules(t *testing.T) {
+	for _, rule := range Rules {
+		constraints, _ := VariantConstraints(Classic, []Rule{rule})
+		g, err := GenerateVariant(Classic, constraints)
+		if err != nil || g == nil {
+			t.Fatalf("no result from GenerateVariant for %v: %v", rule, err)
+		}
+		if err := g.Validate(); err != nil {
+			t.Errorf("invalid result from GenerateVariant for %v: %v", rule, err)
+		}
+		// The solution must also satisfy the rule's constraints on their own.
+		cs, _ := rule.Constraints(Classic)
+		for _, u := range cs {
+			if err := u.Check(g); err != nil {
+				t.Errorf("invalid result from GenerateVariant for %v: %v", rule, err)
+			}
+		}
+	}
+}